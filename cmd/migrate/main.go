@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gofile/internal/config"
+	"gofile/internal/migrate"
+	"gofile/models"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// usage 打印命令行用法
+func usage() {
+	fmt.Fprintf(os.Stderr, `用法: migrate [-dry-run] <命令> [参数]
+
+命令:
+  up             应用所有未应用的迁移
+  down [n]       回滚最近的n个迁移，默认为1
+  to <version>   迁移到指定版本（可升级也可回滚，0表示回滚全部）
+  status         显示每个迁移的应用状态
+
+选项:
+`)
+	flag.PrintDefaults()
+}
+
+// main 数据库迁移命令入口
+// 从config.yaml读取数据库配置，对PostgreSQL或SQLite执行版本化迁移
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只打印将要执行的SQL，不修改数据库")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	config.Init()
+	dbConf := config.AppConfig.Database
+	store, err := models.OpenStore(dbConf.Driver, dbConf.DSN())
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	gormStore, ok := store.(*models.GormStore)
+	if !ok {
+		log.Fatalf("驱动 %q 不需要迁移", dbConf.Driver)
+	}
+	sqlDB, err := gormStore.DB().DB()
+	if err != nil {
+		log.Fatalf("Failed to get database instance: %v", err)
+	}
+
+	m, err := migrate.New(sqlDB, gormStore.Dialect())
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	m.DryRun = *dryRun
+	m.Out = os.Stdout
+
+	args := flag.Args()
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("无效的回滚步数: %s", args[1])
+			}
+		}
+		err = m.Down(steps)
+	case "to":
+		if len(args) < 2 {
+			log.Fatal("to命令需要指定目标版本")
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatalf("无效的目标版本: %s", args[1])
+		}
+		err = m.To(version)
+	case "status":
+		err = printStatus(m)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("迁移失败: %v", err)
+	}
+}
+
+// printStatus 以表格形式打印迁移状态
+func printStatus(m *migrate.Migrator) error {
+	list, err := m.Status()
+	if err != nil {
+		return err
+	}
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range list {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n当前版本: %d，最新版本: %d\n", current, m.Latest())
+	return nil
+}
@@ -1,11 +1,20 @@
 package main
 
 import (
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"gofile/handlers"
 	"gofile/internal/config"
+	"gofile/internal/mail"
+	"gofile/internal/markdown"
+	"gofile/internal/migrate"
+	"gofile/internal/oidc"
+	"gofile/internal/spam"
 	"gofile/middleware"
 	"gofile/models"
 	"log"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,25 +23,108 @@ import (
 // 此函数是整个Web应用的启动入口
 // 主要功能：
 // 1. 初始化配置 - 从配置文件加载应用配置
-// 2. 初始化数据库 - 创建测试数据（开发环境）
+// 2. 初始化存储 - 根据配置选择PostgreSQL、SQLite或内存存储，检查结构版本并创建测试数据
 // 3. 创建Gin引擎实例 - 设置Web服务器框架
 // 4. 配置路由 - 设置API和页面路由
 // 5. 启动HTTP服务器 - 监听指定端口
 func main() {
 	// 初始化配置
 	config.Init()
-	// 初始化数据库
-	// PostgreSQL DSN格式: host=host port=port user=username password=password dbname=dbname
-	dsn := "host=" + config.AppConfig.Database.Host + " port=" + config.AppConfig.Database.Port + " user=" + config.AppConfig.Database.Username + " password=" + config.AppConfig.Database.Password + " dbname=" + config.AppConfig.Database.DBName + " sslmode=require"
-	if err := models.InitDB(dsn); err != nil {
-		log.Printf("Warning: %v", err)
+	// 初始化存储，驱动由database.driver决定
+	store, err := models.OpenStore(config.AppConfig.Database.Driver, config.AppConfig.Database.DSN())
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+	if checkSchema(store) {
+		models.SeedTestData(store)
+	}
+
+	// 初始化快照缓存，数据库不可用时以只读降级模式提供最后已知的数据
+	var snapshot *models.Snapshot
+	if config.AppConfig.Snapshot.Enabled {
+		snapshot, err = models.OpenSnapshot(config.AppConfig.Snapshot.Path, config.AppConfig.Snapshot.FlushInterval)
+		if err != nil {
+			log.Fatalf("Failed to open snapshot: %v", err)
+		}
+		defer snapshot.Close()
 	}
 
 	// 创建Gin引擎并配置路由
 	r := gin.Default()
+	// 只采信可信代理转发的X-Forwarded-For，否则客户端可以伪造来源IP绕过按IP的限流和登录失败限制
+	if err := r.SetTrustedProxies(config.AppConfig.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
 
 	// 设置路由
-	setupRoutes(r)
+	keys, err := loadKeySet(config.AppConfig.Auth)
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	tokens := middleware.NewTokenManager(store, keys, config.AppConfig.Auth.AccessTokenTTL, config.AppConfig.Auth.RefreshTokenTTL)
+	apiKeys := middleware.NewAPIKeys(store, store)
+	tokens.UseAPIKeys(apiKeys)
+	session, err := newSessionCookies(config.AppConfig.Auth.Session)
+	if err != nil {
+		log.Fatalf("Failed to create session cookies: %v", err)
+	}
+	tokens.UseSessionCookies(session)
+	emailTokens, err := newEmailTokens(store, config.AppConfig.Auth)
+	if err != nil {
+		log.Fatalf("Failed to create email tokens: %v", err)
+	}
+	mailer, err := newMailer(config.AppConfig.Mail)
+	if err != nil {
+		log.Fatalf("Failed to create mailer: %v", err)
+	}
+	guard, err := newLoginGuard(store, config.AppConfig.Auth.Lockout)
+	if err != nil {
+		log.Fatalf("Failed to create login guard: %v", err)
+	}
+	cors, err := newCORS(config.AppConfig.CORS)
+	if err != nil {
+		log.Fatalf("Failed to create CORS policy: %v", err)
+	}
+	rateLimiter, err := newRateLimiter(store, config.AppConfig.RateLimit)
+	if err != nil {
+		log.Fatalf("Failed to create rate limiter: %v", err)
+	}
+	oidcProviders, oidcSecret, err := newOIDCProviders(config.AppConfig.OIDC)
+	if err != nil {
+		log.Fatalf("Failed to create OIDC providers: %v", err)
+	}
+	spamFilter, err := newSpamFilter(store, config.AppConfig.Spam)
+	if err != nil {
+		log.Fatalf("Failed to create spam filter: %v", err)
+	}
+	renderer, err := markdown.New(markdown.Options{Style: config.AppConfig.Markdown.HighlightStyle})
+	if err != nil {
+		log.Fatalf("Failed to create markdown renderer: %v", err)
+	}
+	routeMW := routeMiddleware{
+		cors:            cors,
+		securityHeaders: newSecurityHeaders(config.AppConfig.Security.Headers),
+		csrf:            newCSRF(config.AppConfig.Security.CSRF, session),
+		rateLimit:       rateLimiter,
+	}
+	setupRoutes(r, tokens, routeMW, handlers.NewHandler(handlers.Deps{
+		Store:           store,
+		Tokens:          tokens,
+		Guard:           guard,
+		APIKeys:         apiKeys,
+		EmailTokens:     emailTokens,
+		OIDCProviders:   oidcProviders,
+		OIDCStateSecret: oidcSecret,
+		Mailer:          mailer,
+		Links: handlers.AccountLinks{
+			VerifyURL: config.AppConfig.Mail.VerifyURL,
+			ResetURL:  config.AppConfig.Mail.ResetURL,
+		},
+		Snapshot: snapshot,
+		Spam:     spamFilter,
+		Markdown: renderer,
+	}))
 
 	// 启动服务器
 	port := config.AppConfig.Server.Port
@@ -45,41 +137,425 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// checkSchema 检查数据库结构版本，版本落后时拒绝启动
+// 内存存储无需迁移；数据库暂不可达时跳过检查，以降级模式启动
+// 返回：
+//
+//	bool - 数据库可用且结构为最新版本时返回true
+func checkSchema(store models.Store) bool {
+	gormStore, ok := store.(*models.GormStore)
+	if !ok {
+		return true
+	}
+	if err := gormStore.Ping(); err != nil {
+		if models.IsUnavailable(err) {
+			log.Printf("Warning: 数据库暂不可用，跳过结构版本检查，以降级模式启动: %v", err)
+			return false
+		}
+		log.Fatalf("Failed to connect database: %v", err)
+	}
+
+	sqlDB, err := gormStore.DB().DB()
+	if err != nil {
+		log.Fatalf("Failed to get database instance: %v", err)
+	}
+	if err := migrate.CheckCurrent(sqlDB, gormStore.Dialect()); err != nil {
+		if errors.Is(err, migrate.ErrSchemaBehind) {
+			log.Fatalf("%v，请先运行 go run ./cmd/migrate up", err)
+		}
+		log.Fatalf("Failed to check schema version: %v", err)
+	}
+	return true
+}
+
+// loadKeySet 根据认证配置加载JWT签名密钥
+// 未配置任何密钥时生成临时的HS256密钥，仅适用于开发环境；HS256密钥仍是示例占位值时拒绝启动
+// 参数：
+//
+//	cfg - 认证配置
+//
+// 返回：
+//
+//	*middleware.KeySet - 签名密钥集合
+//	error - 密钥配置无效时返回错误
+func loadKeySet(cfg config.AuthConfig) (*middleware.KeySet, error) {
+	if len(cfg.Keys) == 0 {
+		log.Println("Warning: 未配置auth.keys，使用临时生成的签名密钥，重启后已签发的访问令牌将失效")
+		key, err := middleware.GenerateHMACKey("ephemeral")
+		if err != nil {
+			return nil, err
+		}
+		return middleware.NewKeySet(key.ID, key)
+	}
+
+	keys := make([]*middleware.SigningKey, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		if config.IsPlaceholderSecret(kc.Secret) {
+			return nil, fmt.Errorf("签名密钥%s仍是示例中的占位值，请生成随机密钥", kc.ID)
+		}
+		key, err := middleware.LoadSigningKey(kc.ID, kc.Algorithm, kc.Secret, kc.PrivateKeyFile, kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return middleware.NewKeySet(cfg.SigningKey, keys...)
+}
+
+// newEmailTokens 创建邮箱验证和密码重置令牌的签发器
+// 未配置auth.email_token_secret时生成临时密钥，仅适用于开发环境；密钥仍是示例占位值时拒绝启动
+func newEmailTokens(users models.UserStore, cfg config.AuthConfig) (*middleware.EmailTokens, error) {
+	if config.IsPlaceholderSecret(cfg.EmailTokenSecret) {
+		return nil, errors.New("auth.email_token_secret仍是示例中的占位值，请生成随机密钥")
+	}
+	secret := []byte(cfg.EmailTokenSecret)
+	if len(secret) == 0 {
+		log.Println("Warning: 未配置auth.email_token_secret，使用临时生成的密钥，重启后已发送的验证和重置链接将失效")
+		var err error
+		if secret, err = middleware.GenerateEmailTokenSecret(); err != nil {
+			return nil, err
+		}
+	}
+	return middleware.NewEmailTokens(users, secret, cfg.EmailVerifyTTL, cfg.PasswordResetTTL), nil
+}
+
+// newOIDCProviders 根据配置创建第三方登录的身份提供方
+// 未配置oidc.state_secret时生成临时密钥，重启前发起的登录无法完成；密钥仍是示例占位值时拒绝启动
+// 返回：
+//
+//	[]*oidc.Provider - 身份提供方列表，未配置时为空
+//	[]byte - 登录状态cookie的签名密钥
+//	error - 身份提供方配置不完整或名称重复时返回错误
+func newOIDCProviders(cfg config.OIDCConfig) ([]*oidc.Provider, []byte, error) {
+	if config.IsPlaceholderSecret(cfg.StateSecret) {
+		return nil, nil, errors.New("oidc.state_secret仍是示例中的占位值，请生成随机密钥")
+	}
+	providers := make([]*oidc.Provider, 0, len(cfg.Providers))
+	seen := make(map[string]bool)
+	for _, pc := range cfg.Providers {
+		if seen[pc.Name] {
+			return nil, nil, fmt.Errorf("重复的身份提供方名称: %s", pc.Name)
+		}
+		seen[pc.Name] = true
+		provider, err := oidc.NewProvider(oidc.Config{
+			Name:         pc.Name,
+			DisplayName:  pc.DisplayName,
+			Issuer:       pc.Issuer,
+			ClientID:     pc.ClientID,
+			ClientSecret: pc.ClientSecret,
+			RedirectURL:  pc.RedirectURL,
+			Scopes:       pc.Scopes,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		providers = append(providers, provider)
+	}
+
+	secret := []byte(cfg.StateSecret)
+	if len(secret) == 0 {
+		if len(providers) > 0 {
+			log.Println("Warning: 未配置oidc.state_secret，使用临时生成的密钥，多实例部署时第三方登录会失败")
+		}
+		var err error
+		if secret, err = oidc.GenerateStateSecret(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return providers, secret, nil
+}
+
+// newSpamFilter 根据配置创建垃圾内容过滤器，未启用时返回nil
+func newSpamFilter(store models.Store, cfg config.SpamConfig) (*spam.Filter, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	secret := []byte(cfg.FormSecret)
+	if len(secret) == 0 {
+		log.Println("Warning: 未配置spam.form_secret，使用临时生成的密钥，重启后已签发的表单令牌失效")
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return spam.NewFilter(store, spam.Config{
+		FormSecret:      secret,
+		MinSubmitTime:   cfg.MinSubmitTime,
+		MaxFormAge:      cfg.MaxFormAge,
+		MaxLinks:        cfg.MaxLinks,
+		ReviewThreshold: cfg.ReviewThreshold,
+		SpamThreshold:   cfg.SpamThreshold,
+	})
+}
+
+// routeMiddleware setupRoutes使用的由配置决定的中间件
+type routeMiddleware struct {
+	cors            *middleware.CORS        // /api路由组的跨域策略
+	securityHeaders gin.HandlerFunc         // 所有响应的安全响应头
+	csrf            gin.HandlerFunc         // /api路由组的CSRF防护
+	rateLimit       *middleware.RateLimiter // 按路由组限流，为nil时不限流
+}
+
+// newSecurityHeaders 根据配置创建安全响应头中间件
+func newSecurityHeaders(cfg config.SecurityHeadersConfig) gin.HandlerFunc {
+	return middleware.SecurityHeaders(middleware.SecurityHeadersPolicy{
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		HSTSMaxAge:            cfg.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.HSTSIncludeSubdomains,
+		HSTSPreload:           cfg.HSTSPreload,
+		FrameOptions:          cfg.FrameOptions,
+		ReferrerPolicy:        cfg.ReferrerPolicy,
+		PermissionsPolicy:     cfg.PermissionsPolicy,
+	})
+}
+
+// newCSRF 根据配置创建CSRF防护中间件，携带会话cookie的修改类请求需要校验CSRF令牌
+// CSRF cookie的Secure属性沿用会话cookie的配置
+func newCSRF(cfg config.CSRFConfig, session middleware.SessionCookiePolicy) gin.HandlerFunc {
+	return middleware.CSRF(middleware.CSRFPolicy{
+		CookieName:     cfg.CookieName,
+		HeaderName:     cfg.HeaderName,
+		SessionCookies: []string{session.Name, session.RefreshName},
+		Secure:         session.Secure,
+	})
+}
+
+// newSessionCookies 根据配置创建cookie会话策略
+func newSessionCookies(cfg config.SessionConfig) (middleware.SessionCookiePolicy, error) {
+	sameSite, err := middleware.ParseSameSite(cfg.SameSite)
+	if err != nil {
+		return middleware.SessionCookiePolicy{}, err
+	}
+	if sameSite == http.SameSiteNoneMode && !cfg.Secure {
+		return middleware.SessionCookiePolicy{}, errors.New("SameSite=None的cookie必须设置secure")
+	}
+	return middleware.SessionCookiePolicy{
+		Name:        cfg.CookieName,
+		RefreshName: cfg.RefreshCookieName,
+		Domain:      cfg.Domain,
+		Secure:      cfg.Secure,
+		SameSite:    sameSite,
+	}, nil
+}
+
+// newCORS 根据配置创建/api路由组的跨域策略
+func newCORS(cfg config.CORSConfig) (*middleware.CORS, error) {
+	return middleware.NewCORS(middleware.CORSPolicy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		MaxAge:           cfg.MaxAge,
+		AllowCredentials: cfg.AllowCredentials,
+	})
+}
+
+// newRateLimiter 根据配置创建限流器
+// 令牌桶存储为database时使用主存储，多个实例共享限额；memory时只在本进程内计数
+func newRateLimiter(store models.Store, cfg config.RateLimitConfig) (*middleware.RateLimiter, error) {
+	var buckets models.RateLimitStore
+	switch cfg.Store {
+	case "", "database":
+		buckets = store
+	case "memory":
+		buckets = models.NewMemoryRateLimits()
+	default:
+		return nil, fmt.Errorf("不支持的限流存储: %s", cfg.Store)
+	}
+	rules := make(map[string]middleware.RateLimitRule, len(cfg.Groups))
+	for group, rule := range cfg.Groups {
+		rules[group] = middleware.RateLimitRule{
+			Limit:  rule.Limit,
+			Period: rule.Period,
+			Burst:  rule.Burst,
+			Key:    middleware.RateLimitKey(rule.Key),
+		}
+	}
+	return middleware.NewRateLimiter(buckets, rules)
+}
+
+// newLoginGuard 根据配置创建登录保护
+// 计数存储为database时使用主存储，memory时使用进程内计数；锁定审计记录始终写入主存储
+func newLoginGuard(store models.Store, cfg config.LockoutConfig) (*middleware.LoginGuard, error) {
+	var attempts models.LoginAttemptStore
+	switch cfg.Store {
+	case "", "database":
+		attempts = store
+	case "memory":
+		attempts = models.NewMemoryLoginAttempts()
+	default:
+		return nil, fmt.Errorf("不支持的登录计数存储: %s", cfg.Store)
+	}
+	policy := func(p config.LockoutPolicyConfig) middleware.LockoutPolicy {
+		return middleware.LockoutPolicy{
+			FreeAttempts: p.FreeAttempts,
+			LockAfter:    p.LockAfter,
+			BaseDelay:    p.BaseDelay,
+			MaxDelay:     p.MaxDelay,
+			LockDuration: p.LockDuration,
+		}
+	}
+	return middleware.NewLoginGuard(attempts, store, cfg.Window, policy(cfg.User), policy(cfg.IP)), nil
+}
+
+// newMailer 根据邮件配置创建邮件发送器
+// 参数：
+//
+//	cfg - 邮件配置，driver为smtp、file或log
+//
+// 返回：
+//
+//	mail.Mailer - 邮件发送器
+//	error - driver不支持或邮件目录无法创建时返回错误
+func newMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.From), nil
+	case "file":
+		return mail.NewFileMailer(cfg.Dir, cfg.From)
+	case "", "log":
+		return mail.LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的邮件发送方式: %s", cfg.Driver)
+	}
+}
+
 // setupRoutes 配置应用程序的路由
 // 此函数设置所有API路由和页面路由
 // 参数：
-//   r - Gin引擎实例，用于注册路由
+//
+//	r - Gin引擎实例，用于注册路由
+//	tokens - 令牌管理器，提供认证中间件
+//	mw - 由配置决定的中间件：安全响应头作用于所有路由，跨域和CSRF防护作用于/api路由组
+//	h - 持有存储依赖的处理器实例
+//
 // 路由结构：
-//   1. 静态文件路由 - 用于提供静态资源文件
-//   2. API路由组 - 所有API端点的基础路径
-//      - /api/article/* - 文章相关API
-//      - /api/user/* - 用户相关API
-//   3. 首页路由 - 网站首页
-func setupRoutes(r *gin.Engine) {
-	// 添加中间件
-	r.Use(middleware.CORSMiddleware())
+//  1. 静态文件路由 - 用于提供静态资源文件
+//  2. API路由组 - 所有API端点的基础路径
+//     - /api/article/* - 文章相关API，写操作需要认证
+//     - /api/search/* - 全文搜索文章和搜索建议
+//     - /api/user/* - 用户相关API
+//     - /api/auth/oidc/* - 第三方登录
+//     - /api/admin/* - 管理API，需要user:manage权限
+//  3. JWKS路由 - 公开非对称签名密钥的公钥，供其他服务校验令牌，允许任意来源跨域读取
+//  4. 代码高亮样式表 - 按配置的配色主题生成
+//  5. 首页路由 - 网站首页
+func setupRoutes(r *gin.Engine, tokens *middleware.TokenManager, mw routeMiddleware, h *handlers.Handler) {
+	r.Use(mw.securityHeaders)
 
 	// 静态文件路由 - 提供前端资源
 	r.Static("/static", "./static")
 	// API路由组
-	api := r.Group("/api")
+	api := r.Group("/api", mw.cors.Middleware(), mw.csrf, mw.rateLimit.Middleware("api"))
 	{
+		// 认证接口是暴力破解和批量注册的目标，单独使用更严格的限额
+		authLimit := mw.rateLimit.Middleware("auth")
+		api.OPTIONS("/*path", middleware.CORSPreflight) // 预检请求由跨域中间件应答
+		api.GET("/csrf", h.GetCSRFToken)                // 获取CSRF令牌
+		api.GET("/form-token", h.GetFormToken)          // 获取评论和注册表单的防垃圾令牌
+		api.GET("/search", h.SearchArticles)            // 全文搜索已发布的文章
+		api.GET("/search/suggest", h.SuggestSearch)     // 搜索框自动补全和拼写纠错建议
 		article := api.Group("/article")
 		{
-			article.GET("/", handlers.GetArticles)       // 获取文章列表
-			article.GET("/:id", handlers.GetArticle)     // 获取单个文章
-			article.POST("/", handlers.CreateArticle)    // 创建新文章
-			article.PUT("/:id", handlers.UpdateArticle)   // 更新文章
-			article.DELETE("/:id", handlers.DeleteArticle) // 删除文章
+			// 读取不需要登录，登录后作者可以查看自己的草稿，编辑可以查看所有草稿
+			read := article.Group("/", tokens.OptionalAuthMiddleware())
+			read.GET("/", h.GetArticles)                   // 获取文章列表
+			read.GET("/:id", h.GetArticle)                 // 获取单个文章
+			read.GET("/by-slug/:slug", h.GetArticleBySlug) // 按slug获取文章，旧的slug重定向到当前的slug
+
+			// 评论：游客也可以发表，需要审核后显示
+			comments := article.Group("/:id/comments")
+			{
+				comments.GET("", h.GetComments)                           // 获取文章评论
+				comments.GET("/:comment_id/replies", h.GetCommentReplies) // 获取评论的回复
+				// 发表评论，可以不登录
+				comments.POST("", tokens.OptionalAuthMiddleware(), mw.rateLimit.Middleware("comment"), h.CreateComment)
+			}
+
+			// 写操作需要登录，作者身份取自JWT令牌
+			auth := article.Group("/", tokens.AuthMiddleware(), mw.rateLimit.Middleware("write"))
+			auth.POST("/", middleware.RequirePermission(models.PermArticleWrite), h.CreateArticle) // 创建新文章
+			auth.PUT("/:id", h.UpdateArticle)                                                      // 更新文章
+			auth.PUT("/:id/status", h.SetArticleStatus)                                            // 发布或撤回文章
+			auth.DELETE("/:id", h.DeleteArticle)                                                   // 删除文章
+		}
+		// 标签和分类：所有人可以查看，修改需要taxonomy:manage权限
+		tag := api.Group("/tag")
+		{
+			tag.GET("/", h.GetTags) // 获取标签列表
+			manage := tag.Group("/", tokens.AuthMiddleware(), middleware.RequirePermission(models.PermTaxonomyManage), mw.rateLimit.Middleware("write"))
+			manage.POST("/", h.CreateTag)      // 创建标签
+			manage.PUT("/:id", h.UpdateTag)    // 修改标签
+			manage.DELETE("/:id", h.DeleteTag) // 删除标签
+		}
+		category := api.Group("/category")
+		{
+			category.GET("/", h.GetCategories) // 获取分类列表
+			manage := category.Group("/", tokens.AuthMiddleware(), middleware.RequirePermission(models.PermTaxonomyManage), mw.rateLimit.Middleware("write"))
+			manage.POST("/", h.CreateCategory)      // 创建分类
+			manage.PUT("/:id", h.UpdateCategory)    // 修改分类
+			manage.DELETE("/:id", h.DeleteCategory) // 删除分类
 		}
 		user := api.Group("/user")
 		{
-			user.GET("/", handlers.GetUsers) // 获取用户列表
-			user.POST("/login", handlers.Login)//用户登录
-			user.POST("/register", handlers.Register)//用户注册
+			user.GET("/", h.GetUsers)                                  // 获取用户列表
+			user.POST("/login", authLimit, h.Login)                    //用户登录
+			user.POST("/login/mfa", authLimit, h.LoginMFA)             // 两步验证登录的第二步
+			user.POST("/register", authLimit, h.Register)              //用户注册
+			user.POST("/refresh", authLimit, h.Refresh)                // 使用刷新令牌换取新令牌
+			user.GET("/verify", h.VerifyEmailPage)                     // 打开验证邮件中的链接，显示确认页面
+			user.POST("/verify", authLimit, h.VerifyEmail)             // 验证邮箱
+			user.POST("/forgot-password", authLimit, h.ForgotPassword) // 发送重置密码邮件
+			user.POST("/reset-password", authLimit, h.ResetPassword)   // 使用邮件中的令牌重置密码
+
+			// 账号操作只接受访问令牌，不能使用API密钥
+			session := user.Group("/", tokens.AuthMiddleware(), middleware.RequireSession())
+			session.GET("/me", h.GetCurrentUser)            // 获取当前登录用户
+			session.POST("/logout", h.Logout)               // 退出当前会话
+			session.POST("/logout-all", h.LogoutAll)        // 退出所有会话
+			session.POST("/mfa/enroll", h.EnrollMFA)        // 开始绑定两步验证
+			session.POST("/mfa/confirm", h.ConfirmMFA)      // 确认启用两步验证
+			session.GET("/api-keys", h.GetAPIKeys)          // 获取API密钥列表
+			session.POST("/api-keys", h.CreateAPIKey)       // 创建API密钥
+			session.DELETE("/api-keys/:id", h.RevokeAPIKey) // 吊销API密钥
+		}
+		// 评论审核：拥有comment:moderate权限的用户审核所有评论，作者审核自己文章下的评论
+		comment := api.Group("/comment", tokens.AuthMiddleware())
+		{
+			comment.GET("/", h.GetModerationComments)      // 获取待审核评论
+			comment.PUT("/:id/status", h.SetCommentStatus) // 审核评论
+			comment.DELETE("/:id", h.DeleteComment)        // 删除评论及其回复
+		}
+		oidcLogin := api.Group("/auth/oidc")
+		{
+			oidcLogin.GET("/providers", h.GetOIDCProviders)                 // 获取第三方登录方式列表
+			oidcLogin.GET("/:provider/login", authLimit, h.OIDCLogin)       // 跳转到身份提供方登录
+			oidcLogin.GET("/:provider/callback", authLimit, h.OIDCCallback) // 身份提供方登录后的回调
+		}
+		admin := api.Group("/admin", tokens.AuthMiddleware(), middleware.RequirePermission(models.PermUserManage))
+		{
+			admin.GET("/roles", h.GetRoles)                             // 获取角色及权限列表
+			admin.PUT("/user/:id/role", h.UpdateUserRole)               // 修改用户角色
+			admin.POST("/user/:id/logout", h.RevokeUserSessions)        // 强制用户退出所有会话
+			admin.GET("/audit", h.GetAuditLogs)                         // 获取安全审计记录
+			admin.GET("/spam/blocklist", h.GetSpamBlocklist)            // 获取垃圾内容屏蔽列表
+			admin.POST("/spam/blocklist", h.CreateSpamBlockEntry)       // 添加屏蔽列表条目
+			admin.DELETE("/spam/blocklist/:id", h.DeleteSpamBlockEntry) // 删除屏蔽列表条目
+			admin.POST("/spam/train", h.TrainSpam)                      // 用样本训练垃圾内容分类器
 		}
 	}
-	
+
+	// 公开签名公钥
+	wellKnown := r.Group("/.well-known", middleware.PublicCORS().Middleware())
+	{
+		wellKnown.OPTIONS("/*path", middleware.CORSPreflight)
+		wellKnown.GET("/jwks.json", h.JWKS)
+	}
+
+	// 代码高亮样式表
+	r.GET("/assets/highlight.css", h.GetHighlightCSS)
+
 	// 首页路由
-	r.GET("/", handlers.GetHome)
-}
\ No newline at end of file
+	r.GET("/", h.GetHome)
+}
@@ -0,0 +1,2910 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"gofile/handlers"
+	"gofile/internal/config"
+	"gofile/internal/mail"
+	"gofile/internal/oidc"
+	"gofile/internal/spam"
+	"gofile/internal/totp"
+	"gofile/middleware"
+	"gofile/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// testServer 使用内存存储和完整路由配置的测试服务器
+type testServer struct {
+	t      *testing.T
+	store  *models.MemoryStore
+	tokens *middleware.TokenManager
+	mailer *captureMailer
+	router *gin.Engine
+}
+
+// captureMailer 记录发送的邮件，代替真实的邮件服务
+type captureMailer struct {
+	sent []*mail.Message
+}
+
+func (m *captureMailer) Send(msg *mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// lastToken 从最近一封邮件的链接中取出token参数
+func (m *captureMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	if len(m.sent) == 0 {
+		t.Fatal("no mail sent")
+	}
+	match := regexp.MustCompile(`token=([A-Za-z0-9_\-.]+)`).FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	if match == nil {
+		t.Fatalf("no token in mail body: %s", m.sent[len(m.sent)-1].Body)
+	}
+	return match[1]
+}
+
+// 测试服务器预置的用户ID
+const (
+	aliceID = 1 // 作者
+	bobID   = 2 // 作者
+	carolID = 3 // 编辑
+	daveID  = 4 // 读者
+	adminID = 5 // 管理员
+)
+
+// newTestServer 创建测试服务器，并按上面的ID顺序预置不同角色的用户
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newTestServerWithKeys(t, newTestKeySet(t))
+}
+
+// newTestKeySet 创建测试服务器默认使用的HS256签名密钥集合
+func newTestKeySet(t *testing.T) *middleware.KeySet {
+	t.Helper()
+	key, err := middleware.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("create signing key: %v", err)
+	}
+	keys, err := middleware.NewKeySet(key.ID, key)
+	if err != nil {
+		t.Fatalf("create key set: %v", err)
+	}
+	return keys
+}
+
+// newTestServerWithKeys 使用指定的签名密钥集合创建测试服务器，configure可以修改处理器依赖
+func newTestServerWithKeys(t *testing.T, keys *middleware.KeySet, configure ...func(*handlers.Deps)) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := models.NewMemoryStore()
+	users := []struct {
+		name string
+		role models.Role
+	}{
+		{"alice", models.RoleAuthor},
+		{"bob", models.RoleAuthor},
+		{"carol", models.RoleEditor},
+		{"dave", models.RoleReader},
+		{"admin", models.RoleAdmin},
+	}
+	for _, u := range users {
+		if err := store.CreateUser(&models.User{Username: u.name, Nickname: u.name, Role: u.role}); err != nil {
+			t.Fatalf("create user %s: %v", u.name, err)
+		}
+	}
+
+	tokens := middleware.NewTokenManager(store, keys, 15*time.Minute, 24*time.Hour)
+	apiKeys := middleware.NewAPIKeys(store, store)
+	tokens.UseAPIKeys(apiKeys)
+	session := middleware.SessionCookiePolicy{Name: "session", RefreshName: "session_refresh", Secure: true, SameSite: http.SameSiteLaxMode}
+	tokens.UseSessionCookies(session)
+	mailer := &captureMailer{}
+	r := gin.New()
+	// 与未配置trusted_proxies时一样不信任任何代理，客户端IP取自RemoteAddr
+	if err := r.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	// 同一用户名连续失败5次锁定；测试请求都来自同一IP，IP计数不限制
+	guard := middleware.NewLoginGuard(store, store, time.Hour,
+		middleware.LockoutPolicy{FreeAttempts: 5, LockAfter: 5, LockDuration: time.Minute},
+		middleware.LockoutPolicy{FreeAttempts: 1000},
+	)
+	deps := handlers.Deps{
+		Store:       store,
+		Tokens:      tokens,
+		Guard:       guard,
+		APIKeys:     apiKeys,
+		EmailTokens: middleware.NewEmailTokens(store, []byte("email-token-secret"), time.Hour, time.Hour),
+		Mailer:      mailer,
+		Links: handlers.AccountLinks{
+			VerifyURL: "http://blog.test/api/user/verify",
+			ResetURL:  "http://blog.test/reset-password",
+		},
+	}
+	for _, fn := range configure {
+		fn(&deps)
+	}
+	cors, err := middleware.NewCORS(middleware.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.blog.test", "https://*.preview.blog.test"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-API-Key"},
+		ExposedHeaders:   []string{"Retry-After"},
+		MaxAge:           10 * time.Minute,
+		AllowCredentials: true,
+	})
+	if err != nil {
+		t.Fatalf("create cors: %v", err)
+	}
+	// 认证接口每个IP限30次，文章写操作每个用户限20次，测试期间不会恢复
+	limiter, err := middleware.NewRateLimiter(store, map[string]middleware.RateLimitRule{
+		"auth":  {Limit: 30, Period: time.Hour},
+		"write": {Limit: 20, Period: time.Hour, Key: middleware.RateLimitByAPIKey},
+	})
+	if err != nil {
+		t.Fatalf("create rate limiter: %v", err)
+	}
+	mw := routeMiddleware{
+		cors:      cors,
+		rateLimit: limiter,
+		securityHeaders: middleware.SecurityHeaders(middleware.SecurityHeadersPolicy{
+			ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'",
+			HSTSMaxAge:            365 * 24 * time.Hour,
+			HSTSIncludeSubdomains: true,
+			FrameOptions:          "DENY",
+			ReferrerPolicy:        "strict-origin-when-cross-origin",
+		}),
+		csrf: newCSRF(config.CSRFConfig{CookieName: "csrf_token", HeaderName: "X-CSRF-Token"}, session),
+	}
+	setupRoutes(r, tokens, mw, handlers.NewHandler(deps))
+	return &testServer{t: t, store: store, tokens: tokens, mailer: mailer, router: r}
+}
+
+// token 为指定用户生成JWT令牌，角色取自存储中的用户
+func (s *testServer) token(userID uint) string {
+	s.t.Helper()
+	user, err := s.store.GetUserByID(userID)
+	if err != nil || user == nil {
+		s.t.Fatalf("get user %d: %v", userID, err)
+	}
+	token, err := s.tokens.GenerateToken(userID, user.Username, user.Role)
+	if err != nil {
+		s.t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// do 发送请求，token为空时不携带Authorization头
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			s.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// createArticle 直接在存储中为指定作者创建文章
+func (s *testServer) createArticle(userID int) *models.Article {
+	s.t.Helper()
+	article := &models.Article{Title: "标题", Content: "内容", Status: models.ArticleStatusDraft, UserID: userID}
+	if err := s.store.CreateArticle(article); err != nil {
+		s.t.Fatalf("create article: %v", err)
+	}
+	return article
+}
+
+func articlePath(id int) string {
+	return "/api/article/" + strconv.Itoa(id)
+}
+
+func TestArticleWriteRoutesRequireAuth(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	body := gin.H{"title": "新标题", "content": "新内容"}
+
+	cases := []struct {
+		method, path string
+	}{
+		{http.MethodPost, "/api/article/"},
+		{http.MethodPut, articlePath(article.ID)},
+		{http.MethodDelete, articlePath(article.ID)},
+	}
+	for _, tc := range cases {
+		if w := s.do(tc.method, tc.path, "", body); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without token: status = %d, want %d", tc.method, tc.path, w.Code, http.StatusUnauthorized)
+		}
+		if w := s.do(tc.method, tc.path, "not-a-jwt", body); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s with invalid token: status = %d, want %d", tc.method, tc.path, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got == nil || got.Title != "标题" {
+		t.Errorf("article modified by unauthenticated request: %+v", got)
+	}
+}
+
+func TestCreateArticleUsesAuthenticatedAuthor(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.do(http.MethodPost, "/api/article/", s.token(bobID), gin.H{
+		"title":   "bob的文章",
+		"content": "内容",
+		"user_id": aliceID,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Data models.Article `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.UserID != bobID {
+		t.Errorf("user_id = %d, want 2 (taken from token)", resp.Data.UserID)
+	}
+
+	stored, _ := s.store.GetArticleByID(uint(resp.Data.ID))
+	if stored == nil || stored.UserID != bobID {
+		t.Errorf("stored article = %+v, want author 2", stored)
+	}
+}
+
+func TestUpdateArticleOwnership(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	body := gin.H{"title": "修改后", "content": "修改后的内容"}
+
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(bobID), body); w.Code != http.StatusForbidden {
+		t.Errorf("non-owner update: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got.Title != "标题" {
+		t.Errorf("non-owner update changed title to %q", got.Title)
+	}
+
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(aliceID), body); w.Code != http.StatusOK {
+		t.Errorf("owner update: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got.Title != "修改后" || got.UserID != aliceID {
+		t.Errorf("owner update: got %+v", got)
+	}
+
+	if w := s.do(http.MethodPut, articlePath(999), s.token(aliceID), body); w.Code != http.StatusNotFound {
+		t.Errorf("update missing article: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestDeleteArticleOwnership(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+
+	if w := s.do(http.MethodDelete, articlePath(article.ID), s.token(bobID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("non-owner delete: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got == nil {
+		t.Fatal("non-owner delete removed the article")
+	}
+
+	if w := s.do(http.MethodDelete, articlePath(article.ID), s.token(aliceID), nil); w.Code != http.StatusOK {
+		t.Errorf("owner delete: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got != nil {
+		t.Errorf("owner delete left article: %+v", got)
+	}
+}
+
+func TestArticleViewsCountOnlyPublicReads(t *testing.T) {
+	s := newTestServer(t)
+	article := &models.Article{Title: "标题", Content: "内容", Slug: "views", Status: models.ArticleStatusPublished, UserID: aliceID}
+	if err := s.store.CreateArticle(article); err != nil {
+		t.Fatalf("create article: %v", err)
+	}
+
+	for _, path := range []string{articlePath(article.ID), articlePath(article.ID), "/api/article/by-slug/views"} {
+		if w := s.do(http.MethodGet, path, "", nil); w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body = %s", path, w.Code, w.Body)
+		}
+	}
+	// 修改和改变状态时读取文章不计入浏览量，也不能用读取时的旧值覆盖浏览量
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(aliceID), gin.H{"title": "修改后", "content": "内容"}); w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPut, articlePath(article.ID)+"/status", s.token(aliceID), gin.H{"status": models.ArticleStatusPublished}); w.Code != http.StatusOK {
+		t.Fatalf("set status: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got.Views != 3 || got.Title != "修改后" {
+		t.Errorf("views = %d title = %q, want 3 and 修改后", got.Views, got.Title)
+	}
+
+	// 请求体中的views被忽略，作者不能自行设置浏览量
+	w := s.do(http.MethodPost, "/api/article/", s.token(aliceID), gin.H{"title": "刷量", "content": "内容", "views": 1000000})
+	var created struct {
+		Data models.Article `json:"data"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &created) != nil {
+		t.Fatalf("create: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got, _ := s.store.GetArticleByID(uint(created.Data.ID)); got.Views != 0 || created.Data.Views != 0 {
+		t.Errorf("views of created article = %d (response %d), want 0", got.Views, created.Data.Views)
+	}
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(aliceID), gin.H{"title": "修改后", "content": "内容", "views": 1000000}); w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got, _ := s.store.GetArticleByID(uint(article.ID)); got.Views != 3 {
+		t.Errorf("views after update with views in body = %d, want 3", got.Views)
+	}
+}
+
+// unavailableStore 可以模拟数据库故障的内存存储，down为true时读取文章和用户返回ErrStoreUnavailable
+type unavailableStore struct {
+	*models.MemoryStore
+	down bool
+}
+
+func (s *unavailableStore) GetArticles(query models.ArticleQuery) ([]*models.Article, error) {
+	if s.down {
+		return nil, models.ErrStoreUnavailable
+	}
+	return s.MemoryStore.GetArticles(query)
+}
+
+func (s *unavailableStore) GetArticleByID(id uint) (*models.Article, error) {
+	if s.down {
+		return nil, models.ErrStoreUnavailable
+	}
+	return s.MemoryStore.GetArticleByID(id)
+}
+
+func (s *unavailableStore) GetArticleBySlug(slug string) (*models.Article, error) {
+	if s.down {
+		return nil, models.ErrStoreUnavailable
+	}
+	return s.MemoryStore.GetArticleBySlug(slug)
+}
+
+func (s *unavailableStore) GetUsers(limit, offset int) ([]*models.User, error) {
+	if s.down {
+		return nil, models.ErrStoreUnavailable
+	}
+	return s.MemoryStore.GetUsers(limit, offset)
+}
+
+// newDegradedTestServer 创建可以模拟数据库故障的测试服务器，withSnapshot为true时启用快照
+func newDegradedTestServer(t *testing.T, withSnapshot bool) (*testServer, *unavailableStore) {
+	t.Helper()
+	key, err := middleware.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("create signing key: %v", err)
+	}
+	keys, err := middleware.NewKeySet(key.ID, key)
+	if err != nil {
+		t.Fatalf("create key set: %v", err)
+	}
+	var store *unavailableStore
+	s := newTestServerWithKeys(t, keys, func(deps *handlers.Deps) {
+		store = &unavailableStore{MemoryStore: deps.Store.(*models.MemoryStore)}
+		deps.Store = store
+		if withSnapshot {
+			snapshot, err := models.OpenSnapshot(filepath.Join(t.TempDir(), "snapshot.json"), time.Hour)
+			if err != nil {
+				t.Fatalf("open snapshot: %v", err)
+			}
+			t.Cleanup(func() { snapshot.Close() })
+			deps.Snapshot = snapshot
+		}
+	})
+	return s, store
+}
+
+func TestStoreUnavailableWithoutSnapshot(t *testing.T) {
+	s, store := newDegradedTestServer(t, false)
+	article := s.createArticle(aliceID)
+	store.down = true
+
+	for _, path := range []string{"/api/article/", articlePath(article.ID), "/api/user/", "/"} {
+		w := s.do(http.MethodGet, path, "", nil)
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+			t.Errorf("GET %s: status = %d Retry-After = %q, want 503 with Retry-After", path, w.Code, w.Header().Get("Retry-After"))
+		}
+		if !strings.Contains(w.Body.String(), "store_unavailable") || w.Header().Get(handlers.HeaderDegraded) != "" {
+			t.Errorf("GET %s: body = %s degraded = %q, want store_unavailable error without degraded header", path, w.Body, w.Header().Get(handlers.HeaderDegraded))
+		}
+	}
+}
+
+func TestStoreUnavailableServesSnapshot(t *testing.T) {
+	s, store := newDegradedTestServer(t, true)
+	edited, unpublished, deleted := s.createArticle(aliceID), s.createArticle(aliceID), s.createArticle(aliceID)
+	for _, article := range []*models.Article{edited, unpublished, deleted} {
+		if w := s.do(http.MethodPut, articlePath(article.ID)+"/status", s.token(aliceID), gin.H{"status": models.ArticleStatusPublished}); w.Code != http.StatusOK {
+			t.Fatalf("publish: status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+	// 读取一次使文章进入快照
+	if w := s.do(http.MethodGet, "/api/article/?status=published", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("list: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 修改后快照中的副本随之更新
+	if w := s.do(http.MethodPut, articlePath(edited.ID), s.token(aliceID), gin.H{"title": "修改后", "content": "内容"}); w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPut, articlePath(unpublished.ID)+"/status", s.token(aliceID), gin.H{"status": models.ArticleStatusDraft}); w.Code != http.StatusOK {
+		t.Fatalf("unpublish: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodDelete, articlePath(deleted.ID), s.token(aliceID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	store.down = true
+	w := s.do(http.MethodGet, "/api/article/?status=published", "", nil)
+	if w.Code != http.StatusOK || w.Header().Get(handlers.HeaderDegraded) != "snapshot" || w.Header().Get(handlers.HeaderSnapshotAt) == "" {
+		t.Fatalf("degraded list: status = %d headers = %v, want 200 with snapshot headers", w.Code, w.Header())
+	}
+	var list struct {
+		Data []*models.Article `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].ID != edited.ID || list.Data[0].Title != "修改后" {
+		t.Errorf("degraded list = %+v, want only the edited article with its new title", list.Data)
+	}
+
+	w = s.do(http.MethodGet, articlePath(edited.ID), "", nil)
+	if w.Code != http.StatusOK || w.Header().Get(handlers.HeaderDegraded) != "snapshot" || !strings.Contains(w.Body.String(), "修改后") {
+		t.Errorf("degraded get: status = %d body = %s, want edited article from snapshot", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodGet, articlePath(deleted.ID), "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("degraded get of deleted article: status = %d, want 404", w.Code)
+	}
+	// 写操作不使用快照
+	if w := s.do(http.MethodPut, articlePath(edited.ID), s.token(aliceID), gin.H{"title": "再次修改", "content": "内容"}); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("degraded update: status = %d, want 503", w.Code)
+	}
+}
+
+func TestReaderCannotCreateArticle(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.do(http.MethodPost, "/api/article/", s.token(daveID), gin.H{"title": "标题", "content": "内容"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("reader create: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestEditorCanEditAndPublishOthersDrafts(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	publish := gin.H{"status": models.ArticleStatusPublished}
+
+	if w := s.do(http.MethodPut, articlePath(article.ID)+"/status", s.token(bobID), publish); w.Code != http.StatusForbidden {
+		t.Errorf("author publishing another author's draft: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(carolID), gin.H{"title": "编辑修改", "content": "内容"}); w.Code != http.StatusOK {
+		t.Errorf("editor update: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := s.do(http.MethodPut, articlePath(article.ID)+"/status", s.token(carolID), publish); w.Code != http.StatusOK {
+		t.Errorf("editor publish: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	got, _ := s.store.GetArticleByID(uint(article.ID))
+	if got.Status != models.ArticleStatusPublished || got.Title != "编辑修改" || got.UserID != aliceID {
+		t.Errorf("after editor changes: got %+v", got)
+	}
+
+	// 编辑没有article:delete_any权限
+	if w := s.do(http.MethodDelete, articlePath(article.ID), s.token(carolID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("editor delete: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestArticleListPagination(t *testing.T) {
+	s := newTestServer(t)
+	for i := 0; i < 60; i++ {
+		article := &models.Article{Title: "标题", Content: "内容", Slug: fmt.Sprintf("a%d", i), Status: models.ArticleStatusPublished, UserID: aliceID}
+		if err := s.store.CreateArticle(article); err != nil {
+			t.Fatalf("create article: %v", err)
+		}
+	}
+	count := func(query string) (int, int) {
+		t.Helper()
+		var resp struct {
+			Data []models.Article `json:"data"`
+		}
+		w := s.do(http.MethodGet, "/api/article/?"+query, "", nil)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil || len(resp.Data) == 0 {
+			t.Fatalf("list %s: status = %d, body = %s", query, w.Code, w.Body)
+		}
+		return len(resp.Data), resp.Data[0].ID
+	}
+	_, firstID := count("")
+	// limit不大于0时使用默认值，超过上限时取上限；page小于1时视为第一页
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"limit=0", 10},
+		{"limit=-1", 10},
+		{"limit=1000", 50},
+		{"page=0&limit=5", 5},
+		{"page=-3", 10},
+	}
+	for _, tt := range tests {
+		if n, first := count(tt.query); n != tt.want || first != firstID {
+			t.Errorf("%s: %d articles starting at %d, want %d starting at %d", tt.query, n, first, tt.want, firstID)
+		}
+	}
+}
+
+func TestDraftVisibility(t *testing.T) {
+	s := newTestServer(t)
+	create := func(userID int, slug, status string) *models.Article {
+		article := &models.Article{Title: slug, Content: "内容", Slug: slug, Status: status, UserID: userID}
+		if err := s.store.CreateArticle(article); err != nil {
+			t.Fatalf("create article: %v", err)
+		}
+		return article
+	}
+	published := create(aliceID, "published", models.ArticleStatusPublished)
+	aliceDraft := create(aliceID, "alice-draft", models.ArticleStatusDraft)
+	bobDraft := create(bobID, "bob-draft", models.ArticleStatusDraft)
+	list := func(query, token string) (int, []int) {
+		t.Helper()
+		w := s.do(http.MethodGet, "/api/article/"+query, token, nil)
+		var resp struct {
+			Data []*models.Article `json:"data"`
+		}
+		if w.Code == http.StatusOK && json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("decode list: %s", w.Body)
+		}
+		ids := make([]int, len(resp.Data))
+		for i, a := range resp.Data {
+			ids[i] = a.ID
+		}
+		return w.Code, ids
+	}
+
+	// 列表默认只返回已发布的文章，登录的作者和编辑也一样
+	for _, token := range []string{"", s.token(aliceID), s.token(carolID)} {
+		if code, ids := list("", token); code != http.StatusOK || fmt.Sprint(ids) != fmt.Sprint([]int{published.ID}) {
+			t.Errorf("default list: status = %d ids = %v, want only the published article", code, ids)
+		}
+	}
+	draftLists := []struct {
+		name  string
+		token string
+		code  int
+		want  []int
+	}{
+		{"anonymous", "", http.StatusUnauthorized, nil},
+		{"reader", s.token(daveID), http.StatusForbidden, nil},
+		{"author", s.token(aliceID), http.StatusOK, []int{aliceDraft.ID}},
+		{"editor", s.token(carolID), http.StatusOK, []int{bobDraft.ID, aliceDraft.ID}},
+	}
+	for _, tc := range draftLists {
+		code, ids := list("?status=draft", tc.token)
+		if code != tc.code || (code == http.StatusOK && fmt.Sprint(ids) != fmt.Sprint(tc.want)) {
+			t.Errorf("%s draft list: status = %d ids = %v, want %d %v", tc.name, code, ids, tc.code, tc.want)
+		}
+	}
+	if code, _ := list("?status=deleted", ""); code != http.StatusBadRequest {
+		t.Errorf("invalid status: status = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// 草稿详情只有作者和编辑可见，其他人与文章不存在时相同
+	reads := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"anonymous", "", http.StatusNotFound},
+		{"other author", s.token(bobID), http.StatusNotFound},
+		{"reader", s.token(daveID), http.StatusNotFound},
+		{"owner", s.token(aliceID), http.StatusOK},
+		{"editor", s.token(carolID), http.StatusOK},
+	}
+	for _, tc := range reads {
+		for _, path := range []string{articlePath(aliceDraft.ID), "/api/article/by-slug/alice-draft"} {
+			if w := s.do(http.MethodGet, path, tc.token, nil); w.Code != tc.code {
+				t.Errorf("%s GET %s: status = %d, want %d", tc.name, path, w.Code, tc.code)
+			}
+		}
+		if w := s.do(http.MethodGet, articlePath(published.ID), tc.token, nil); w.Code != http.StatusOK {
+			t.Errorf("%s GET published: status = %d, want %d", tc.name, w.Code, http.StatusOK)
+		}
+	}
+	// 预览草稿不计入浏览量
+	if got, _ := s.store.GetArticleByID(uint(aliceDraft.ID)); got.Views != 0 {
+		t.Errorf("draft views = %d, want 0", got.Views)
+	}
+}
+
+func TestAdminUpdatesUserRole(t *testing.T) {
+	s := newTestServer(t)
+	path := "/api/admin/user/" + strconv.Itoa(daveID) + "/role"
+
+	if w := s.do(http.MethodPut, path, s.token(carolID), gin.H{"role": "author"}); w.Code != http.StatusForbidden {
+		t.Errorf("editor changing role: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodPut, path, s.token(adminID), gin.H{"role": "superuser"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid role: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := s.do(http.MethodPut, path, s.token(adminID), gin.H{"role": "author"}); w.Code != http.StatusOK {
+		t.Fatalf("admin changing role: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if user, _ := s.store.GetUserByID(daveID); user.Role != models.RoleAuthor {
+		t.Errorf("role = %q, want %q", user.Role, models.RoleAuthor)
+	}
+
+	// 新令牌携带新角色后即可发表文章
+	w := s.do(http.MethodPost, "/api/article/", s.token(daveID), gin.H{"title": "标题", "content": "内容"})
+	if w.Code != http.StatusOK {
+		t.Errorf("promoted user create: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRoleChangeRevokesSessions(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	oldToken := s.token(carolID)
+	refresh, err := s.tokens.IssueRefreshToken(carolID, "")
+	if err != nil {
+		t.Fatalf("issue refresh token: %v", err)
+	}
+	edit := gin.H{"title": "编辑修改", "content": "内容"}
+	if w := s.do(http.MethodPut, articlePath(article.ID), oldToken, edit); w.Code != http.StatusOK {
+		t.Fatalf("editor update before demotion: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	path := "/api/admin/user/" + strconv.Itoa(carolID) + "/role"
+	if w := s.do(http.MethodPut, path, s.token(adminID), gin.H{"role": "reader"}); w.Code != http.StatusOK {
+		t.Fatalf("demote editor: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 携带旧角色的访问令牌和刷新令牌都已失效
+	if w := s.do(http.MethodPut, articlePath(article.ID), oldToken, edit); w.Code != http.StatusUnauthorized {
+		t.Errorf("old token after demotion: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := s.do(http.MethodPost, "/api/user/refresh", "", gin.H{"refresh_token": refresh}); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after demotion: status = %d, want %d, body = %s", w.Code, http.StatusUnauthorized, w.Body)
+	}
+	// 重新登录后的令牌只有读者的权限
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(carolID), edit); w.Code != http.StatusForbidden {
+		t.Errorf("new token after demotion: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestRefreshTokenRotationAndReuseDetection(t *testing.T) {
+	s := newTestServer(t)
+	first, err := s.tokens.IssueRefreshToken(aliceID, "")
+	if err != nil {
+		t.Fatalf("issue refresh token: %v", err)
+	}
+
+	w := s.do(http.MethodPost, "/api/user/refresh", "", gin.H{"refresh_token": first})
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		Data struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	second := resp.Data.RefreshToken
+	if resp.Data.Token == "" || second == "" || second == first {
+		t.Fatalf("refresh returned token=%q refresh_token=%q", resp.Data.Token, second)
+	}
+
+	// 重复使用已轮换的令牌会吊销整个家族，包括刚签发的新令牌
+	if w := s.do(http.MethodPost, "/api/user/refresh", "", gin.H{"refresh_token": first}); w.Code != http.StatusUnauthorized {
+		t.Errorf("reusing rotated token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := s.do(http.MethodPost, "/api/user/refresh", "", gin.H{"refresh_token": second}); w.Code != http.StatusUnauthorized {
+		t.Errorf("token from revoked family: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLogoutRevokesAccessAndRefreshTokens(t *testing.T) {
+	s := newTestServer(t)
+	token := s.token(aliceID)
+	refresh, err := s.tokens.IssueRefreshToken(aliceID, "")
+	if err != nil {
+		t.Fatalf("issue refresh token: %v", err)
+	}
+	other := s.token(aliceID)
+
+	if w := s.do(http.MethodPost, "/api/user/logout", token, gin.H{"refresh_token": refresh}); w.Code != http.StatusOK {
+		t.Fatalf("logout: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	body := gin.H{"title": "标题", "content": "内容"}
+	if w := s.do(http.MethodPost, "/api/article/", token, body); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked access token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := s.do(http.MethodPost, "/api/user/refresh", "", gin.H{"refresh_token": refresh}); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked refresh token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	// 只退出当前会话，其他令牌仍然有效
+	if w := s.do(http.MethodPost, "/api/article/", other, body); w.Code != http.StatusOK {
+		t.Errorf("other session: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if w := s.do(http.MethodPost, "/api/user/logout-all", other, nil); w.Code != http.StatusOK {
+		t.Fatalf("logout-all: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := s.do(http.MethodPost, "/api/article/", other, body); w.Code != http.StatusUnauthorized {
+		t.Errorf("after logout-all: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSigningKeyRotationAndJWKS(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	newKey, err := middleware.NewEdDSAKey("ed-1", private, nil)
+	if err != nil {
+		t.Fatalf("create eddsa key: %v", err)
+	}
+	oldKey, err := middleware.NewHMACKey("hs-1", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("create hmac key: %v", err)
+	}
+	keys, err := middleware.NewKeySet("ed-1", newKey, oldKey)
+	if err != nil {
+		t.Fatalf("create key set: %v", err)
+	}
+	s := newTestServerWithKeys(t, keys)
+	body := gin.H{"title": "标题", "content": "内容"}
+
+	// 新令牌使用活动的EdDSA密钥签发
+	if w := s.do(http.MethodPost, "/api/article/", s.token(aliceID), body); w.Code != http.StatusOK {
+		t.Fatalf("eddsa token: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// 轮换前由旧密钥签发的令牌仍然有效
+	oldKeys, err := middleware.NewKeySet("hs-1", oldKey)
+	if err != nil {
+		t.Fatalf("create old key set: %v", err)
+	}
+	oldTokens := middleware.NewTokenManager(s.store, oldKeys, time.Minute, time.Hour)
+	oldToken, err := oldTokens.GenerateToken(aliceID, "alice", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("generate old token: %v", err)
+	}
+	if w := s.do(http.MethodPost, "/api/article/", oldToken, body); w.Code != http.StatusOK {
+		t.Errorf("token signed with rotated key: status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// 未知kid签发的令牌被拒绝
+	otherKey, _ := middleware.NewHMACKey("other", []byte("fedcba9876543210fedcba9876543210"))
+	otherKeys, _ := middleware.NewKeySet("other", otherKey)
+	otherToken, err := middleware.NewTokenManager(s.store, otherKeys, time.Minute, time.Hour).GenerateToken(aliceID, "alice", models.RoleAuthor)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	if w := s.do(http.MethodPost, "/api/article/", otherToken, body); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown kid: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// JWKS只公开非对称密钥
+	w := s.do(http.MethodGet, "/.well-known/jwks.json", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("jwks: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var jwks middleware.JWKS
+	if err := json.Unmarshal(w.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("decode jwks: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "ed-1" || jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].X == "" {
+		t.Errorf("jwks = %+v, want only the ed-1 public key", jwks.Keys)
+	}
+}
+
+func TestPlaceholderSecretsRejected(t *testing.T) {
+	key := func(secret string) config.AuthConfig {
+		return config.AuthConfig{SigningKey: "hs-1", Keys: []config.SigningKeyConfig{
+			{ID: "hs-1", Algorithm: "HS256", Secret: secret},
+		}}
+	}
+	if _, err := loadKeySet(key("please-change-this-secret-in-production")); err == nil {
+		t.Error("signing key with the example placeholder secret was accepted")
+	}
+	if _, err := loadKeySet(key(strings.Repeat("k", 32))); err != nil {
+		t.Errorf("load signing key: %v", err)
+	}
+	if _, err := loadKeySet(config.AuthConfig{}); err != nil {
+		t.Errorf("load ephemeral signing key: %v", err)
+	}
+}
+
+func TestPlaceholderEmailTokenSecretRejected(t *testing.T) {
+	store := models.NewMemoryStore()
+	if _, err := newEmailTokens(store, config.AuthConfig{EmailTokenSecret: "please-change-this-email-token-secret"}); err == nil {
+		t.Error("email token secret with the example placeholder was accepted")
+	}
+	if _, err := newEmailTokens(store, config.AuthConfig{}); err != nil {
+		t.Errorf("generate email token secret: %v", err)
+	}
+}
+
+func TestPlaceholderOIDCStateSecretRejected(t *testing.T) {
+	if _, _, err := newOIDCProviders(config.OIDCConfig{StateSecret: "please-change-this-oidc-state-secret"}); err == nil {
+		t.Error("oidc state secret with the example placeholder was accepted")
+	}
+	if _, secret, err := newOIDCProviders(config.OIDCConfig{}); err != nil || len(secret) == 0 {
+		t.Errorf("generate oidc state secret = %d bytes, %v", len(secret), err)
+	}
+}
+
+func TestEmailOnlyInAccountResponses(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.store.GetUserByID(aliceID)
+	alice.Email, alice.EmailVerified = "alice@example.com", true
+	if err := s.store.UpdateUser(alice); err != nil {
+		t.Fatal(err)
+	}
+	article := &models.Article{Title: "标题", Content: "内容", Slug: "public", Status: models.ArticleStatusPublished, UserID: aliceID}
+	if err := s.store.CreateArticle(article); err != nil {
+		t.Fatalf("create article: %v", err)
+	}
+
+	// 公开的用户列表和文章作者不包含邮箱
+	for _, path := range []string{"/api/user/", "/api/article/", articlePath(article.ID), "/api/article/by-slug/public"} {
+		w := s.do(http.MethodGet, path, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body = %s", path, w.Code, w.Body)
+		}
+		if body := w.Body.String(); strings.Contains(body, "alice@example.com") || strings.Contains(body, "email") {
+			t.Errorf("GET %s exposes email: %s", path, body)
+		}
+	}
+
+	// 用户本人和管理员能看到邮箱
+	if w := s.do(http.MethodGet, "/api/user/me", s.token(aliceID), nil); !strings.Contains(w.Body.String(), `"email":"alice@example.com","email_verified":true`) {
+		t.Errorf("/me: status = %d, body = %s, want email", w.Code, w.Body)
+	}
+	w := s.do(http.MethodPut, fmt.Sprintf("/api/admin/user/%d/role", aliceID), s.token(adminID), gin.H{"role": models.RoleEditor})
+	if !strings.Contains(w.Body.String(), `"email":"alice@example.com"`) {
+		t.Errorf("admin role update: status = %d, body = %s, want email", w.Code, w.Body)
+	}
+}
+
+func TestRegisterAndVerifyEmail(t *testing.T) {
+	s := newTestServer(t)
+	body := gin.H{"username": "erin", "password": "secret", "nickname": "Erin", "email": "Erin@Example.com"}
+	if w := s.do(http.MethodPost, "/api/user/register", "", body); w.Code != http.StatusOK {
+		t.Fatalf("register: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/api/user/register", "", gin.H{"username": "erin2", "password": "secret", "nickname": "Erin", "email": "erin@example.com"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate email: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	user, err := s.store.GetUserByUsername("erin")
+	if err != nil || user == nil {
+		t.Fatalf("get user: %v", err)
+	}
+	if user.Email != "erin@example.com" || user.EmailVerified {
+		t.Fatalf("registered user email = %q verified = %v", user.Email, user.EmailVerified)
+	}
+	if len(s.mailer.sent) != 1 || s.mailer.sent[0].To != "erin@example.com" {
+		t.Fatalf("verification mail not sent: %+v", s.mailer.sent)
+	}
+
+	// 打开链接只显示确认页面，不消耗令牌，链接可以被预先访问任意次
+	token := s.mailer.lastToken(t)
+	var page *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		page = s.do(http.MethodGet, "/api/user/verify?token="+token, "", nil)
+		if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), `method="post"`) {
+			t.Fatalf("verify page: status = %d, want %d, body = %s", page.Code, http.StatusOK, page.Body)
+		}
+	}
+	if user, _ := s.store.GetUserByUsername("erin"); user.EmailVerified {
+		t.Fatal("opening the verify link marked the email as verified")
+	}
+
+	// 确认页面的表单以POST提交令牌；携带会话cookie时还需要表单中的CSRF令牌
+	csrf := responseCookies(page)["csrf_token"]
+	field := regexp.MustCompile(`name="csrf_token" value="([^"]+)"`).FindStringSubmatch(page.Body.String())
+	if csrf == nil || field == nil || field[1] != csrf.Value {
+		t.Fatalf("verify page csrf token = %v, cookie = %v", field, csrf)
+	}
+	submit := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/verify", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(csrf)
+		req.AddCookie(&http.Cookie{Name: "session", Value: "x"})
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	if w := submit(url.Values{"token": {token}}); w.Code != http.StatusForbidden {
+		t.Errorf("confirm without csrf token: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := submit(url.Values{"token": {token}, "csrf_token": {field[1]}}); w.Code != http.StatusOK {
+		t.Fatalf("confirm: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if user, _ := s.store.GetUserByUsername("erin"); !user.EmailVerified {
+		t.Error("email not marked as verified")
+	}
+	if w := s.do(http.MethodGet, "/api/user/verify?token="+token, "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("verify page for used token: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := s.do(http.MethodPost, "/api/user/verify", "", gin.H{"token": token}); w.Code != http.StatusBadRequest {
+		t.Errorf("reused verify token: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestForgotAndResetPassword(t *testing.T) {
+	s := newTestServer(t)
+	body := gin.H{"username": "erin", "password": "old-secret", "nickname": "Erin", "email": "erin@example.com"}
+	if w := s.do(http.MethodPost, "/api/user/register", "", body); w.Code != http.StatusOK {
+		t.Fatalf("register: status = %d, body = %s", w.Code, w.Body)
+	}
+	login := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "erin", "password": "old-secret"})
+	var resp struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(login.Body.Bytes(), &resp); err != nil || resp.Data.Token == "" {
+		t.Fatalf("login: status = %d, body = %s", login.Code, login.Body)
+	}
+
+	// 未注册的邮箱返回相同的响应，但不发送邮件
+	sent := len(s.mailer.sent)
+	if w := s.do(http.MethodPost, "/api/user/forgot-password", "", gin.H{"email": "nobody@example.com"}); w.Code != http.StatusOK {
+		t.Errorf("unknown email: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(s.mailer.sent) != sent {
+		t.Errorf("mail sent for unknown email")
+	}
+
+	if w := s.do(http.MethodPost, "/api/user/forgot-password", "", gin.H{"email": "erin@example.com"}); w.Code != http.StatusOK {
+		t.Fatalf("forgot password: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	token := s.mailer.lastToken(t)
+	if w := s.do(http.MethodPost, "/api/user/verify", "", gin.H{"token": token}); w.Code != http.StatusBadRequest {
+		t.Errorf("reset token used for verify: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := s.do(http.MethodPost, "/api/user/reset-password", "", gin.H{"token": token, "password": "new-secret"}); w.Code != http.StatusOK {
+		t.Fatalf("reset password: status = %d, want %d, body = %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/api/user/reset-password", "", gin.H{"token": token, "password": "other"}); w.Code != http.StatusBadRequest {
+		t.Errorf("reused reset token: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 重置后旧会话失效，只能使用新密码登录
+	if w := s.do(http.MethodPost, "/api/user/logout", resp.Data.Token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("old session after reset: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "erin", "password": "old-secret"}); w.Code == http.StatusOK {
+		t.Error("old password still accepted")
+	}
+	w := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "erin", "password": "new-secret"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login with new password: status = %d, body = %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode login: %v", err)
+	}
+	if w := s.do(http.MethodPost, "/api/user/logout", resp.Data.Token, nil); w.Code != http.StatusOK {
+		t.Errorf("new session: status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestTOTPEnrollmentAndTwoStepLogin(t *testing.T) {
+	s := newTestServer(t)
+	body := gin.H{"username": "erin", "password": "secret", "nickname": "Erin", "email": "erin@example.com"}
+	if w := s.do(http.MethodPost, "/api/user/register", "", body); w.Code != http.StatusOK {
+		t.Fatalf("register: status = %d, body = %s", w.Code, w.Body)
+	}
+	type loginResponse struct {
+		Data struct {
+			Token       string `json:"token"`
+			MFARequired bool   `json:"mfa_required"`
+			MFAToken    string `json:"mfa_token"`
+		} `json:"data"`
+	}
+	var login loginResponse
+	decode := func(w *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode response: %v, body = %s", err, w.Body)
+		}
+	}
+	credentials := gin.H{"username": "erin", "password": "secret"}
+	login = loginResponse{}
+	decode(s.do(http.MethodPost, "/api/user/login", "", credentials), &login)
+	token := login.Data.Token
+
+	// 绑定：获取密钥并用第一个验证码确认
+	w := s.do(http.MethodPost, "/api/user/mfa/enroll", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enroll: status = %d, body = %s", w.Code, w.Body)
+	}
+	var enroll struct {
+		Data struct {
+			Secret     string `json:"secret"`
+			OTPAuthURI string `json:"otpauth_uri"`
+		} `json:"data"`
+	}
+	decode(w, &enroll)
+	if enroll.Data.Secret == "" || !strings.HasPrefix(enroll.Data.OTPAuthURI, "otpauth://totp/") {
+		t.Fatalf("enroll response = %+v", enroll.Data)
+	}
+	step := totp.Step(time.Now())
+	code := func(step int64) string {
+		c, err := totp.Code(enroll.Data.Secret, step)
+		if err != nil {
+			t.Fatalf("totp code: %v", err)
+		}
+		return c
+	}
+	if w := s.do(http.MethodPost, "/api/user/mfa/confirm", token, gin.H{"code": "000000"}); w.Code != http.StatusBadRequest && code(step) != "000000" {
+		t.Errorf("confirm with wrong code: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	w = s.do(http.MethodPost, "/api/user/mfa/confirm", token, gin.H{"code": code(step)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("confirm: status = %d, body = %s", w.Code, w.Body)
+	}
+	var confirm struct {
+		Data struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		} `json:"data"`
+	}
+	decode(w, &confirm)
+	if len(confirm.Data.RecoveryCodes) == 0 {
+		t.Fatal("no recovery codes returned")
+	}
+
+	// 密码登录只返回待定令牌，待定令牌不能访问需要认证的接口
+	login = loginResponse{}
+	decode(s.do(http.MethodPost, "/api/user/login", "", credentials), &login)
+	if !login.Data.MFARequired || login.Data.MFAToken == "" || login.Data.Token != "" {
+		t.Fatalf("login with mfa enabled = %+v", login.Data)
+	}
+	pending := login.Data.MFAToken
+	if w := s.do(http.MethodPost, "/api/user/logout", pending, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("pending token as access token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// 确认时使用过的验证码不能重放
+	if w := s.do(http.MethodPost, "/api/user/login/mfa", "", gin.H{"mfa_token": pending, "code": code(step)}); w.Code != http.StatusUnauthorized {
+		t.Errorf("replayed code: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	w = s.do(http.MethodPost, "/api/user/login/mfa", "", gin.H{"mfa_token": pending, "code": code(step + 1)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login with code: status = %d, body = %s", w.Code, w.Body)
+	}
+	login = loginResponse{}
+	decode(w, &login)
+	if login.Data.Token == "" {
+		t.Fatal("no access token after mfa")
+	}
+	if w := s.do(http.MethodPost, "/api/user/login/mfa", "", gin.H{"mfa_token": pending, "recovery_code": confirm.Data.RecoveryCodes[0]}); w.Code != http.StatusUnauthorized {
+		t.Errorf("reused pending token: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// 恢复码只能使用一次
+	recovery := strings.ToUpper(confirm.Data.RecoveryCodes[0])
+	login = loginResponse{}
+	decode(s.do(http.MethodPost, "/api/user/login", "", credentials), &login)
+	if w := s.do(http.MethodPost, "/api/user/login/mfa", "", gin.H{"mfa_token": login.Data.MFAToken, "recovery_code": recovery}); w.Code != http.StatusOK {
+		t.Fatalf("login with recovery code: status = %d, body = %s", w.Code, w.Body)
+	}
+	login = loginResponse{}
+	decode(s.do(http.MethodPost, "/api/user/login", "", credentials), &login)
+	if w := s.do(http.MethodPost, "/api/user/login/mfa", "", gin.H{"mfa_token": login.Data.MFAToken, "recovery_code": recovery}); w.Code != http.StatusUnauthorized {
+		t.Errorf("reused recovery code: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := newTestServer(t)
+	body := gin.H{"username": "erin", "password": "secret", "nickname": "Erin", "email": "erin@example.com"}
+	if w := s.do(http.MethodPost, "/api/user/register", "", body); w.Code != http.StatusOK {
+		t.Fatalf("register: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 用户不存在和密码错误的响应相同
+	unknown := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "nobody", "password": "secret"})
+	wrong := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "erin", "password": "wrong"})
+	if unknown.Code != http.StatusUnauthorized || wrong.Code != http.StatusUnauthorized || unknown.Body.String() != wrong.Body.String() {
+		t.Errorf("unknown user = %d %s, wrong password = %d %s", unknown.Code, unknown.Body, wrong.Code, wrong.Body)
+	}
+
+	for i := 0; i < 4; i++ {
+		if w := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "erin", "password": "wrong"}); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want %d", i+2, w.Code, http.StatusUnauthorized)
+		}
+	}
+	// 锁定期间即使密码正确也拒绝登录，用户名大小写不影响计数
+	w := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "ERIN", "password": "secret"})
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("locked login: status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "alice", "password": "x"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("other user: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w = s.do(http.MethodGet, "/api/admin/audit?action=login.lockout", s.token(adminID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit: status = %d, body = %s", w.Code, w.Body)
+	}
+	var audit struct {
+		Data []models.AuditLog `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &audit); err != nil {
+		t.Fatalf("decode audit: %v", err)
+	}
+	if len(audit.Data) != 1 || audit.Data[0].Target != "user:erin" || audit.Data[0].UserID == nil {
+		t.Errorf("audit entries = %+v, want one lockout for user:erin", audit.Data)
+	}
+}
+
+func TestLoginLockoutIgnoresForwardedFor(t *testing.T) {
+	// 同一IP失败3次后锁定，用户名不限制
+	s := newTestServerWithKeys(t, newTestKeySet(t), func(d *handlers.Deps) {
+		d.Guard = middleware.NewLoginGuard(d.Store, d.Store, time.Hour,
+			middleware.LockoutPolicy{FreeAttempts: 1000},
+			middleware.LockoutPolicy{FreeAttempts: 3, LockAfter: 3, LockDuration: time.Minute},
+		)
+	})
+	login := func(n int) *httptest.ResponseRecorder {
+		body := strings.NewReader(fmt.Sprintf(`{"username":"user%d","password":"wrong"}`, n))
+		req := httptest.NewRequest(http.MethodPost, "/api/user/login", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:40000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("192.0.2.%d", n))
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	// 每次伪造不同的X-Forwarded-For和用户名，仍按连接地址计数
+	for i := 0; i < 3; i++ {
+		if w := login(i); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want %d", i+1, w.Code, http.StatusUnauthorized)
+		}
+	}
+	if w := login(3); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("after ip lockout: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	var audit struct {
+		Data []models.AuditLog `json:"data"`
+	}
+	w := s.do(http.MethodGet, "/api/admin/audit?action=login.lockout", s.token(adminID), nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &audit); err != nil || len(audit.Data) != 1 {
+		t.Fatalf("audit: status = %d, body = %s", w.Code, w.Body)
+	}
+	if entry := audit.Data[0]; entry.IP != "203.0.113.7" || entry.Target != "ip:203.0.113.7" {
+		t.Errorf("lockout audit entry = %+v, want the connection IP", entry)
+	}
+}
+
+func TestAPIKeyScopesAndRevocation(t *testing.T) {
+	s := newTestServer(t)
+	session := s.token(carolID)
+
+	if w := s.do(http.MethodPost, "/api/user/api-keys", session, gin.H{"name": "ci", "scopes": []string{"user:manage"}}); w.Code != http.StatusForbidden {
+		t.Errorf("scope beyond role: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodPost, "/api/user/api-keys", session, gin.H{"name": "ci", "scopes": []string{"article:fly"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown scope: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := s.do(http.MethodPost, "/api/user/api-keys", session, gin.H{"name": "release-notes-ci", "scopes": []string{"article:write"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("create key: status = %d, body = %s", w.Code, w.Body)
+	}
+	var created struct {
+		Data struct {
+			Key    string        `json:"key"`
+			APIKey models.APIKey `json:"api_key"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode key: %v", err)
+	}
+	key := created.Data.Key
+	if !strings.HasPrefix(key, created.Data.APIKey.Prefix) || strings.Contains(w.Body.String(), "key_hash") {
+		t.Fatalf("created key = %q, record = %+v", key, created.Data.APIKey)
+	}
+
+	// Authorization: Bearer和X-API-Key两种方式都可以使用密钥
+	if w := s.do(http.MethodPost, "/api/article/", key, gin.H{"title": "发布说明", "content": "内容"}); w.Code != http.StatusOK {
+		t.Fatalf("create article with bearer key: status = %d, body = %s", w.Code, w.Body)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/article/", strings.NewReader(`{"title":"发布说明2","content":"内容"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.APIKeyHeader, key)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create article with X-API-Key: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	// 编辑角色可以修改他人文章，但密钥只授予了article:write
+	article := s.createArticle(aliceID)
+	if w := s.do(http.MethodPut, articlePath(article.ID), key, gin.H{"title": "新标题", "content": "内容"}); w.Code != http.StatusForbidden {
+		t.Errorf("edit other's article with key: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodPut, articlePath(article.ID), session, gin.H{"title": "新标题", "content": "内容"}); w.Code != http.StatusOK {
+		t.Errorf("edit other's article with session: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	// 密钥不能用于账号操作，包括创建新密钥
+	if w := s.do(http.MethodPost, "/api/user/api-keys", key, gin.H{"name": "more", "scopes": []string{"article:write"}}); w.Code != http.StatusForbidden {
+		t.Errorf("create key with key: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = s.do(http.MethodGet, "/api/user/api-keys", session, nil)
+	var list struct {
+		Data []models.APIKey `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list.Data) != 1 || list.Data[0].LastUsedAt == nil || list.Data[0].Name != "release-notes-ci" {
+		t.Fatalf("keys = %+v, want one used key", list.Data)
+	}
+
+	path := "/api/user/api-keys/" + strconv.Itoa(list.Data[0].ID)
+	if w := s.do(http.MethodDelete, path, s.token(aliceID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoke other's key: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := s.do(http.MethodDelete, path, session, nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke key: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/api/article/", key, gin.H{"title": "标题", "content": "内容"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := s.do(http.MethodDelete, path, session, nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoke twice: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+// mockIssuer 本地的OpenID Connect身份提供方，实现元数据、授权、令牌和JWKS端点
+// 授权端点不显示登录页面，直接以next中的声明为当前用户签发授权码；令牌端点校验PKCE
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	next   jwt.MapClaims
+	codes  map[string]mockAuthCode
+}
+
+// mockAuthCode 授权码绑定的请求数据
+type mockAuthCode struct {
+	nonce     string
+	challenge string
+	claims    jwt.MapClaims
+}
+
+const mockClientID = "blog-client"
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	m := &mockIssuer{t: t, key: key, codes: make(map[string]mockAuthCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                m.server.URL,
+			"authorization_endpoint":                m.server.URL + "/authorize",
+			"token_endpoint":                        m.server.URL + "/token",
+			"jwks_uri":                              m.server.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "mock-1",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != mockClientID || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "invalid authorization request", http.StatusBadRequest)
+			return
+		}
+		code := strconv.Itoa(len(m.codes) + 1)
+		m.codes[code] = mockAuthCode{nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), claims: m.next}
+		redirect, _ := url.Parse(q.Get("redirect_uri"))
+		rq := redirect.Query()
+		rq.Set("code", code)
+		rq.Set("state", q.Get("state"))
+		redirect.RawQuery = rq.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		code, ok := m.codes[r.FormValue("code")]
+		delete(m.codes, r.FormValue("code"))
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   m.server.URL,
+			"aud":   mockClientID,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": code.nonce,
+		}
+		for k, v := range code.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "mock-1"
+		idToken, err := token.SignedString(m.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "mock-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// newOIDCTestServer 创建配置了mock身份提供方的测试服务器
+func newOIDCTestServer(t *testing.T, issuer *mockIssuer) *testServer {
+	t.Helper()
+	provider, err := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       issuer.server.URL,
+		ClientID:     mockClientID,
+		ClientSecret: "mock-secret",
+		RedirectURL:  "http://blog.test/api/auth/oidc/mock/callback",
+		Scopes:       []string{"email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	key, err := middleware.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("create signing key: %v", err)
+	}
+	keys, err := middleware.NewKeySet(key.ID, key)
+	if err != nil {
+		t.Fatalf("create key set: %v", err)
+	}
+	return newTestServerWithKeys(t, keys, func(deps *handlers.Deps) {
+		deps.OIDCProviders = []*oidc.Provider{provider}
+		deps.OIDCStateSecret = []byte("oidc-state-secret")
+	})
+}
+
+// oidcLogin 以claims为身份提供方中的用户完成一次完整的第三方登录，返回回调的响应
+func (s *testServer) oidcLogin(issuer *mockIssuer, claims jwt.MapClaims) *httptest.ResponseRecorder {
+	s.t.Helper()
+	w := s.do(http.MethodGet, "/api/auth/oidc/mock/login", "", nil)
+	if w.Code != http.StatusFound {
+		s.t.Fatalf("login: status = %d, body = %s", w.Code, w.Body)
+	}
+	cookies := w.Result().Cookies()
+	// 测试请求不是HTTPS，状态cookie仍按会话cookie的配置设置Secure
+	if state := responseCookies(w)["oidc_state"]; state == nil || !state.Secure || !state.HttpOnly {
+		s.t.Fatalf("oidc state cookie = %+v", state)
+	}
+
+	issuer.next = claims
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(w.Header().Get("Location"))
+	if err != nil {
+		s.t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil {
+		s.t.Fatalf("authorize: status = %d, location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOIDCLoginProvisionsAndLinksUsers(t *testing.T) {
+	issuer := newMockIssuer(t)
+	s := newOIDCTestServer(t, issuer)
+
+	bob, _ := s.store.GetUserByID(bobID)
+	bob.Email, bob.EmailVerified = "bob@example.com", true
+	dave, _ := s.store.GetUserByID(daveID)
+	dave.Email = "dave@example.com"
+	if err := s.store.UpdateUser(bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store.UpdateUser(dave); err != nil {
+		t.Fatal(err)
+	}
+
+	userOf := func(w *httptest.ResponseRecorder) models.Account {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("callback: status = %d, body = %s", w.Code, w.Body)
+		}
+		var resp struct {
+			Data struct {
+				Token string         `json:"token"`
+				User  models.Account `json:"user"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.Token == "" {
+			t.Fatalf("decode callback: %v, body = %s", err, w.Body)
+		}
+		return resp.Data.User
+	}
+
+	// 新用户自动创建，用户名冲突时追加数字
+	newbie := jwt.MapClaims{"sub": "u-1", "email": "Newbie@Example.com", "email_verified": true, "preferred_username": "alice", "name": "New Alice"}
+	created := userOf(s.oidcLogin(issuer, newbie))
+	if created.Username != "alice2" || created.Email != "newbie@example.com" || !created.EmailVerified || created.Role != models.RoleReader {
+		t.Errorf("provisioned user = %+v", created)
+	}
+	if again := userOf(s.oidcLogin(issuer, newbie)); again.ID != created.ID {
+		t.Errorf("second login user id = %d, want %d", again.ID, created.ID)
+	}
+
+	// 身份提供方和本地都已验证的邮箱自动关联到已有用户
+	if linked := userOf(s.oidcLogin(issuer, jwt.MapClaims{"sub": "u-2", "email": "bob@example.com", "email_verified": true})); linked.ID != bobID {
+		t.Errorf("linked user id = %d, want %d", linked.ID, bobID)
+	}
+	// 身份提供方未验证的邮箱不用于关联，创建的新用户也不保存该邮箱
+	if other := userOf(s.oidcLogin(issuer, jwt.MapClaims{"sub": "u-3", "email": "bob@example.com", "email_verified": false})); other.ID == bobID || other.Email != "" {
+		t.Errorf("unverified email login = %+v, want new user without email", other)
+	}
+	// 本地邮箱未验证时拒绝关联
+	if w := s.oidcLogin(issuer, jwt.MapClaims{"sub": "u-4", "email": "dave@example.com", "email_verified": true}); w.Code != http.StatusConflict {
+		t.Errorf("unverified local email: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	logs, _ := s.store.GetAuditLogs(10, 0, models.AuditOIDCLink)
+	if len(logs) != 3 {
+		t.Errorf("oidc.link audit entries = %d, want 3", len(logs))
+	}
+}
+
+func TestOIDCCallbackRejectsForgedState(t *testing.T) {
+	issuer := newMockIssuer(t)
+	s := newOIDCTestServer(t, issuer)
+
+	if w := s.do(http.MethodGet, "/api/auth/oidc/unknown/login", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown provider: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	w := s.do(http.MethodGet, "/api/auth/oidc/mock/login", "", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: status = %d", w.Code)
+	}
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if location.Query().Get("code_challenge_method") != "S256" || location.Query().Get("nonce") == "" {
+		t.Errorf("authorization url missing PKCE or nonce: %s", location)
+	}
+
+	// 没有登录状态cookie，或state与cookie不一致
+	if w := s.do(http.MethodGet, "/api/auth/oidc/mock/callback?code=1&state="+location.Query().Get("state"), "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("callback without cookie: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?code=1&state=forged", nil)
+	for _, c := range w.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("callback with forged state: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	s := newTestServer(t)
+	request := func(method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	preflight := map[string]string{"Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "authorization, content-type"}
+
+	// 完整来源和子域名通配都回显请求的来源，并允许携带凭据
+	for _, origin := range []string{"https://app.blog.test", "https://pr-42.preview.blog.test"} {
+		w := request(http.MethodOptions, "/api/article/1", origin, preflight)
+		if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != origin ||
+			w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("preflight from %s: status = %d, headers = %v", origin, w.Code, w.Header())
+		}
+	}
+
+	rejected := []struct {
+		origin  string
+		headers map[string]string
+	}{
+		{"https://evil.test", preflight},
+		{"https://preview.blog.test", preflight},       // 通配不包括域名本身
+		{"http://app.blog.test", preflight},            // scheme不同
+		{"https://app.blog.test.evil.test", preflight}, // 只是前缀相同
+		{"https://app.blog.test", map[string]string{"Access-Control-Request-Method": "PATCH"}},
+		{"https://app.blog.test", map[string]string{"Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "x-custom"}},
+	}
+	for _, tc := range rejected {
+		w := request(http.MethodOptions, "/api/article/1", tc.origin, tc.headers)
+		if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("preflight from %s %v: status = %d, allow-origin = %q", tc.origin, tc.headers, w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+
+	// 实际请求：允许的来源回显并暴露响应头，不允许的来源不带CORS响应头
+	w := request(http.MethodGet, "/api/article/", "https://app.blog.test", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.blog.test" || w.Header().Get("Access-Control-Expose-Headers") != "Retry-After" ||
+		!strings.Contains(strings.Join(w.Header().Values("Vary"), ","), "Origin") {
+		t.Errorf("allowed origin: headers = %v", w.Header())
+	}
+	w = request(http.MethodGet, "/api/article/", "https://evil.test", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("disallowed origin: status = %d, headers = %v", w.Code, w.Header())
+	}
+
+	// JWKS允许任意来源读取，但不允许携带凭据
+	w = request(http.MethodGet, "/.well-known/jwks.json", "https://evil.test", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("jwks: headers = %v", w.Header())
+	}
+	w = request(http.MethodOptions, "/.well-known/jwks.json", "https://evil.test", preflight)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("jwks preflight for PUT: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	if _, err := middleware.NewCORS(middleware.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}); err == nil {
+		t.Error("NewCORS accepted wildcard origin with credentials")
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	s := newTestServer(t)
+	nonce := regexp.MustCompile(`'nonce-([A-Za-z0-9+/=]+)'`)
+
+	first := s.do(http.MethodGet, "/", "", nil)
+	second := s.do(http.MethodGet, "/api/article/", "", nil)
+	for _, w := range []*httptest.ResponseRecorder{first, second} {
+		h := w.Header()
+		if h.Get("X-Content-Type-Options") != "nosniff" || h.Get("X-Frame-Options") != "DENY" ||
+			h.Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" ||
+			h.Get("Referrer-Policy") != "strict-origin-when-cross-origin" || !nonce.MatchString(h.Get("Content-Security-Policy")) {
+			t.Errorf("security headers = %v", h)
+		}
+	}
+	if a, b := nonce.FindStringSubmatch(first.Header().Get("Content-Security-Policy")), nonce.FindStringSubmatch(second.Header().Get("Content-Security-Policy")); a[1] == b[1] {
+		t.Errorf("CSP nonce reused across requests: %s", a[1])
+	}
+}
+
+func TestCSRFDoubleSubmitCookie(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.do(http.MethodGet, "/api/csrf", "", nil)
+	var resp struct {
+		Data struct {
+			CSRFToken string `json:"csrf_token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.CSRFToken == "" {
+		t.Fatalf("get csrf token: %v, body = %s", err, w.Body)
+	}
+	var csrfCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "csrf_token" {
+			csrfCookie = c
+		}
+	}
+	if csrfCookie == nil || csrfCookie.Value != resp.Data.CSRFToken || csrfCookie.HttpOnly {
+		t.Fatalf("csrf cookie = %+v, want readable cookie matching %q", csrfCookie, resp.Data.CSRFToken)
+	}
+
+	post := func(header string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/article/", strings.NewReader(`{"title":"标题","content":"内容"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		return rec
+	}
+	session := &http.Cookie{Name: "session", Value: "anything"}
+
+	// 使用会话cookie时必须提交与cookie相同的令牌
+	if w := post("", session, csrfCookie); w.Code != http.StatusForbidden {
+		t.Errorf("cookie session without token: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := post("forged", session, csrfCookie); w.Code != http.StatusForbidden {
+		t.Errorf("cookie session with wrong token: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := post(resp.Data.CSRFToken, session); w.Code != http.StatusForbidden {
+		t.Errorf("cookie session with token but no csrf cookie: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := post(resp.Data.CSRFToken, session, csrfCookie); w.Code == http.StatusForbidden {
+		t.Errorf("cookie session with matching token rejected by CSRF check: %s", w.Body)
+	}
+
+	// 使用请求头认证的请求不需要CSRF令牌
+	if w := s.do(http.MethodPost, "/api/article/", s.token(aliceID), gin.H{"title": "标题", "content": "内容"}); w.Code != http.StatusOK {
+		t.Errorf("bearer request: status = %d, body = %s", w.Code, w.Body)
+	}
+}
+
+// cookieRequest 发送携带cookie的请求，csrf不为空时放入X-CSRF-Token请求头
+func (s *testServer) cookieRequest(method, path, csrf string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	if csrf != "" {
+		req.Header.Set("X-CSRF-Token", csrf)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// responseCookies 按名称索引响应设置的cookie
+func responseCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+func TestCookieSessionLogin(t *testing.T) {
+	s := newTestServer(t)
+	body := gin.H{"username": "erin", "password": "secret", "nickname": "Erin", "email": "erin@example.com"}
+	if w := s.do(http.MethodPost, "/api/user/register", "", body); w.Code != http.StatusOK {
+		t.Fatalf("register: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	w := s.do(http.MethodPost, "/api/user/login", "", gin.H{"username": "erin", "password": "secret", "cookie": true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("cookie login: status = %d, body = %s", w.Code, w.Body)
+	}
+	var login struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil {
+		t.Fatalf("decode login: %v", err)
+	}
+	if _, ok := login.Data["token"]; ok {
+		t.Errorf("cookie login returned access token in body: %s", w.Body)
+	}
+	if _, ok := login.Data["refresh_token"]; ok {
+		t.Errorf("cookie login returned refresh token in body: %s", w.Body)
+	}
+	cookies := responseCookies(w)
+	session, refresh, csrf := cookies["session"], cookies["session_refresh"], cookies["csrf_token"]
+	if session == nil || !session.HttpOnly || !session.Secure || session.SameSite != http.SameSiteLaxMode || session.Path != "/" {
+		t.Fatalf("session cookie = %+v", session)
+	}
+	if refresh == nil || !refresh.HttpOnly || !refresh.Secure || refresh.Path != "/api/user" {
+		t.Fatalf("refresh cookie = %+v", refresh)
+	}
+	// 请求经过终止TLS的反向代理到达时没有TLS连接，CSRF cookie仍按会话配置设置Secure
+	if csrf == nil || !csrf.Secure || csrf.HttpOnly {
+		t.Fatalf("csrf cookie = %+v", csrf)
+	}
+
+	// 会话cookie即可获取当前用户，不需要Authorization头
+	w = s.cookieRequest(http.MethodGet, "/api/user/me", "", session)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"username":"erin"`) {
+		t.Fatalf("me: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.cookieRequest(http.MethodGet, "/api/user/me", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("me without session: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// 刷新令牌从cookie读取，新的令牌对同样写入cookie
+	if w := s.cookieRequest(http.MethodPost, "/api/user/refresh", "", refresh, csrf); w.Code != http.StatusForbidden {
+		t.Errorf("cookie refresh without csrf token: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	w = s.cookieRequest(http.MethodPost, "/api/user/refresh", csrf.Value, refresh, csrf)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "refresh_token") {
+		t.Fatalf("cookie refresh: status = %d, body = %s", w.Code, w.Body)
+	}
+	cookies = responseCookies(w)
+	if cookies["session"] == nil || cookies["session_refresh"] == nil || cookies["session_refresh"].Value == refresh.Value {
+		t.Fatalf("cookie refresh did not rotate cookies: %v", cookies)
+	}
+	session, refresh = cookies["session"], cookies["session_refresh"]
+
+	// 退出登录需要CSRF令牌，成功后删除cookie并吊销令牌
+	if w := s.cookieRequest(http.MethodPost, "/api/user/logout", "", session, refresh, csrf); w.Code != http.StatusForbidden {
+		t.Errorf("cookie logout without csrf token: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	w = s.cookieRequest(http.MethodPost, "/api/user/logout", csrf.Value, session, refresh, csrf)
+	if w.Code != http.StatusOK {
+		t.Fatalf("cookie logout: status = %d, body = %s", w.Code, w.Body)
+	}
+	cookies = responseCookies(w)
+	for _, name := range []string{"session", "session_refresh"} {
+		if c := cookies[name]; c == nil || c.MaxAge >= 0 {
+			t.Errorf("logout did not clear %s cookie: %+v", name, c)
+		}
+	}
+	if w := s.cookieRequest(http.MethodGet, "/api/user/me", "", session); w.Code != http.StatusUnauthorized {
+		t.Errorf("me after logout: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := s.cookieRequest(http.MethodPost, "/api/user/refresh", csrf.Value, refresh, csrf); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newTestServer(t)
+	// 每个请求伪造不同的X-Forwarded-For，不可信的请求头不能改变限流使用的IP
+	spoofed := 0
+	register := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/register", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":40000"
+		spoofed++
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("192.0.2.%d", spoofed))
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 认证接口按IP限流
+	w := register("203.0.113.7")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("first request: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "30" {
+		t.Errorf("RateLimit-Limit = %q, want 30", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "29" {
+		t.Errorf("RateLimit-Remaining = %q, want 29", got)
+	}
+	for i := 1; i < 30; i++ {
+		if w := register("203.0.113.7"); w.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d limited before burst was used", i+1)
+		}
+	}
+	w = register("203.0.113.7")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	// 每小时恢复30个令牌，即每120秒恢复一个
+	if got := w.Header().Get("Retry-After"); got != "120" {
+		t.Errorf("Retry-After = %q, want 120", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining when limited = %q, want 0", got)
+	}
+	if w := register("198.51.100.4"); w.Code != http.StatusBadRequest {
+		t.Errorf("other ip: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 写操作按用户限流，API密钥单独计数
+	w = s.do(http.MethodPost, "/api/user/api-keys", s.token(carolID), gin.H{"name": "ci", "scopes": []string{"article:write"}})
+	var created struct {
+		Data struct {
+			Key string `json:"key"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.Data.Key == "" {
+		t.Fatalf("create key: status = %d, body = %s", w.Code, w.Body)
+	}
+	carol := s.token(carolID)
+	for i := 0; i < 20; i++ {
+		if w := s.do(http.MethodDelete, "/api/article/999", carol, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("write %d: status = %d, body = %s", i+1, w.Code, w.Body)
+		}
+	}
+	if w := s.do(http.MethodDelete, "/api/article/999", s.token(carolID), nil); w.Code != http.StatusTooManyRequests {
+		t.Errorf("user over limit with new token: status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w := s.do(http.MethodDelete, "/api/article/999", created.Data.Key, nil); w.Code != http.StatusNotFound {
+		t.Errorf("api key of limited user: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := s.do(http.MethodDelete, "/api/article/999", s.token(aliceID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("other user: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+// commentList 解析评论列表响应
+func commentList(t *testing.T, w *httptest.ResponseRecorder) []*models.Comment {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("list comments: status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data []*models.Comment `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode comments: %v", err)
+	}
+	return resp.Data
+}
+
+func TestCommentsThreadingAndModeration(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	path := fmt.Sprintf("/api/article/%d/comments", article.ID)
+	guest := gin.H{"content": "写得好", "guest_name": "路人", "guest_email": "Guest@Example.com"}
+
+	if w := s.do(http.MethodPost, path, "", guest); w.Code != http.StatusNotFound {
+		t.Errorf("comment on draft: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	article.Status = models.ArticleStatusPublished
+	if err := s.store.UpdateArticle(article); err != nil {
+		t.Fatalf("publish article: %v", err)
+	}
+	if w := s.do(http.MethodPost, path, "", gin.H{"content": "匿名"}); w.Code != http.StatusBadRequest {
+		t.Errorf("guest without name: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 游客评论需要审核，邮箱不会出现在响应中
+	w := s.do(http.MethodPost, path, "", guest)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"pending"`) || strings.Contains(w.Body.String(), "guest@example.com") {
+		t.Fatalf("guest comment: status = %d, body = %s", w.Code, w.Body)
+	}
+	var guestComment struct {
+		Data models.Comment `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &guestComment); err != nil {
+		t.Fatalf("decode comment: %v", err)
+	}
+	if got := commentList(t, s.do(http.MethodGet, path, "", nil)); len(got) != 0 {
+		t.Errorf("pending comment listed publicly: %+v", got)
+	}
+	if w := s.do(http.MethodPost, path, "", gin.H{"content": "回复", "parent_id": guestComment.Data.ID, "guest_name": "路人", "guest_email": "a@example.com"}); w.Code != http.StatusBadRequest {
+		t.Errorf("reply to pending comment: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 登录用户的评论直接通过，回复分页加载
+	w = s.do(http.MethodPost, path, s.token(daveID), gin.H{"content": "第一条"})
+	var daveComment struct {
+		Data models.Comment `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &daveComment); err != nil || daveComment.Data.Status != models.CommentStatusApproved {
+		t.Fatalf("user comment: status = %d, body = %s", w.Code, w.Body)
+	}
+	bob := s.token(bobID)
+	for i := 0; i < 4; i++ {
+		if w := s.do(http.MethodPost, path, bob, gin.H{"content": fmt.Sprintf("回复%d", i), "parent_id": daveComment.Data.ID}); w.Code != http.StatusOK {
+			t.Fatalf("reply %d: status = %d, body = %s", i, w.Code, w.Body)
+		}
+	}
+	comments := commentList(t, s.do(http.MethodGet, path+"?replies=2", "", nil))
+	if len(comments) != 1 || comments[0].ReplyCount != 4 || len(comments[0].Replies) != 2 || comments[0].Replies[0].Content != "回复0" {
+		t.Fatalf("threaded comments = %+v", comments)
+	}
+	if comments[0].User == nil || comments[0].User.Username != "dave" {
+		t.Errorf("comment user = %+v, want dave", comments[0].User)
+	}
+	replies := commentList(t, s.do(http.MethodGet, fmt.Sprintf("%s/%d/replies?page=2&limit=3", path, daveComment.Data.ID), "", nil))
+	if len(replies) != 1 || replies[0].Content != "回复3" {
+		t.Errorf("second page of replies = %+v", replies)
+	}
+
+	// 作者和编辑可以审核，其他人不能
+	if w := s.do(http.MethodGet, "/api/comment/", s.token(daveID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("reader moderation queue: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got := commentList(t, s.do(http.MethodGet, "/api/comment/", bob, nil)); len(got) != 0 {
+		t.Errorf("other author sees pending comments: %+v", got)
+	}
+	for _, id := range []uint{aliceID, carolID} {
+		if got := commentList(t, s.do(http.MethodGet, "/api/comment/", s.token(id), nil)); len(got) != 1 || got[0].ID != guestComment.Data.ID {
+			t.Errorf("user %d moderation queue = %+v", id, got)
+		}
+	}
+	statusPath := fmt.Sprintf("/api/comment/%d/status", guestComment.Data.ID)
+	if w := s.do(http.MethodPut, statusPath, bob, gin.H{"status": "approved"}); w.Code != http.StatusForbidden {
+		t.Errorf("other author approves: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodPut, statusPath, s.token(aliceID), gin.H{"status": "approved"}); w.Code != http.StatusOK {
+		t.Fatalf("owner approves: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := commentList(t, s.do(http.MethodGet, path, "", nil)); len(got) != 2 {
+		t.Errorf("approved comments = %d, want 2", len(got))
+	}
+
+	// 文章列表附带已通过审核的评论数量
+	commentCount := func() int {
+		t.Helper()
+		w := s.do(http.MethodGet, "/api/article/", "", nil)
+		var resp struct {
+			Data []*models.Article `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Data) != 1 {
+			t.Fatalf("list articles: %v, body = %s", err, w.Body)
+		}
+		return resp.Data[0].CommentCount
+	}
+	if got := commentCount(); got != 6 {
+		t.Errorf("comment_count = %d, want 6", got)
+	}
+
+	// 发表者可以删除自己的评论，回复一起删除
+	if w := s.do(http.MethodDelete, fmt.Sprintf("/api/comment/%d", guestComment.Data.ID), s.token(daveID), nil); w.Code != http.StatusForbidden {
+		t.Errorf("delete other's comment: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodDelete, fmt.Sprintf("/api/comment/%d", daveComment.Data.ID), s.token(daveID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete own comment: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := commentCount(); got != 1 {
+		t.Errorf("comment_count after delete = %d, want 1", got)
+	}
+
+	// 删除文章时评论一起删除
+	if err := s.store.DeleteArticle(article); err != nil {
+		t.Fatalf("delete article: %v", err)
+	}
+	if left, err := s.store.GetComments(models.CommentQuery{}); err != nil || len(left) != 0 {
+		t.Errorf("comments after deleting article = %d, %v", len(left), err)
+	}
+}
+
+// newSpamTestServer 创建启用垃圾内容过滤的测试服务器，不检查提交耗时
+func newSpamTestServer(t *testing.T) *testServer {
+	t.Helper()
+	key, err := middleware.NewHMACKey("test", []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("create signing key: %v", err)
+	}
+	keys, err := middleware.NewKeySet(key.ID, key)
+	if err != nil {
+		t.Fatalf("create key set: %v", err)
+	}
+	return newTestServerWithKeys(t, keys, func(deps *handlers.Deps) {
+		filter, err := spam.NewFilter(deps.Store, spam.Config{
+			FormSecret:      []byte("spam-form-secret"),
+			MaxFormAge:      time.Hour,
+			MaxLinks:        2,
+			ReviewThreshold: 0.5,
+			SpamThreshold:   1.0,
+		})
+		if err != nil {
+			t.Fatalf("create spam filter: %v", err)
+		}
+		deps.Spam = filter
+	})
+}
+
+func TestSpamFilter(t *testing.T) {
+	s := newSpamTestServer(t)
+	article := s.createArticle(aliceID)
+	article.Status = models.ArticleStatusPublished
+	if err := s.store.UpdateArticle(article); err != nil {
+		t.Fatalf("publish article: %v", err)
+	}
+	path := fmt.Sprintf("/api/article/%d/comments", article.ID)
+
+	w := s.do(http.MethodGet, "/api/form-token", "", nil)
+	var form struct {
+		Data struct {
+			FormToken string `json:"form_token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &form); err != nil || form.Data.FormToken == "" {
+		t.Fatalf("form token: status = %d, body = %s", w.Code, w.Body)
+	}
+	token := form.Data.FormToken
+	post := func(content, website string) models.Comment {
+		t.Helper()
+		w := s.do(http.MethodPost, path, "", gin.H{
+			"content": content, "guest_name": "路人", "guest_email": "guest@example.com",
+			"website": website, "form_token": token,
+		})
+		var resp struct {
+			Data models.Comment `json:"data"`
+		}
+		// 判定为垃圾评论时响应与待审核相同
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK || resp.Data.Status != models.CommentStatusPending {
+			t.Fatalf("post comment: status = %d, body = %s", w.Code, w.Body)
+		}
+		stored, err := s.store.GetCommentByID(uint(resp.Data.ID))
+		if err != nil || stored == nil {
+			t.Fatalf("get comment %d: %v", resp.Data.ID, err)
+		}
+		return *stored
+	}
+
+	if c := post("写得好", ""); c.Status != models.CommentStatusPending {
+		t.Errorf("normal comment status = %s, want pending", c.Status)
+	}
+	if c := post("写得好", "http://spam.example"); c.Status != models.CommentStatusSpam {
+		t.Errorf("honeypot comment status = %s, want spam", c.Status)
+	}
+	links := "看这里 http://a.example http://b.example http://c.example http://d.example http://e.example www.f.example"
+	if c := post(links, ""); c.Status != models.CommentStatusSpam {
+		t.Errorf("comment with too many links status = %s, want spam", c.Status)
+	}
+	token = "forged." + token
+	if c := post("写得好", ""); c.Status != models.CommentStatusPending {
+		t.Errorf("comment with invalid form token status = %s, want pending", c.Status)
+	}
+	token = form.Data.FormToken
+
+	// 屏蔽列表只有管理员可以修改，域名匹配子域名
+	blocklist := "/api/admin/spam/blocklist"
+	admin := s.token(adminID)
+	if w := s.do(http.MethodPost, blocklist, s.token(carolID), gin.H{"kind": "domain", "value": "casino.example"}); w.Code != http.StatusForbidden {
+		t.Errorf("editor edits blocklist: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := s.do(http.MethodPost, blocklist, admin, gin.H{"kind": "ip", "value": "not-an-ip"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid ip entry: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := s.do(http.MethodPost, blocklist, admin, gin.H{"kind": "domain", "value": "Casino.Example"}); w.Code != http.StatusOK {
+		t.Fatalf("add domain entry: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, blocklist, admin, gin.H{"kind": "domain", "value": "casino.example"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate entry: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if c := post("欢迎访问 https://www.casino.example/", ""); c.Status != models.CommentStatusSpam {
+		t.Errorf("blocked domain comment status = %s, want spam", c.Status)
+	}
+
+	// 被屏蔽的IP不能注册，并留下审计记录
+	w = s.do(http.MethodPost, blocklist, admin, gin.H{"kind": "ip", "value": "192.0.2.0/24"})
+	var entry struct {
+		Data models.SpamBlockEntry `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &entry); err != nil || entry.Data.ID == 0 {
+		t.Fatalf("add ip entry: status = %d, body = %s", w.Code, w.Body)
+	}
+	register := gin.H{"username": "spammer", "password": "secret", "nickname": "Spammer", "email": "spammer@example.com", "form_token": token}
+	if w := s.do(http.MethodPost, "/api/user/register", "", register); w.Code != http.StatusBadRequest {
+		t.Errorf("register from blocked ip: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if logs, _ := s.store.GetAuditLogs(10, 0, models.AuditSpamBlocked); len(logs) != 1 || logs[0].Target != "spammer" {
+		t.Errorf("spam audit logs = %+v", logs)
+	}
+	if w := s.do(http.MethodDelete, fmt.Sprintf("%s/%d", blocklist, entry.Data.ID), admin, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete ip entry: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/api/user/register", "", register); w.Code != http.StatusOK {
+		t.Errorf("register after unblocking: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 审核时训练分类器，训练样本足够后相似的评论被判定为垃圾评论
+	editor := s.token(carolID)
+	moderate := func(id int, status string) {
+		t.Helper()
+		if w := s.do(http.MethodPut, fmt.Sprintf("/api/comment/%d/status", id), editor, gin.H{"status": status}); w.Code != http.StatusOK {
+			t.Fatalf("moderate comment %d: status = %d, body = %s", id, w.Code, w.Body)
+		}
+	}
+	var lastSpam models.Comment
+	for i := 0; i < 5; i++ {
+		lastSpam = post(fmt.Sprintf("buy cheap pills now %d", i), "")
+		moderate(lastSpam.ID, models.CommentStatusSpam)
+		moderate(post(fmt.Sprintf("感谢分享这篇文章 %d", i), "").ID, models.CommentStatusApproved)
+	}
+	if c := post("cheap pills here", ""); c.Status != models.CommentStatusSpam {
+		t.Errorf("comment similar to trained spam status = %s, want spam", c.Status)
+	}
+	if c := post("感谢分享", ""); c.Status != models.CommentStatusPending {
+		t.Errorf("comment similar to trained ham status = %s, want pending", c.Status)
+	}
+
+	// 改回待审核时撤销训练
+	moderate(lastSpam.ID, models.CommentStatusPending)
+	counts, err := s.store.GetSpamTokens([]string{"", "pills"})
+	if err != nil || counts[""].Spam != 4 || counts[""].Ham != 5 || counts["pills"].Spam != 4 {
+		t.Errorf("token counts after untraining = %+v, %v", counts, err)
+	}
+}
+
+func TestArticleContentFormats(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	article.Content = "# 简介\n\n**Go** <script>alert(1)</script> [x](javascript:alert(1))\n\n## 用法\n\n- [x] 表格\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n注释[^1]\n\n[^1]: 脚注\n"
+	if err := s.store.UpdateArticle(article); err != nil {
+		t.Fatalf("update article: %v", err)
+	}
+	get := func(format string) articleResponse {
+		t.Helper()
+		// 文章是草稿，由作者预览
+		w := s.do(http.MethodGet, articlePath(article.ID)+"?format="+format, s.token(aliceID), nil)
+		var resp struct {
+			Data articleResponse `json:"data"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("get %s: status = %d, body = %s", format, w.Code, w.Body)
+		}
+		return resp.Data
+	}
+
+	if got := get("markdown"); got.Content != article.Content || got.Format != "markdown" {
+		t.Errorf("markdown content = %q", got.Content)
+	}
+	html := get("html")
+	for _, want := range []string{`<h1 id="简介">简介<a class="heading-anchor" href="#%E7%AE%80%E4%BB%8B"`, "<strong>Go</strong>", `type="checkbox"`, "<table>", `class="footnotes"`} {
+		if !strings.Contains(html.Content, want) {
+			t.Errorf("html missing %q: %s", want, html.Content)
+		}
+	}
+	if strings.Contains(html.Content, "<script>") || strings.Contains(html.Content, "javascript:") {
+		t.Errorf("html not sanitized: %s", html.Content)
+	}
+	if len(html.TOC) != 2 || html.TOC[0].ID != "简介" || html.TOC[1].Level != 2 || html.TOC[1].Text != "用法" {
+		t.Errorf("toc = %+v", html.TOC)
+	}
+	if text := get("text"); !strings.HasPrefix(text.Content, "简介\nGo") || strings.Contains(text.Content, "**") {
+		t.Errorf("text content = %q", text.Content)
+	}
+	if w := s.do(http.MethodGet, articlePath(article.ID)+"?format=pdf", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid format: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 渲染结果缓存在文章中，修改文章时失效
+	if cached, _ := s.store.GetArticleByID(uint(article.ID)); cached.RenderVersion == 0 || !strings.Contains(cached.ContentHTML, "简介") {
+		t.Fatalf("render cache not saved: version = %d", cached.RenderVersion)
+	}
+	if w := s.do(http.MethodPut, articlePath(article.ID), s.token(aliceID), gin.H{"title": "标题", "content": "# 新标题"}); w.Code != http.StatusOK {
+		t.Fatalf("update article: status = %d, body = %s", w.Code, w.Body)
+	}
+	if cached, _ := s.store.GetArticleByID(uint(article.ID)); cached.RenderVersion != 0 || cached.ContentHTML != "" {
+		t.Errorf("render cache not cleared on update: version = %d", cached.RenderVersion)
+	}
+	if html := get("html"); !strings.Contains(html.Content, "新标题") || len(html.TOC) != 1 {
+		t.Errorf("html after update = %s, toc = %+v", html.Content, html.TOC)
+	}
+}
+
+// articleResponse 按格式获取文章的响应数据
+type articleResponse struct {
+	Content string `json:"content"`
+	Format  string `json:"format"`
+	TOC     []struct {
+		Level int    `json:"level"`
+		ID    string `json:"id"`
+		Text  string `json:"text"`
+	} `json:"toc"`
+}
+
+func TestArticleCodeHighlighting(t *testing.T) {
+	s := newTestServer(t)
+	article := s.createArticle(aliceID)
+	article.Content = "## 示例 {#example}\n\n```go {linenos=table,hl_lines=[2],linenostart=10}\npackage main\nfunc main() {}\n```\n\n```nosuchlang\na < b\n```\n"
+	if err := s.store.UpdateArticle(article); err != nil {
+		t.Fatalf("update article: %v", err)
+	}
+	w := s.do(http.MethodGet, articlePath(article.ID)+"?format=html", s.token(aliceID), nil)
+	var resp struct {
+		Data articleResponse `json:"data"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+		t.Fatalf("get html: status = %d, body = %s", w.Code, w.Body)
+	}
+	html := resp.Data.Content
+	for _, want := range []string{
+		`<h2 id="example">示例<a class="heading-anchor" href="#example"`,
+		`<span class="kn">package</span>`, // 基于CSS类的高亮
+		`<span class="lnt">10`,            // 行号从linenostart开始
+		`<span class="line hl">`,          // 高亮第2行
+		`<code class="language-nosuchlang">a &lt; b`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html missing %q: %s", want, html)
+		}
+	}
+	if strings.Contains(html, "style=") {
+		t.Errorf("html contains inline styles: %s", html)
+	}
+
+	w = s.do(http.MethodGet, "/assets/highlight.css", "", nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") || !strings.Contains(w.Body.String(), ".chroma .kn") {
+		t.Errorf("highlight.css: status = %d, content-type = %q", w.Code, w.Header().Get("Content-Type"))
+	}
+}
+
+// xssPayloads 常见的XSS攻击载荷
+var xssPayloads = []string{
+	`<script>alert(1)</script>`,
+	`<img src=x onerror=alert(1)>`,
+	`<svg onload=alert(1)></svg>`,
+	`<a href="JaVaScRiPt:alert(1)">a</a>`,
+	`<a href="  javascript:alert(1)">b</a>`,
+	`<iframe src="javascript:alert(1)"></iframe>`,
+	`<img src="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">`,
+	`<div style="background:url(javascript:alert(1))" onclick="alert(1)">c</div>`,
+	`<object data="x.swf"></object><embed src="x.swf">`,
+	`<form action="javascript:alert(1)"><input type="submit"></form>`,
+	`<body onload=alert(1)>`,
+	`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+}
+
+// assertNoXSS 检查内容中不包含可执行脚本的标签、属性或URL
+func assertNoXSS(t *testing.T, label, content string) {
+	t.Helper()
+	lower := strings.ToLower(content)
+	for _, bad := range []string{"<script", "onerror", "onload", "onclick", "javascript:", "<iframe", "<svg", "data:text/html", "<object", "<embed", "<form", "<style", "style="} {
+		if strings.Contains(lower, bad) {
+			t.Errorf("%s contains %q: %s", label, bad, content)
+		}
+	}
+}
+
+func TestHTMLSanitization(t *testing.T) {
+	s := newTestServer(t)
+	payloads := strings.Join(xssPayloads, "\n")
+	alice := s.token(aliceID)
+
+	// 文章写入时标题去掉所有标签，内容中的原始HTML被过滤，代码块保持原样
+	code := "```text\n<script>alert(1)</script>\n```\n"
+	links := "[站内](/about) [站外](https://example.com) [x](JaVaScRiPt:alert(1))"
+	content := "# 标题\n\n<kbd>Ctrl</kbd> " + links + "\n\n" + payloads + "\n\n" + code
+	w := s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": `<img src=x onerror=alert(1)>标题<script>alert(1)</script>`, "content": content})
+	var created struct {
+		Data models.Article `json:"data"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &created) != nil {
+		t.Fatalf("create article: status = %d, body = %s", w.Code, w.Body)
+	}
+	if created.Data.Title != "标题" {
+		t.Errorf("title = %q, want %q", created.Data.Title, "标题")
+	}
+	stored := strings.TrimSuffix(created.Data.Content, code)
+	if stored == created.Data.Content {
+		t.Errorf("code block changed: %s", created.Data.Content)
+	}
+	// Markdown链接不是HTML，写入时保持原样，渲染时过滤
+	assertNoXSS(t, "stored content", strings.Replace(stored, links, "", 1))
+
+	// 渲染时再次过滤，Markdown链接中的危险URL被去掉，站外链接带noopener
+	w = s.do(http.MethodGet, articlePath(created.Data.ID)+"?format=html", alice, nil)
+	var resp struct {
+		Data articleResponse `json:"data"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+		t.Fatalf("get html: status = %d, body = %s", w.Code, w.Body)
+	}
+	html := resp.Data.Content
+	for _, want := range []string{
+		"<kbd>Ctrl</kbd>",
+		`<a href="/about">站内</a>`,
+		`<a href="https://example.com" target="_blank" rel="noopener">站外</a>`,
+		`&lt;script&gt;alert(1)&lt;/script&gt;`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html missing %q: %s", want, html)
+		}
+	}
+	assertNoXSS(t, "html", strings.ReplaceAll(html, "&lt;script&gt;alert(1)&lt;/script&gt;", ""))
+
+	// 更新文章时同样过滤，已保存的危险内容在渲染时也会被过滤
+	for _, payload := range xssPayloads {
+		w := s.do(http.MethodPut, articlePath(created.Data.ID), alice, gin.H{"title": "新标题" + payload, "content": "正文 " + payload})
+		var updated struct {
+			Data models.Article `json:"data"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &updated) != nil {
+			t.Fatalf("update %q: status = %d, body = %s", payload, w.Code, w.Body)
+		}
+		assertNoXSS(t, "updated title", updated.Data.Title)
+		assertNoXSS(t, "updated content", updated.Data.Content)
+	}
+	raw := s.createArticle(aliceID)
+	raw.Content = payloads
+	if err := s.store.UpdateArticle(raw); err != nil {
+		t.Fatalf("update article: %v", err)
+	}
+	if err := json.Unmarshal(s.do(http.MethodGet, articlePath(raw.ID)+"?format=html", "", nil).Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode article: %v", err)
+	}
+	assertNoXSS(t, "unsanitized article html", resp.Data.Content)
+
+	// 评论只允许少量标签，链接带nofollow，过滤后为空的评论被拒绝
+	created.Data.Status = models.ArticleStatusPublished
+	if err := s.store.UpdateArticle(&created.Data); err != nil {
+		t.Fatalf("publish article: %v", err)
+	}
+	path := fmt.Sprintf("/api/article/%d/comments", created.Data.ID)
+	dave := s.token(daveID)
+	for _, payload := range xssPayloads {
+		w := s.do(http.MethodPost, path, dave, gin.H{"content": "<b>评论</b>" + payload})
+		var comment struct {
+			Data models.Comment `json:"data"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &comment) != nil {
+			t.Fatalf("comment %q: status = %d, body = %s", payload, w.Code, w.Body)
+		}
+		if !strings.HasPrefix(comment.Data.Content, "<b>评论</b>") {
+			t.Errorf("comment content = %q", comment.Data.Content)
+		}
+		assertNoXSS(t, "comment", comment.Data.Content)
+	}
+	w = s.do(http.MethodPost, path, dave, gin.H{"content": `<h1>标题</h1><a href="https://example.com">链接</a>`})
+	var linked struct {
+		Data models.Comment `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &linked); err != nil || linked.Data.Content != `标题<a href="https://example.com" rel="nofollow noopener" target="_blank">链接</a>` {
+		t.Errorf("comment link: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, path, dave, gin.H{"content": xssPayloads[0]}); w.Code != http.StatusBadRequest {
+		t.Errorf("empty comment after sanitizing: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	w = s.do(http.MethodPost, path, "", gin.H{"content": "写得好", "guest_name": `<img src=x onerror=alert(1)>路人`, "guest_email": "guest@example.com"})
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"guest_name":"路人"`) {
+		t.Errorf("guest name: status = %d, body = %s", w.Code, w.Body)
+	}
+
+	// 用户资料只保留纯文本，用户名包含标签时拒绝注册
+	w = s.do(http.MethodPost, "/api/user/register", "", gin.H{"username": "eve", "password": "secret", "nickname": `<svg onload=alert(1)></svg>Eve & <b>Co</b>`, "email": "eve@example.com"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("register: status = %d, body = %s", w.Code, w.Body)
+	}
+	if user, _ := s.store.GetUserByUsername("eve"); user == nil || user.Nickname != "Eve &amp; Co" {
+		t.Errorf("nickname = %+v", user)
+	}
+	if w := s.do(http.MethodPost, "/api/user/register", "", gin.H{"username": "<b>mallory</b>", "password": "secret", "nickname": "M", "email": "m@example.com"}); w.Code != http.StatusBadRequest {
+		t.Errorf("register with html username: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := s.do(http.MethodPost, "/api/user/register", "", gin.H{"username": "mallory", "password": "secret", "nickname": xssPayloads[0], "email": "m@example.com"}); w.Code != http.StatusBadRequest {
+		t.Errorf("register with empty nickname after sanitizing: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTagsAndCategories(t *testing.T) {
+	s := newTestServer(t)
+	carol, alice := s.token(carolID), s.token(aliceID)
+	create := func(path, token string, body gin.H) int {
+		t.Helper()
+		w := s.do(http.MethodPost, path, token, body)
+		var resp struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("create %s: status = %d, body = %s", path, w.Code, w.Body)
+		}
+		return resp.Data.ID
+	}
+
+	// 管理标签和分类需要taxonomy:manage权限
+	if w := s.do(http.MethodPost, "/api/category/", alice, gin.H{"name": "技术"}); w.Code != http.StatusForbidden {
+		t.Errorf("author create category: status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	tech := create("/api/category/", carol, gin.H{"name": "技术", "slug": "tech"})
+	golang := create("/api/category/", carol, gin.H{"name": "Go 语言", "parent_id": tech})
+	life := create("/api/category/", carol, gin.H{"name": "生活"})
+	if w := s.do(http.MethodPost, "/api/category/", carol, gin.H{"name": "Tech", "slug": "tech"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate slug: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := s.do(http.MethodPut, fmt.Sprintf("/api/category/%d", tech), carol, gin.H{"name": "技术", "slug": "tech", "parent_id": golang}); w.Code != http.StatusBadRequest {
+		t.Errorf("category cycle: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 作者保存文章时按名称关联标签，不存在的标签自动创建，名称不区分大小写
+	publish := func(title string, category int, tags ...string) int {
+		t.Helper()
+		id := create("/api/article/", alice, gin.H{"title": title, "content": "内容", "category_id": category, "tags": tags})
+		if w := s.do(http.MethodPut, articlePath(id)+"/status", alice, gin.H{"status": models.ArticleStatusPublished}); w.Code != http.StatusOK {
+			t.Fatalf("publish: status = %d, body = %s", w.Code, w.Body)
+		}
+		return id
+	}
+	first := publish("Go并发", golang, "Go", "并发")
+	second := publish("随笔", life, "go", "  ", "生活")
+	if w := s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "t", "content": "c", "category_id": 999}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown category: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	list := func(query string) []int {
+		t.Helper()
+		var resp struct {
+			Data []struct {
+				ID   int `json:"id"`
+				Tags []struct {
+					Slug string `json:"slug"`
+				} `json:"tags"`
+			} `json:"data"`
+		}
+		w := s.do(http.MethodGet, "/api/article/?"+query, "", nil)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("list %s: status = %d, body = %s", query, w.Code, w.Body)
+		}
+		ids := []int{}
+		for _, a := range resp.Data {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+	if got := list("tag=go"); fmt.Sprint(got) != fmt.Sprint([]int{second, first}) {
+		t.Errorf("tag=go: %v", got)
+	}
+	if got := list("tag=生活"); fmt.Sprint(got) != fmt.Sprint([]int{second}) {
+		t.Errorf("tag=生活: %v", got)
+	}
+	// 按父分类过滤包括子分类中的文章
+	if got := list("category=tech"); fmt.Sprint(got) != fmt.Sprint([]int{first}) {
+		t.Errorf("category=tech: %v", got)
+	}
+	if got := list("category=nosuch"); len(got) != 0 {
+		t.Errorf("unknown category: %v", got)
+	}
+
+	type taxonomy struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		Slug         string `json:"slug"`
+		ArticleCount int    `json:"article_count"`
+	}
+	counts := func(path string) map[string]int {
+		t.Helper()
+		var resp struct {
+			Data []taxonomy `json:"data"`
+		}
+		if err := json.Unmarshal(s.do(http.MethodGet, path, "", nil).Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		got := map[string]int{}
+		for _, item := range resp.Data {
+			got[item.Slug] = item.ArticleCount
+		}
+		return got
+	}
+	if got := counts("/api/tag/"); fmt.Sprint(got) != "map[go:2 并发:1 生活:1]" {
+		t.Errorf("tag counts = %v", got)
+	}
+	if got := counts("/api/category/"); fmt.Sprint(got) != "map[go-语言:1 tech:1 生活:1]" {
+		t.Errorf("category counts = %v", got)
+	}
+
+	// 更新文章时不提供tags不修改标签，空数组移除所有标签
+	if w := s.do(http.MethodPut, articlePath(first), alice, gin.H{"title": "Go并发", "content": "新内容"}); w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := list("tag=并发"); len(got) != 1 {
+		t.Errorf("tags lost on update: %v", got)
+	}
+	if w := s.do(http.MethodPut, articlePath(first), alice, gin.H{"title": "Go并发", "content": "新内容", "tags": []string{}, "category_id": 0}); w.Code != http.StatusOK {
+		t.Fatalf("clear tags: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := list("category=tech"); len(got) != 0 {
+		t.Errorf("category not cleared: %v", got)
+	}
+
+	// 重命名标签后文章仍然关联，删除标签和分类后文章不再关联
+	goTag, _ := s.store.GetTagBySlug("go")
+	if w := s.do(http.MethodPut, fmt.Sprintf("/api/tag/%d", goTag.ID), carol, gin.H{"name": "Golang"}); w.Code != http.StatusOK {
+		t.Fatalf("rename tag: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := list("tag=golang"); fmt.Sprint(got) != fmt.Sprint([]int{second}) {
+		t.Errorf("tag=golang after rename: %v", got)
+	}
+	if w := s.do(http.MethodDelete, fmt.Sprintf("/api/tag/%d", goTag.ID), carol, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete tag: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodDelete, fmt.Sprintf("/api/category/%d", tech), carol, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete category: status = %d, body = %s", w.Code, w.Body)
+	}
+	if category, _ := s.store.GetCategoryByID(uint(golang)); category == nil || category.ParentID != nil {
+		t.Errorf("child category after parent deleted = %+v", category)
+	}
+	article, _ := s.store.GetArticleByID(uint(second))
+	if len(article.Tags) != 1 || article.Tags[0].Slug != "生活" || article.Category == nil || article.Category.Slug != "生活" {
+		t.Errorf("article taxonomy = %+v, tags = %+v", article.Category, article.Tags)
+	}
+}
+
+func TestTaxonomySlugCollisions(t *testing.T) {
+	s := newTestServer(t)
+	carol, alice := s.token(carolID), s.token(aliceID)
+
+	// 名称不同但生成的slug相同的标签是不同的标签，slug加上数字后缀
+	w := s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "t", "content": "c", "tags": []string{"C", "C++", "C#", "c++"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("create article: status = %d, body = %s", w.Code, w.Body)
+	}
+	tags, _ := s.store.GetTags()
+	got := map[string]string{}
+	for _, tag := range tags {
+		got[tag.Name] = tag.Slug
+	}
+	if fmt.Sprint(got) != "map[C:c C#:c-3 C++:c-2]" {
+		t.Errorf("tag slugs = %v", got)
+	}
+	if w := s.do(http.MethodPost, "/api/tag/", carol, gin.H{"name": "Tom & Jerry"}); !strings.Contains(w.Body.String(), `"slug":"tom-jerry"`) {
+		t.Errorf("tag with HTML characters: status = %d, body = %s, want slug tom-jerry", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/api/tag/", carol, gin.H{"name": "c#"}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate tag name: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	category := func(name string) (int, string) {
+		t.Helper()
+		var resp struct {
+			Data struct {
+				Slug string `json:"slug"`
+			} `json:"data"`
+		}
+		w := s.do(http.MethodPost, "/api/category/", carol, gin.H{"name": name})
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data.Slug
+	}
+	if code, slug := category("C"); code != http.StatusOK || slug != "c" {
+		t.Errorf("category C = %d %q, want c", code, slug)
+	}
+	if code, slug := category("C++"); code != http.StatusOK || slug != "c-2" {
+		t.Errorf("category C++ = %d %q, want c-2", code, slug)
+	}
+	if code, _ := category("c++"); code != http.StatusConflict {
+		t.Errorf("duplicate category name: status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestArticleSlugs(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.token(aliceID)
+	create := func(body gin.H) (int, string) {
+		t.Helper()
+		var resp struct {
+			Data struct {
+				ID   int    `json:"id"`
+				Slug string `json:"slug"`
+			} `json:"data"`
+		}
+		w := s.do(http.MethodPost, "/api/article/", alice, body)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("create: status = %d, body = %s", w.Code, w.Body)
+		}
+		return resp.Data.ID, resp.Data.Slug
+	}
+	bySlug := func(slug string) *httptest.ResponseRecorder {
+		return s.do(http.MethodGet, "/api/article/by-slug/"+slug+"?format=text", alice, nil)
+	}
+
+	// 中文标题转为拼音，重复的slug加上数字后缀
+	first, firstSlug := create(gin.H{"title": "Go语言入门", "content": "内容"})
+	_, secondSlug := create(gin.H{"title": "Go 语言 入门！", "content": "内容"})
+	_, fallback := create(gin.H{"title": "？？", "content": "内容"})
+	if firstSlug != "go-yu-yan-ru-men" || secondSlug != "go-yu-yan-ru-men-2" || fallback != "article" {
+		t.Errorf("generated slugs = %q, %q, %q", firstSlug, secondSlug, fallback)
+	}
+	// 标题中的&、<等字符不会以HTML实体的形式进入slug
+	if _, escaped := create(gin.H{"title": "Tom & Jerry: a < b", "content": "内容"}); escaped != "tom-jerry-a-b" {
+		t.Errorf("slug of title with HTML characters = %q, want tom-jerry-a-b", escaped)
+	}
+	if w := s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "t", "content": "c", "slug": firstSlug}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate slug: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "t", "content": "c", "slug": "Bad Slug"}); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid slug: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w := bySlug(firstSlug)
+	var resp struct {
+		Data struct {
+			ID int `json:"id"`
+		} `json:"data"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil || resp.Data.ID != first {
+		t.Fatalf("by slug: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := bySlug("nosuch"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown slug: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// 修改标题不改变slug；修改slug后旧的slug重定向到新的slug
+	update := func(body gin.H) *httptest.ResponseRecorder {
+		return s.do(http.MethodPut, articlePath(first), alice, body)
+	}
+	if w := update(gin.H{"title": "新标题", "content": "内容"}); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), firstSlug) {
+		t.Errorf("update title: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := update(gin.H{"title": "新标题", "content": "内容", "slug": secondSlug}); w.Code != http.StatusConflict {
+		t.Errorf("rename to taken slug: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	if w := update(gin.H{"title": "新标题", "content": "内容", "slug": "xin-biao-ti"}); w.Code != http.StatusOK {
+		t.Fatalf("rename: status = %d, body = %s", w.Code, w.Body)
+	}
+	w = bySlug(firstSlug)
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/api/article/by-slug/xin-biao-ti?format=text" {
+		t.Errorf("old slug: status = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+	// 旧的slug仍属于原文章，其他文章不能使用
+	if w := s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "t", "content": "c", "slug": firstSlug}); w.Code != http.StatusConflict {
+		t.Errorf("reuse old slug: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	// 改回旧的slug后不再重定向
+	if w := update(gin.H{"title": "新标题", "content": "内容", "slug": firstSlug}); w.Code != http.StatusOK {
+		t.Fatalf("rename back: status = %d, body = %s", w.Code, w.Body)
+	}
+	if w := bySlug(firstSlug); w.Code != http.StatusOK {
+		t.Errorf("renamed back: status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := bySlug("xin-biao-ti"); w.Code != http.StatusMovedPermanently {
+		t.Errorf("second old slug: status = %d, want %d", w.Code, http.StatusMovedPermanently)
+	}
+
+	// 删除文章后旧的slug不再重定向
+	if w := s.do(http.MethodDelete, articlePath(first), alice, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d", w.Code)
+	}
+	if w := bySlug("xin-biao-ti"); w.Code != http.StatusNotFound {
+		t.Errorf("deleted article slug: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestFullTextSearch(t *testing.T) {
+	s := newTestServer(t)
+	alice, bob := s.token(aliceID), s.token(bobID)
+	publish := func(token string, body gin.H) int {
+		t.Helper()
+		var resp struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}
+		w := s.do(http.MethodPost, "/api/article/", token, body)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("create: status = %d, body = %s", w.Code, w.Body)
+		}
+		if w := s.do(http.MethodPut, articlePath(resp.Data.ID)+"/status", token, gin.H{"status": models.ArticleStatusPublished}); w.Code != http.StatusOK {
+			t.Fatalf("publish: status = %d, body = %s", w.Code, w.Body)
+		}
+		return resp.Data.ID
+	}
+	type hit struct {
+		ID        int     `json:"id"`
+		Score     float64 `json:"score"`
+		Highlight struct {
+			Title   string `json:"title"`
+			Snippet string `json:"snippet"`
+		} `json:"highlight"`
+	}
+	searchFor := func(query string) []hit {
+		t.Helper()
+		var resp struct {
+			Data []hit `json:"data"`
+		}
+		w := s.do(http.MethodGet, "/api/search?"+query, "", nil)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("search %s: status = %d, body = %s", query, w.Code, w.Body)
+		}
+		return resp.Data
+	}
+	ids := func(hits []hit) string {
+		got := make([]int, len(hits))
+		for i, h := range hits {
+			got[i] = h.ID
+		}
+		return fmt.Sprint(got)
+	}
+
+	inTitle := publish(alice, gin.H{"title": "Go语言并发编程", "content": "goroutine和channel的用法", "tags": []string{"Go"}})
+	inContent := publish(bob, gin.H{"title": "周末随笔", "content": "读了一本书。\n\n这本书讲的是**Go语言**的并发模型，写得很好。"})
+	s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "Go语言草稿", "content": "未发布"})
+
+	// 标题中的匹配排在前面，草稿不出现在结果中
+	hits := searchFor("q=" + url.QueryEscape("go 语言"))
+	if ids(hits) != fmt.Sprint([]int{inTitle, inContent}) || hits[0].Score <= hits[1].Score {
+		t.Fatalf("search: %+v", hits)
+	}
+	if hits[0].Highlight.Title != "<mark>Go语言</mark>并发编程" {
+		t.Errorf("title highlight = %q", hits[0].Highlight.Title)
+	}
+	if !strings.Contains(hits[1].Highlight.Snippet, "<mark>Go语言</mark>的并发") || strings.Contains(hits[1].Highlight.Snippet, "**") {
+		t.Errorf("snippet = %q", hits[1].Highlight.Snippet)
+	}
+	// 中文按相邻两字匹配，必须包含所有词
+	if got := searchFor("q=" + url.QueryEscape("并发模型")); ids(got) != fmt.Sprint([]int{inContent}) {
+		t.Errorf("bigram search: %v", ids(got))
+	}
+	if got := searchFor("q=" + url.QueryEscape("语言 Rust")); len(got) != 0 {
+		t.Errorf("all terms required: %v", ids(got))
+	}
+
+	// 过滤条件
+	if got := searchFor("q=go&author=bob"); ids(got) != fmt.Sprint([]int{inContent}) {
+		t.Errorf("author filter: %v", ids(got))
+	}
+	if got := searchFor("q=go&tag=go"); ids(got) != fmt.Sprint([]int{inTitle}) {
+		t.Errorf("tag filter: %v", ids(got))
+	}
+	today := time.Now().Format("2006-01-02")
+	if got := searchFor("q=go&from=" + today + "&to=" + today); len(got) != 2 {
+		t.Errorf("date filter today: %v", ids(got))
+	}
+	if got := searchFor("q=go&to=2000-01-01"); len(got) != 0 {
+		t.Errorf("date filter past: %v", ids(got))
+	}
+	if w := s.do(http.MethodGet, "/api/search?q=go&from=yesterday", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid date: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := s.do(http.MethodGet, "/api/search?q=+%3F", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("empty query: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// 修改和删除文章后索引随之更新
+	if w := s.do(http.MethodPut, articlePath(inTitle), alice, gin.H{"title": "Rust入门", "content": "所有权"}); w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", w.Code, w.Body)
+	}
+	if got := searchFor("q=rust"); ids(got) != fmt.Sprint([]int{inTitle}) {
+		t.Errorf("after update: %v", ids(got))
+	}
+	if w := s.do(http.MethodDelete, articlePath(inContent), bob, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d", w.Code)
+	}
+	if got := searchFor("q=go"); len(got) != 0 {
+		t.Errorf("after delete: %v", ids(got))
+	}
+}
+
+func TestSearchSuggestions(t *testing.T) {
+	s := newTestServer(t)
+	alice, carol := s.token(aliceID), s.token(carolID)
+	publish := func(body gin.H) int {
+		t.Helper()
+		var resp struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}
+		body["status"] = models.ArticleStatusPublished
+		w := s.do(http.MethodPost, "/api/article/", alice, body)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil {
+			t.Fatalf("create: status = %d, body = %s", w.Code, w.Body)
+		}
+		return resp.Data.ID
+	}
+	type item struct {
+		ID    int    `json:"id"`
+		Text  string `json:"text"`
+		Views int    `json:"views"`
+	}
+	var result struct {
+		Titles     []item   `json:"titles"`
+		Tags       []item   `json:"tags"`
+		Categories []item   `json:"categories"`
+		DidYouMean []string `json:"did_you_mean"`
+	}
+	suggest := func(prefix string) {
+		t.Helper()
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		w := s.do(http.MethodGet, "/api/search/suggest?prefix="+url.QueryEscape(prefix), "", nil)
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &resp) != nil || json.Unmarshal(resp.Data, &result) != nil {
+			t.Fatalf("suggest %q: status = %d, body = %s", prefix, w.Code, w.Body)
+		}
+	}
+	texts := func(items []item) string {
+		got := make([]string, len(items))
+		for i, it := range items {
+			got[i] = fmt.Sprintf("%s:%d", it.Text, it.Views)
+		}
+		return strings.Join(got, ",")
+	}
+
+	var category struct {
+		Data struct {
+			ID int `json:"id"`
+		} `json:"data"`
+	}
+	w := s.do(http.MethodPost, "/api/category/", carol, gin.H{"name": "Golang", "slug": "golang"})
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &category) != nil {
+		t.Fatalf("create category: status = %d, body = %s", w.Code, w.Body)
+	}
+	basics := publish(gin.H{"title": "Go语言入门", "content": "变量和函数", "tags": []string{"Go"}, "category_id": category.Data.ID})
+	goroutines := publish(gin.H{"title": "深入理解goroutine调度", "content": "goroutine和channel", "tags": []string{"Go", "并发"}})
+	s.do(http.MethodPost, "/api/article/", alice, gin.H{"title": "Go草稿", "content": "未发布"})
+	for i := 0; i < 3; i++ {
+		s.do(http.MethodGet, articlePath(goroutines), "", nil)
+	}
+	s.do(http.MethodGet, articlePath(basics), "", nil)
+
+	// 按浏览量排序，草稿不出现在建议中；标签和分类的热度是其中文章浏览量之和
+	suggest("GO")
+	if got := texts(result.Titles); got != "深入理解goroutine调度:3,Go语言入门:1" {
+		t.Errorf("titles = %s", got)
+	}
+	if got := texts(result.Tags); got != "Go:4" {
+		t.Errorf("tags = %s", got)
+	}
+	if got := texts(result.Categories); got != "Golang:1" {
+		t.Errorf("categories = %s", got)
+	}
+	if len(result.DidYouMean) != 0 {
+		t.Errorf("did you mean = %v", result.DidYouMean)
+	}
+	// 中文可以从标题中间开始匹配
+	suggest("理解")
+	if got := texts(result.Titles); got != "深入理解goroutine调度:3" {
+		t.Errorf("titles = %s", got)
+	}
+
+	// 拼写错误时给出纠错建议，并按纠错后的输入补全
+	suggest("gorutine")
+	if fmt.Sprint(result.DidYouMean) != "[goroutine]" || texts(result.Titles) != "深入理解goroutine调度:3" {
+		t.Errorf("typo: did you mean = %v, titles = %s", result.DidYouMean, texts(result.Titles))
+	}
+	suggest("chanel")
+	if fmt.Sprint(result.DidYouMean) != "[channel]" {
+		t.Errorf("typo in content: did you mean = %v", result.DidYouMean)
+	}
+
+	// 发布新文章后建议立即更新
+	publish(gin.H{"title": "Channel详解", "content": "缓冲"})
+	suggest("chan")
+	if got := texts(result.Titles); got != "Channel详解:0" {
+		t.Errorf("after publish: titles = %s", got)
+	}
+
+	if w := s.do(http.MethodGet, "/api/search/suggest?prefix=+", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("empty prefix: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"gofile/internal/mail"
+	"gofile/middleware"
+	"gofile/models"
+	"html/template"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountLinks 账号邮件中使用的链接地址，令牌以token查询参数附加在地址后
+type AccountLinks struct {
+	VerifyURL string // 邮箱验证链接，通常直接指向/api/user/verify，打开后显示确认页面
+	ResetURL  string // 前端的重置密码页面，页面再调用/api/user/reset-password
+}
+
+// normalizeEmail 校验邮箱格式并转为小写，只接受不带显示名的纯地址
+func normalizeEmail(email string) (string, bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", false
+	}
+	return email, true
+}
+
+// withToken 在链接地址后附加token查询参数
+func withToken(link, token string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// sendVerificationEmail 向用户邮箱发送邮箱验证邮件
+func (h *Handler) sendVerificationEmail(user *models.User) error {
+	token, err := h.emailTokens.Issue(middleware.PurposeVerifyEmail, user)
+	if err != nil {
+		return err
+	}
+	return h.mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: "请验证您的邮箱",
+		Body: "您好，" + user.Nickname + "：\n\n" +
+			"请打开以下链接完成邮箱验证：\n" + withToken(h.links.VerifyURL, token) + "\n\n" +
+			"如果这不是您本人的操作，请忽略此邮件。\n",
+	})
+}
+
+// sendPasswordResetEmail 向用户邮箱发送重置密码邮件
+func (h *Handler) sendPasswordResetEmail(user *models.User) error {
+	token, err := h.emailTokens.Issue(middleware.PurposeResetPassword, user)
+	if err != nil {
+		return err
+	}
+	return h.mailer.Send(&mail.Message{
+		To:      user.Email,
+		Subject: "重置您的密码",
+		Body: "您好，" + user.Nickname + "：\n\n" +
+			"请打开以下链接重置密码，链接只能使用一次：\n" + withToken(h.links.ResetURL, token) + "\n\n" +
+			"如果您没有申请重置密码，请忽略此邮件，您的密码不会改变。\n",
+	})
+}
+
+// respondEmailTokenError 将邮件令牌校验错误转换为响应
+func respondEmailTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, middleware.ErrEmailTokenExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "链接已过期，请重新申请"})
+	case errors.Is(err, middleware.ErrEmailTokenInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "链接无效或已被使用"})
+	default:
+		respondStoreError(c, err, "校验令牌失败")
+	}
+}
+
+// verifyEmailPage 邮箱验证确认页面
+// 打开链接只展示页面，点击按钮后才以POST提交令牌，避免邮件安全网关等预先访问链接的程序替用户完成验证
+var verifyEmailPage = template.Must(template.New("verify").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="utf-8"><title>验证邮箱</title></head>
+<body>
+<p>请确认将 {{.Email}} 设为账号 {{.Username}} 的邮箱。</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<button type="submit">确认验证</button>
+</form>
+</body>
+</html>
+`))
+
+// VerifyEmailPage 处理打开验证邮件链接的请求
+// 此函数处理HTTP GET请求，只校验令牌并返回确认页面，不修改用户状态，令牌仍可使用
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求参数：
+//
+//	token - 验证邮件中的令牌，放在查询参数中
+//
+// 返回：
+//
+//	令牌有效时返回HTML确认页面，页面中的表单以POST提交到同一地址；令牌无效时返回JSON格式的错误信息
+func (h *Handler) VerifyEmailPage(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	user, err := h.emailTokens.Verify(middleware.PurposeVerifyEmail, token)
+	if err != nil {
+		respondEmailTokenError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	err = verifyEmailPage.Execute(c.Writer, map[string]string{
+		"Email":     user.Email,
+		"Username":  user.Username,
+		"Action":    c.Request.URL.Path,
+		"Token":     token,
+		"CSRFToken": middleware.CSRFToken(c),
+	})
+	if err != nil {
+		log.Printf("渲染邮箱验证页面失败: %v", err)
+	}
+}
+
+// VerifyEmail 处理邮箱验证请求
+// 此函数处理HTTP POST请求，由确认页面的表单或前端调用，令牌使用后失效
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求参数：
+//
+//	token - 验证邮件中的令牌，可以放在查询参数、表单字段或JSON请求体中
+//
+// 返回：
+//
+//	JSON格式的响应，包含验证后的用户信息或错误信息
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	if token == "" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		token = req.Token
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	user, err := h.emailTokens.Verify(middleware.PurposeVerifyEmail, token)
+	if err != nil {
+		respondEmailTokenError(c, err)
+		return
+	}
+	user.EmailVerified = true
+	if err := h.store.UpdateUser(user); err != nil {
+		respondStoreError(c, err, "验证邮箱失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "邮箱验证成功",
+		"data": user.Account(),
+	})
+}
+
+// ForgotPassword 处理忘记密码请求
+// 此函数处理HTTP POST请求，向邮箱对应的用户发送重置密码邮件
+// 无论邮箱是否已注册都返回相同的响应，避免被用来探测注册邮箱
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	email - 注册时使用的邮箱
+//
+// 返回：
+//
+//	JSON格式的响应，表示请求已受理或错误信息
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	email, ok := normalizeEmail(req.Email)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱格式无效"})
+		return
+	}
+
+	user, err := h.store.GetUserByEmail(email)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user != nil {
+		if err := h.sendPasswordResetEmail(user); err != nil {
+			log.Printf("发送重置密码邮件失败 user=%d: %v", user.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "如果该邮箱已注册，重置密码邮件已发送",
+	})
+}
+
+// ResetPassword 处理重置密码请求
+// 此函数处理HTTP POST请求，使用重置密码邮件中的令牌设置新密码
+// 重置成功后用户的所有会话都会失效；能收到邮件说明用户拥有该邮箱，邮箱同时标记为已验证
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	token - 重置密码邮件中的令牌
+//	password - 新密码
+//
+// 返回：
+//
+//	JSON格式的响应，表示重置成功或错误信息
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	user, err := h.emailTokens.Verify(middleware.PurposeResetPassword, req.Token)
+	if err != nil {
+		respondEmailTokenError(c, err)
+		return
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
+		return
+	}
+	user.Password = string(hashedPassword)
+	user.EmailVerified = true
+	if err := h.store.UpdateUser(user); err != nil {
+		respondStoreError(c, err, "重置密码失败")
+		return
+	}
+	if err := h.tokens.RevokeAllSessions(uint(user.ID)); err != nil {
+		respondStoreError(c, err, "重置密码失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "密码已重置，请重新登录",
+	})
+}
+
+// GetCurrentUser 处理获取当前登录用户的请求
+// 此函数处理HTTP GET请求，需要经过AuthMiddleware认证，浏览器前端使用cookie会话时据此获取登录状态
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含当前用户信息或错误信息
+func (h *Handler) GetCurrentUser(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	user, err := h.store.GetUserByID(userID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": user.Account(),
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"gofile/middleware"
+	"gofile/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRoles 处理获取角色及其权限列表的请求
+// 此函数处理HTTP GET请求，返回系统内置的所有角色和每个角色拥有的权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含角色列表
+func (h *Handler) GetRoles(c *gin.Context) {
+	roles := make([]gin.H, 0, len(models.Roles()))
+	for _, role := range models.Roles() {
+		roles = append(roles, gin.H{
+			"role":        role,
+			"permissions": role.Permissions(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": roles,
+	})
+}
+
+// UpdateUserRole 处理修改用户角色的请求
+// 此函数处理HTTP PUT请求，需要user:manage权限
+// 管理员不能修改自己的角色，避免系统中失去最后一个管理员
+// 角色改变时吊销该用户的所有会话，已签发的令牌携带旧角色，用户需要重新登录获取带有新角色的令牌
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 用户ID
+//
+// 请求体（JSON格式）：
+//
+//	role - 新角色：admin、editor、author或reader
+//
+// 返回：
+//
+//	JSON格式的响应，包含更新后的用户信息或错误信息
+func (h *Handler) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+	var req struct {
+		Role models.Role `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的角色"})
+		return
+	}
+	if currentID, _ := middleware.CurrentUserID(c); int(currentID) == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能修改自己的角色"})
+		return
+	}
+
+	user, err := h.store.GetUserByID(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	changed := user.Role != req.Role
+	user.Role = req.Role
+	if err := h.store.UpdateUser(user); err != nil {
+		respondStoreError(c, err, "更新用户失败")
+		return
+	}
+	if changed {
+		if err := h.tokens.RevokeAllSessions(uint(user.ID)); err != nil {
+			respondStoreError(c, err, "吊销会话失败")
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": user.Account(),
+	})
+}
+
+// GetAuditLogs 处理获取安全审计记录的请求
+// 此函数处理HTTP GET请求，需要user:manage权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL查询参数：
+//
+//	page - 页码，默认为1
+//	limit - 每页数量，默认为20
+//	action - 事件类型过滤，如login.lockout，可选参数
+//
+// 返回：
+//
+//	JSON格式的响应，包含审计记录列表或错误信息
+func (h *Handler) GetAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	entries, err := h.store.GetAuditLogs(limit, (page-1)*limit, c.Query("action"))
+	if err != nil {
+		respondStoreError(c, err, "获取审计记录失败")
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditLog{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": entries,
+	})
+}
+
+// recordAudit 写入审计记录，写入失败只记录日志，不影响请求结果
+func (h *Handler) recordAudit(c *gin.Context, action string, userID int, target, detail string) {
+	entry := &models.AuditLog{
+		Action: action,
+		Target: target,
+		IP:     c.ClientIP(),
+		Detail: detail,
+	}
+	if userID != 0 {
+		entry.UserID = &userID
+	}
+	if err := h.store.CreateAuditLog(entry); err != nil {
+		log.Printf("写入审计记录失败: %v", err)
+	}
+}
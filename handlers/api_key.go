@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"gofile/middleware"
+	"gofile/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyNameMaxLen API密钥名称的最大长度（字符数）
+const apiKeyNameMaxLen = 64
+
+// CreateAPIKey 处理创建API密钥的请求
+// 此函数处理HTTP POST请求，需要使用访问令牌认证
+// 只能授予当前角色拥有的权限，密钥原文只在响应中返回一次
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	name - 密钥名称，如"release-notes-ci"
+//	scopes - 密钥允许使用的权限列表，如["article:write"]
+//	expires_at - 过期时间（RFC 3339格式），可选，不提供时永不过期
+//
+// 返回：
+//
+//	JSON格式的响应，包含密钥原文和密钥信息，或错误信息
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	var req struct {
+		Name      string              `json:"name"`
+		Scopes    []models.Permission `json:"scopes"`
+		ExpiresAt *time.Time          `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > apiKeyNameMaxLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("密钥名称不能为空且不超过%d个字符", apiKeyNameMaxLen)})
+		return
+	}
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "至少需要一个权限"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "过期时间必须晚于当前时间"})
+		return
+	}
+
+	user, err := h.store.GetUserByID(userID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	scopes := make([]models.Permission, 0, len(req.Scopes))
+	seen := make(map[models.Permission]bool)
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未知的权限: " + string(scope)})
+			return
+		}
+		if !user.Role.Can(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "当前角色没有该权限: " + string(scope)})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	raw, key, err := h.apiKeys.Create(user, req.Name, scopes, req.ExpiresAt)
+	if err != nil {
+		respondStoreError(c, err, "创建API密钥失败")
+		return
+	}
+	h.recordAudit(c, models.AuditAPIKeyCreate, user.ID, key.Prefix, key.Name)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "API密钥已创建，请妥善保存，密钥不会再次显示",
+		"data": gin.H{
+			"key":     raw, // 密钥原文，通过Authorization: Bearer或X-API-Key请求头使用
+			"api_key": key,
+		},
+	})
+}
+
+// GetAPIKeys 处理获取当前用户API密钥列表的请求
+// 此函数处理HTTP GET请求，需要使用访问令牌认证，只返回未吊销的密钥，不包含密钥原文
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含密钥列表或错误信息
+func (h *Handler) GetAPIKeys(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	keys, err := h.store.GetUserAPIKeys(userID)
+	if err != nil {
+		respondStoreError(c, err, "获取API密钥失败")
+		return
+	}
+	if keys == nil {
+		keys = []*models.APIKey{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": keys,
+	})
+}
+
+// RevokeAPIKey 处理吊销API密钥的请求
+// 此函数处理HTTP DELETE请求，需要使用访问令牌认证，只能吊销自己的密钥
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 密钥ID
+//
+// 返回：
+//
+//	JSON格式的响应，表示吊销成功或错误信息
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的密钥ID"})
+		return
+	}
+	revoked, err := h.store.RevokeAPIKey(userID, id, time.Now())
+	if err != nil {
+		respondStoreError(c, err, "吊销API密钥失败")
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API密钥不存在"})
+		return
+	}
+	h.recordAudit(c, models.AuditAPIKeyRevoke, int(userID), strconv.Itoa(id), "")
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "API密钥已吊销",
+	})
+}
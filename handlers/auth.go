@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"gofile/middleware"
+	"gofile/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondTokens 为用户签发访问令牌和刷新令牌并返回登录成功响应
+// 参数：
+//
+//	c - Gin上下文
+//	user - 登录的用户
+//	familyID - 刷新令牌家族ID，为空表示新的登录会话
+//	msg - 响应消息
+//	cookie - 是否使用cookie会话，为true时令牌写入HttpOnly cookie而不出现在响应体中
+func (h *Handler) respondTokens(c *gin.Context, user *models.User, familyID, msg string, cookie bool) {
+	refreshToken, err := h.tokens.IssueRefreshToken(uint(user.ID), familyID)
+	if err != nil {
+		respondStoreError(c, err, "生成令牌失败")
+		return
+	}
+	h.writeTokens(c, user, refreshToken, msg, cookie)
+}
+
+// writeTokens 签发访问令牌，与刷新令牌一起写入响应
+func (h *Handler) writeTokens(c *gin.Context, user *models.User, refreshToken, msg string, cookie bool) {
+	token, err := h.tokens.GenerateToken(uint(user.ID), user.Username, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+		return
+	}
+	data := gin.H{
+		"expires_in": int(h.tokens.AccessTTL().Seconds()), // 访问令牌有效期（秒）
+		"user":       user.Account(),                      // 用户信息对象，包含邮箱
+	}
+	if cookie {
+		h.tokens.SetSessionCookies(c, token, refreshToken)
+	} else {
+		data["token"] = token                // 访问令牌
+		data["refresh_token"] = refreshToken // 刷新令牌，每次刷新后都会更换
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  msg,
+		"data": data,
+	})
+}
+
+// checkCookieSession 检查是否支持客户端请求的cookie会话，不支持时返回400错误
+func (h *Handler) checkCookieSession(c *gin.Context, cookie bool) bool {
+	if cookie && !h.tokens.SessionCookiesEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持cookie会话"})
+		return false
+	}
+	return true
+}
+
+// Refresh 处理刷新令牌请求
+// 此函数处理HTTP POST请求，使用刷新令牌换取新的访问令牌和刷新令牌
+// 旧的刷新令牌立即失效；已失效的刷新令牌被再次使用时，同一会话的所有刷新令牌都会被吊销
+// 请求体中没有刷新令牌时使用cookie会话中的刷新令牌，新的令牌对同样写入cookie
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式，使用cookie会话时可省略）：
+//
+//	refresh_token - 登录或上次刷新时获得的刷新令牌
+//
+// 返回：
+//
+//	JSON格式的响应，包含新的令牌对和用户信息，或错误信息
+func (h *Handler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// 使用cookie会话时请求体可以为空
+	_ = c.ShouldBindJSON(&req)
+	cookie := false
+	if req.RefreshToken == "" {
+		req.RefreshToken = h.tokens.SessionRefreshToken(c)
+		cookie = true
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	old, next, err := h.tokens.RotateRefreshToken(req.RefreshToken)
+	switch {
+	case errors.Is(err, middleware.ErrRefreshTokenReused):
+		h.tokens.ClearSessionCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "刷新令牌已被使用，请重新登录"})
+		return
+	case errors.Is(err, middleware.ErrRefreshTokenInvalid):
+		h.tokens.ClearSessionCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的刷新令牌"})
+		return
+	case err != nil:
+		respondStoreError(c, err, "刷新令牌失败")
+		return
+	}
+
+	// 重新读取用户，使角色变更在刷新后生效
+	user, err := h.store.GetUserByID(uint(old.UserID))
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil {
+		h.tokens.ClearSessionCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+	h.writeTokens(c, user, next, "success", cookie)
+}
+
+// Logout 处理退出当前会话的请求
+// 此函数处理HTTP POST请求，需要经过AuthMiddleware认证
+// 当前访问令牌加入吊销列表；如果提供了刷新令牌，该会话的所有刷新令牌也会被吊销
+// 使用cookie会话时从cookie读取刷新令牌，并删除会话cookie
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式，可选）：
+//
+//	refresh_token - 当前会话的刷新令牌
+//
+// 返回：
+//
+//	JSON格式的响应，表示退出成功或错误信息
+func (h *Handler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// 请求体可选，解析失败时只吊销访问令牌
+	_ = c.ShouldBindJSON(&req)
+
+	claims := middleware.CurrentClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken = h.tokens.SessionRefreshToken(c)
+	}
+	if err := h.tokens.RevokeToken(claims); err != nil {
+		respondStoreError(c, err, "退出登录失败")
+		return
+	}
+	if req.RefreshToken != "" {
+		if err := h.tokens.RevokeRefreshToken(claims.UserID, req.RefreshToken); err != nil {
+			respondStoreError(c, err, "退出登录失败")
+			return
+		}
+	}
+	h.tokens.ClearSessionCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "已退出登录",
+	})
+}
+
+// LogoutAll 处理退出所有会话的请求
+// 此函数处理HTTP POST请求，需要经过AuthMiddleware认证
+// 吊销当前用户的所有刷新令牌，并使此前签发的所有访问令牌失效，同时删除当前浏览器的会话cookie
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，表示退出成功或错误信息
+func (h *Handler) LogoutAll(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	if err := h.tokens.RevokeAllSessions(userID); err != nil {
+		respondStoreError(c, err, "退出登录失败")
+		return
+	}
+	h.tokens.ClearSessionCookies(c)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "已退出所有会话",
+	})
+}
+
+// RevokeUserSessions 处理管理员强制用户退出所有会话的请求
+// 此函数处理HTTP POST请求，需要user:manage权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 用户ID
+//
+// 返回：
+//
+//	JSON格式的响应，表示操作成功或错误信息
+func (h *Handler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+	user, err := h.store.GetUserByID(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if err := h.tokens.RevokeAllSessions(uint(user.ID)); err != nil {
+		respondStoreError(c, err, "吊销会话失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "已吊销该用户的所有会话",
+	})
+}
+
+// JWKS 返回用于校验访问令牌的公钥集合（JSON Web Key Set）
+// 其他服务可以据此校验博客签发的RS256或EdDSA令牌，HS256共享密钥不会公开
+// 响应直接使用RFC 7517格式，不包装code/msg
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.Keys().JWKS())
+}
+
+// GetCSRFToken 处理获取CSRF令牌的请求
+// 此函数处理HTTP GET请求，返回CSRF中间件为本次请求设置的令牌（同时写在csrf cookie中），
+// 使用cookie会话的前端在修改类请求中通过X-CSRF-Token请求头提交此令牌
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含CSRF令牌
+func (h *Handler) GetCSRFToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"csrf_token": middleware.CSRFToken(c),
+		},
+	})
+}
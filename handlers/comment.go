@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"fmt"
+	"gofile/internal/sanitize"
+	"gofile/internal/spam"
+	"gofile/middleware"
+	"gofile/models"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 评论相关的长度和分页限制
+const (
+	commentMaxLen         = 2000 // 评论内容的最大长度（字符数）
+	guestNameMaxLen       = 50   // 游客昵称的最大长度（字符数）
+	commentPageMax        = 100  // 每页评论数量上限
+	commentRepliesMax     = 20   // 列表中每条评论附带的回复数量上限
+	commentRepliesDefault = 3    // 列表中每条评论默认附带的回复数量
+)
+
+// commentPage 解析page和limit查询参数，返回每页数量和偏移量
+func commentPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > commentPageMax {
+		limit = commentPageMax
+	}
+	return limit, (page - 1) * limit
+}
+
+// attachReplies 为评论填充已通过审核的回复数量，replies大于0时同时附带前replies条回复
+// 附带的回复同样填充回复数量，客户端可以据此继续加载更深层的回复
+func (h *Handler) attachReplies(comments []*models.Comment, replies int) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	counts, err := h.store.CountReplies(ids, models.CommentStatusApproved)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		comment.ReplyCount = counts[comment.ID]
+		if replies <= 0 || comment.ReplyCount == 0 {
+			continue
+		}
+		parentID := comment.ID
+		comment.Replies, err = h.store.GetComments(models.CommentQuery{
+			ParentID: &parentID,
+			Status:   models.CommentStatusApproved,
+			Limit:    replies,
+		})
+		if err != nil {
+			return err
+		}
+		if err := h.attachReplies(comment.Replies, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canModerateComment 判断当前用户能否审核文章下的评论
+// 拥有comment:moderate权限的用户可以审核所有评论，作者可以审核自己文章下的评论
+func canModerateComment(c *gin.Context, article *models.Article) bool {
+	return canModifyArticle(c, article, models.PermCommentModerate)
+}
+
+// GetComments 处理获取文章评论列表的请求
+// 此函数处理HTTP GET请求，按发表时间正序返回已通过审核的顶层评论，每条评论附带回复数量和前几条回复
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 文章ID
+//
+// URL查询参数：
+//
+//	page - 页码，默认为1
+//	limit - 每页顶层评论数量，默认为20，最大为100
+//	replies - 每条评论附带的回复数量，默认为3，最大为20，更多回复通过GetCommentReplies分页获取
+//
+// 返回：
+//
+//	JSON格式的响应，包含评论列表或错误信息
+func (h *Handler) GetComments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
+		return
+	}
+	replies, err := strconv.Atoi(c.DefaultQuery("replies", strconv.Itoa(commentRepliesDefault)))
+	if err != nil || replies < 0 || replies > commentRepliesMax {
+		replies = commentRepliesDefault
+	}
+	limit, offset := commentPage(c)
+
+	// 不读取文章，避免加载评论时重复增加文章浏览量；文章不存在时返回空列表
+	topLevel := 0
+	comments, err := h.store.GetComments(models.CommentQuery{
+		ArticleID: id,
+		ParentID:  &topLevel,
+		Status:    models.CommentStatusApproved,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err == nil {
+		err = h.attachReplies(comments, replies)
+	}
+	if err != nil {
+		respondStoreError(c, err, "获取评论失败")
+		return
+	}
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": comments,
+	})
+}
+
+// GetCommentReplies 处理获取评论回复列表的请求
+// 此函数处理HTTP GET请求，按发表时间正序分页返回已通过审核的直接回复，每条回复附带自己的回复数量
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 文章ID
+//	comment_id - 评论ID
+//
+// URL查询参数：
+//
+//	page - 页码，默认为1
+//	limit - 每页数量，默认为20，最大为100
+//
+// 返回：
+//
+//	JSON格式的响应，包含回复列表或错误信息
+func (h *Handler) GetCommentReplies(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
+		return
+	}
+	commentID, err := strconv.Atoi(c.Param("comment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return
+	}
+	limit, offset := commentPage(c)
+
+	parent, err := h.store.GetCommentByID(uint(commentID))
+	if err != nil {
+		respondStoreError(c, err, "获取评论失败")
+		return
+	}
+	if parent == nil || parent.ArticleID != articleID || parent.Status != models.CommentStatusApproved {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return
+	}
+	replies, err := h.store.GetComments(models.CommentQuery{
+		ParentID: &commentID,
+		Status:   models.CommentStatusApproved,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err == nil {
+		err = h.attachReplies(replies, 0)
+	}
+	if err != nil {
+		respondStoreError(c, err, "获取评论失败")
+		return
+	}
+	if replies == nil {
+		replies = []*models.Comment{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": replies,
+	})
+}
+
+// CreateComment 处理发表评论的请求
+// 此函数处理HTTP POST请求，只能评论已发布的文章
+// 登录用户的评论直接通过审核；游客需要提供昵称和邮箱，评论需要审核后才会显示，
+// 并经过垃圾内容过滤，被判定为垃圾内容的评论直接进入spam状态
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 文章ID
+//
+// 请求体（JSON格式）：
+//
+//	content - 评论内容，只保留b、i、code、http(s)链接等少量标签，链接带nofollow
+//	parent_id - 回复的评论ID，可选
+//	guest_name - 游客昵称，未登录时必填
+//	guest_email - 游客邮箱，未登录时必填，不会公开显示
+//	website - 蜜罐字段，前端应隐藏该字段，正常用户不会填写
+//	form_token - 打开评论表单时获取的表单令牌，可选参数
+//
+// 返回：
+//
+//	JSON格式的响应，包含创建的评论或错误信息
+func (h *Handler) CreateComment(c *gin.Context) {
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
+		return
+	}
+	var req struct {
+		Content    string `json:"content"`
+		ParentID   *int   `json:"parent_id"`
+		GuestName  string `json:"guest_name"`
+		GuestEmail string `json:"guest_email"`
+		Website    string `json:"website"`
+		FormToken  string `json:"form_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	// 评论只保留评论策略允许的标签，过滤后为空的评论同样被拒绝
+	req.Content = sanitize.Comment(req.Content)
+	if req.Content == "" || utf8.RuneCountInString(req.Content) > commentMaxLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("评论内容不能为空且不超过%d个字符", commentMaxLen)})
+		return
+	}
+
+	comment := &models.Comment{
+		ArticleID: articleID,
+		Content:   req.Content,
+		Status:    models.CommentStatusApproved,
+		IP:        c.ClientIP(),
+	}
+	if userID, ok := middleware.CurrentUserID(c); ok {
+		id := int(userID)
+		comment.UserID = &id
+	} else {
+		name := sanitize.Text(req.GuestName)
+		if name == "" || utf8.RuneCountInString(name) > guestNameMaxLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("昵称不能为空且不超过%d个字符", guestNameMaxLen)})
+			return
+		}
+		email, ok := normalizeEmail(req.GuestEmail)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的邮箱地址"})
+			return
+		}
+		comment.GuestName, comment.GuestEmail = name, email
+		comment.Status = models.CommentStatusPending
+		// 判定为垃圾内容的评论直接标记为spam，响应与待审核相同，不给发送者提示
+		result := h.checkSpam(spam.Submission{
+			Content:   comment.Content,
+			Name:      name,
+			Email:     email,
+			IP:        comment.IP,
+			Honeypot:  req.Website,
+			FormToken: req.FormToken,
+		})
+		if result.Action == spam.ActionReject {
+			comment.Status = models.CommentStatusSpam
+		}
+	}
+
+	article, err := h.store.GetArticleByID(uint(articleID))
+	if err != nil {
+		respondStoreError(c, err, "发表评论失败")
+		return
+	}
+	if article == nil || article.Status != models.ArticleStatusPublished {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+		return
+	}
+	if req.ParentID != nil {
+		parent, err := h.store.GetCommentByID(uint(*req.ParentID))
+		if err != nil {
+			respondStoreError(c, err, "发表评论失败")
+			return
+		}
+		if parent == nil || parent.ArticleID != articleID || parent.Status != models.CommentStatusApproved {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "回复的评论不存在"})
+			return
+		}
+		comment.ParentID = req.ParentID
+	}
+
+	if err := h.store.CreateComment(comment); err != nil {
+		respondStoreError(c, err, "发表评论失败")
+		return
+	}
+	msg := "success"
+	if comment.Status != models.CommentStatusApproved {
+		msg = "评论已提交，审核通过后显示"
+		comment.Status = models.CommentStatusPending
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  msg,
+		"data": comment,
+	})
+}
+
+// GetModerationComments 处理获取待审核评论列表的请求
+// 此函数处理HTTP GET请求，需要经过AuthMiddleware认证，按发表时间正序返回
+// 拥有comment:moderate权限的用户可以查看所有文章的评论，其他用户只能查看自己文章下的评论
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL查询参数：
+//
+//	status - 评论状态：pending、approved或spam，默认为pending
+//	article_id - 只查看指定文章的评论，可选参数
+//	page - 页码，默认为1
+//	limit - 每页数量，默认为20，最大为100
+//
+// 返回：
+//
+//	JSON格式的响应，包含评论列表或错误信息
+func (h *Handler) GetModerationComments(c *gin.Context) {
+	status := c.DefaultQuery("status", models.CommentStatusPending)
+	if !models.ValidCommentStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论状态"})
+		return
+	}
+	articleID, _ := strconv.Atoi(c.Query("article_id"))
+	limit, offset := commentPage(c)
+
+	query := models.CommentQuery{
+		ArticleID: articleID,
+		Status:    status,
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if !middleware.HasPermission(c, models.PermCommentModerate) {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok || !middleware.HasPermission(c, models.PermArticleWrite) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权审核评论"})
+			return
+		}
+		query.ArticleUserID = int(userID)
+	}
+	comments, err := h.store.GetComments(query)
+	if err != nil {
+		respondStoreError(c, err, "获取评论失败")
+		return
+	}
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": comments,
+	})
+}
+
+// loadCommentForModeration 读取评论及其文章，并检查当前用户能否审核
+// 评论不存在或无权审核时写入错误响应并返回nil
+func (h *Handler) loadCommentForModeration(c *gin.Context, allowAuthor bool) *models.Comment {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论ID"})
+		return nil
+	}
+	comment, err := h.store.GetCommentByID(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "获取评论失败")
+		return nil
+	}
+	if comment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评论不存在"})
+		return nil
+	}
+	if allowAuthor && comment.UserID != nil {
+		if userID, ok := middleware.CurrentUserID(c); ok && int(userID) == *comment.UserID {
+			return comment
+		}
+	}
+	article, err := h.store.GetArticleByID(uint(comment.ArticleID))
+	if err != nil {
+		respondStoreError(c, err, "获取评论失败")
+		return nil
+	}
+	if article == nil || !canModerateComment(c, article) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权审核该评论"})
+		return nil
+	}
+	return comment
+}
+
+// SetCommentStatus 处理审核评论的请求
+// 此函数处理HTTP PUT请求，需要comment:moderate权限或者是评论所在文章的作者
+// 启用垃圾内容过滤时，标记为spam或approved的评论会用于训练分类器，改回pending时撤销训练
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 评论ID
+//
+// 请求体（JSON格式）：
+//
+//	status - 新状态：pending、approved或spam
+//
+// 返回：
+//
+//	JSON格式的响应，包含更新后的评论或错误信息
+func (h *Handler) SetCommentStatus(c *gin.Context) {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidCommentStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评论状态"})
+		return
+	}
+	comment := h.loadCommentForModeration(c, false)
+	if comment == nil {
+		return
+	}
+	previous := comment.Trained
+	comment.Status = req.Status
+	if h.spam != nil {
+		comment.Trained = commentTrainingClass(req.Status)
+	}
+	if err := h.store.UpdateComment(comment); err != nil {
+		respondStoreError(c, err, "更新评论失败")
+		return
+	}
+	h.trainComment(comment, previous)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": comment,
+	})
+}
+
+// DeleteComment 处理删除评论的请求
+// 此函数处理HTTP DELETE请求，评论的发表者、文章作者和拥有comment:moderate权限的用户可以删除，
+// 评论下的所有回复会一起删除
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 评论ID
+//
+// 返回：
+//
+//	JSON格式的响应，包含被删除的评论或错误信息
+func (h *Handler) DeleteComment(c *gin.Context) {
+	comment := h.loadCommentForModeration(c, true)
+	if comment == nil {
+		return
+	}
+	if err := h.store.DeleteComment(comment); err != nil {
+		respondStoreError(c, err, "删除评论失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": comment,
+	})
+}
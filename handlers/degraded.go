@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"gofile/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 降级模式下附加的响应头
+const (
+	// HeaderDegraded 标记响应数据来自快照而不是实时存储
+	HeaderDegraded = "X-Degraded-Mode"
+	// HeaderSnapshotAt 快照数据最后一次更新的时间（RFC3339格式）
+	HeaderSnapshotAt = "X-Snapshot-At"
+)
+
+// respondUnavailable 存储不可用且无快照可用时返回503和结构化错误
+func respondUnavailable(c *gin.Context, err error) {
+	log.Printf("存储不可用: %v", err)
+	c.Header("Retry-After", "30")
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"code":  http.StatusServiceUnavailable,
+		"msg":   "存储服务暂不可用，请稍后重试",
+		"error": "store_unavailable",
+	})
+}
+
+// respondStoreError 根据存储错误类型返回响应
+// 存储不可用时返回503，其他错误返回500并附带给定的错误信息
+func respondStoreError(c *gin.Context, err error, msg string) {
+	if models.IsUnavailable(err) {
+		respondUnavailable(c, err)
+		return
+	}
+	log.Printf("%s: %v", msg, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
+
+// serveFromSnapshot 判断是否可以用快照响应读请求
+// 仅在存储不可用且启用了快照时返回true，并设置降级模式响应头
+func (h *Handler) serveFromSnapshot(c *gin.Context, err error) bool {
+	if h.snapshot == nil || !models.IsUnavailable(err) {
+		return false
+	}
+	log.Printf("存储不可用，使用快照数据响应: %v", err)
+	c.Header(HeaderDegraded, "snapshot")
+	c.Header(HeaderSnapshotAt, h.snapshot.SavedAt().Format(time.RFC3339))
+	return true
+}
+
+// recordSnapshot 把从存储读取或修改后的文章记入快照
+// 修改后必须及时更新，否则存储不可用期间会返回修改前的内容（如已撤回的文章）
+func (h *Handler) recordSnapshot(article *models.Article) {
+	if h.snapshot != nil {
+		h.snapshot.RecordArticles(article)
+	}
+}
@@ -1,18 +1,102 @@
 package handlers
 
 import (
+	"gofile/internal/mail"
+	"gofile/internal/markdown"
+	"gofile/internal/oidc"
+	"gofile/internal/sanitize"
+	"gofile/internal/spam"
+	"gofile/internal/suggest"
 	"gofile/middleware"
 	"gofile/models"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Handler 持有处理请求所需的依赖
+// 所有HTTP处理函数都是Handler的方法，通过注入的存储访问数据，而不是使用全局变量
+type Handler struct {
+	store         models.Store
+	tokens        *middleware.TokenManager
+	guard         *middleware.LoginGuard
+	apiKeys       *middleware.APIKeys
+	oidcProviders []*oidc.Provider
+	oidcSecret    []byte
+	emailTokens   *middleware.EmailTokens
+	mailer        mail.Mailer
+	links         AccountLinks
+	snapshot      *models.Snapshot
+	spam          *spam.Filter
+	markdown      *markdown.Renderer
+	suggester     *suggest.Suggester
+}
+
+// Deps 创建处理器所需的依赖
+type Deps struct {
+	Store           models.Store             // 文章、用户和令牌数据使用的存储实现
+	Tokens          *middleware.TokenManager // 签发和校验访问令牌、刷新令牌
+	Guard           *middleware.LoginGuard   // 登录失败计数和临时锁定
+	APIKeys         *middleware.APIKeys      // 创建个人API密钥
+	OIDCProviders   []*oidc.Provider         // 第三方登录使用的OpenID Connect身份提供方，可以为空
+	OIDCStateSecret []byte                   // 第三方登录状态cookie的签名密钥，配置了身份提供方时必须提供
+	EmailTokens     *middleware.EmailTokens  // 签发和校验邮箱验证、密码重置令牌
+	Mailer          mail.Mailer              // 发送账号相关邮件，为nil时只打印到日志
+	Links           AccountLinks             // 邮件中的链接地址
+	Snapshot        *models.Snapshot         // 可选的只读快照缓存，为nil时存储不可用直接返回503
+	Spam            *spam.Filter             // 评论和注册的垃圾内容过滤器，为nil时不过滤
+	Markdown        *markdown.Renderer       // 文章内容的Markdown渲染器，为nil时使用默认配色主题
+}
+
+// NewHandler 创建处理器实例
+// 参数：
+//
+//	deps - 处理器依赖，Store、Tokens、Guard、APIKeys和EmailTokens必须提供
+//
+// 返回：
+//
+//	*Handler - 处理器实例
+func NewHandler(deps Deps) *Handler {
+	mailer := deps.Mailer
+	if mailer == nil {
+		mailer = mail.LogMailer{}
+	}
+	renderer := deps.Markdown
+	if renderer == nil {
+		renderer = markdown.Default()
+	}
+	return &Handler{
+		store:         deps.Store,
+		tokens:        deps.Tokens,
+		guard:         deps.Guard,
+		apiKeys:       deps.APIKeys,
+		oidcProviders: deps.OIDCProviders,
+		oidcSecret:    deps.OIDCStateSecret,
+		emailTokens:   deps.EmailTokens,
+		mailer:        mailer,
+		links:         deps.Links,
+		snapshot:      deps.Snapshot,
+		spam:          deps.Spam,
+		markdown:      renderer,
+		suggester:     suggest.New(deps.Store),
+	}
+}
+
+// 文章列表的分页限制
+const (
+	articlePageDefault = 10 // 每页文章数量的默认值
+	articlePageMax     = 50 // 每页文章数量上限
+)
+
 // GetArticles 处理获取文章列表的请求
 // 此函数处理HTTP GET请求，支持分页和状态过滤，返回文章列表数据
+// 默认只返回已发布的文章；查看草稿需要登录，拥有article:edit_any权限的用户可以查看所有草稿，
+// 作者只能查看自己的草稿
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -21,57 +105,64 @@ import (
 // URL查询参数：
 //
 //	page - 页码，默认为1
-//	limit - 每页数量，默认为10
-//	status - 文章状态过滤，可选参数
+//	limit - 每页数量，默认为10，最多50
+//	status - 文章状态：published（默认）或draft
+//	tag - 标签的slug，只返回带有该标签的文章，可选参数
+//	category - 分类的slug，只返回该分类及其子分类中的文章，可选参数
 //
 // 返回：
 //
 //	JSON格式的响应，包含文章列表数据或错误信息
-func GetArticles(c *gin.Context) {
+//	存储不可用时，若启用了快照则返回快照数据并设置X-Degraded-Mode响应头，否则返回503
+func (h *Handler) GetArticles(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	status := c.Query("status")
-	offset := (page - 1) * limit
-
-	articles, err := models.GetArticles(limit, offset, status)
-	// 如果获取文章失败（可能是数据库连接问题），返回模拟数据
-	if err != nil || len(articles) == 0 {
-		// 返回模拟文章数据
-		mockArticles := []models.Article{
-			{
-				ID:        1,
-				Title:     "欢迎来到我的博客",
-				Content:   "这是我的个人博客，用于记录生活点滴和分享技术知识。感谢您的访问！",
-				Slug:      "welcome-to-my-blog",
-				Category:  "博客",
-				Tags:      "博客,Go,Gin",
-				Status:    "published",
-				Views:     42,
-				UserID:    1,
-				CreatedAt: time.Now().AddDate(0, 0, -7),
-				UpdatedAt: time.Now().AddDate(0, 0, -7),
-			},
-			{
-				ID:        2,
-				Title:     "Gin框架入门教程",
-				Content:   "Gin是一个用Go语言编写的高性能Web框架，本文将介绍Gin的基本用法和核心功能。",
-				Slug:      "gin-tutorial",
-				Category:  "技术",
-				Tags:      "Go,Gin,Web框架",
-				Status:    "published",
-				Views:     128,
-				UserID:    1,
-				CreatedAt: time.Now().AddDate(0, 0, -14),
-				UpdatedAt: time.Now().AddDate(0, 0, -14),
-			},
-		}
-		c.JSON(http.StatusOK, gin.H{
-			"code": 0,
-			"msg":  "success",
-			"data": mockArticles,
-		})
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(articlePageDefault)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = articlePageDefault
+	}
+	if limit > articlePageMax {
+		limit = articlePageMax
+	}
+	query := models.ArticleQuery{
+		Status:   c.DefaultQuery("status", models.ArticleStatusPublished),
+		Tag:      c.Query("tag"),
+		Category: c.Query("category"),
+		Limit:    limit,
+		Offset:   (page - 1) * limit,
+	}
+	if !models.ValidArticleStatus(query.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章状态"})
 		return
 	}
+	if query.Status != models.ArticleStatusPublished && !middleware.HasPermission(c, models.PermArticleEditAny) {
+		userID, ok := middleware.CurrentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+			return
+		}
+		if !middleware.HasPermission(c, models.PermArticleWrite) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权查看草稿"})
+			return
+		}
+		query.AuthorID = int(userID)
+	}
+
+	articles, err := h.store.GetArticles(query)
+	if err != nil {
+		if !h.serveFromSnapshot(c, err) {
+			respondStoreError(c, err, "获取文章失败")
+			return
+		}
+		articles = h.snapshot.GetArticles(query)
+	} else if h.snapshot != nil {
+		h.snapshot.RecordArticles(articles...)
+	}
+	if articles == nil {
+		articles = []*models.Article{}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
@@ -81,7 +172,8 @@ func GetArticles(c *gin.Context) {
 }
 
 // GetArticle 处理获取单个文章详情的请求
-// 此函数处理HTTP GET请求，根据文章ID返回文章详细信息
+// 此函数处理HTTP GET请求，根据文章ID返回文章详细信息，并增加已发布文章的浏览量
+// 草稿只有作者和拥有article:edit_any权限的用户可以查看，其他人访问时返回404
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -91,51 +183,83 @@ func GetArticles(c *gin.Context) {
 //
 //	id - 文章的ID
 //
+// URL查询参数：
+//
+//	format - 内容格式：markdown（原文，默认）、html（渲染后的HTML，同时返回目录toc）或text（纯文本）
+//
 // 返回：
 //
 //	JSON格式的响应，包含文章详情数据或错误信息
-// GetArticle 处理获取单个文章的请求
-func GetArticle(c *gin.Context) {
+//	存储不可用时，若启用了快照则返回快照数据并设置X-Degraded-Mode响应头，否则返回503
+func (h *Handler) GetArticle(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
 		return
 	}
+	format, ok := articleFormat(c)
+	if !ok {
+		return
+	}
 
-	article, err := models.GetArticleByID(uint(id))
-	// 如果获取文章失败，返回模拟数据
-	if err != nil || article == nil {
-		// 返回模拟文章数据
-		mockArticle := models.Article{
-			ID:        id,
-			Title:     "示例文章",
-			Content:   "这是一篇模拟文章内容。在实际使用中，这里将显示真实的文章内容。",
-			Slug:      "example-article",
-			Category:  "技术",
-			Tags:      "Go,Gin,Web开发",
-			Status:    "published",
-			Views:     100,
-			UserID:    1,
-			CreatedAt: time.Now().AddDate(0, 0, -7),
-			UpdatedAt: time.Now().AddDate(0, 0, -7),
+	article, err := h.store.GetArticleByID(uint(id))
+	if err != nil {
+		if !h.serveFromSnapshot(c, err) {
+			respondStoreError(c, err, "获取文章失败")
+			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"code": 0,
-			"msg":  "success",
-			"data": mockArticle,
-		})
+		article = h.snapshot.GetArticleByID(id)
+	} else if article != nil {
+		h.recordSnapshot(article)
+		h.countArticleView(article)
+	}
+	h.respondArticle(c, article, format)
+}
+
+// countArticleView 增加已发布文章的浏览量，只在公开读取文章详情时调用
+// 作者和编辑预览草稿不计入浏览量；失败时只记录日志，不影响返回文章
+func (h *Handler) countArticleView(article *models.Article) {
+	if article.Status != models.ArticleStatusPublished {
+		return
+	}
+	if err := h.store.IncreaseArticleViews(uint(article.ID)); err != nil {
+		log.Printf("增加文章浏览量失败 article=%d: %v", article.ID, err)
+	}
+}
+
+// articleFormat 读取查询参数format，格式无效时写入400响应并返回false
+func articleFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", formatMarkdown)
+	if format != formatMarkdown && format != formatHTML && format != formatText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的内容格式"})
+		return "", false
+	}
+	return format, true
+}
+
+// respondArticle 按指定格式返回文章详情，文章为nil或当前用户无权查看时返回404
+func (h *Handler) respondArticle(c *gin.Context, article *models.Article, format string) {
+	if article == nil || !canViewArticle(c, article) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+		return
+	}
+	view, err := h.newArticleView(article, format)
+	if err != nil {
+		log.Printf("渲染文章失败 article=%d: %v", article.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "渲染文章失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "success",
-		"data": article,
+		"data": view,
 	})
 }
 
 // CreateArticle 处理创建新文章的请求
 // 此函数处理HTTP POST请求，接收文章数据并创建新文章
+// 需要经过AuthMiddleware认证并拥有article:write权限，文章作者为当前登录用户
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -143,27 +267,57 @@ func GetArticle(c *gin.Context) {
 //
 // 请求体（JSON格式）：
 //
-//	包含Article模型的字段，如Title、Content、UserID等
+//	包含Article模型的字段，如Title、Content等，请求体中的user_id会被忽略
+//	status为空时默认为draft；标题中的HTML标签会被去掉，内容中的原始HTML按文章策略过滤
+//	slug - 文章的slug，可选参数，为空时由标题生成（汉字转为拼音），与已有文章重复时加上数字后缀
+//	category_id - 分类ID，可选参数
+//	tags - 标签名称列表，不存在的标签会自动创建，可选参数
 //
 // 返回：
 //
-//	JSON格式的响应，包含创建成功的文章数据或错误信息
-func CreateArticle(c *gin.Context) {
-	var article models.Article
-	if err := c.ShouldBindJSON(&article); err != nil {
+//	JSON格式的响应，包含创建成功的文章数据或错误信息，指定的slug已被使用时返回409
+func (h *Handler) CreateArticle(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req articleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
+	article := req.Article
 
-	if article.Title == "" || article.Content == "" || article.UserID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "标题、内容和用户ID不能为空"})
+	article.Title, article.Content = sanitize.Text(article.Title), sanitize.Markdown(article.Content)
+	if article.Title == "" || article.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标题和内容不能为空"})
+		return
+	}
+	if article.Status == "" {
+		article.Status = models.ArticleStatusDraft
+	}
+	if !models.ValidArticleStatus(article.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章状态"})
+		return
+	}
+	// 作者始终取自认证令牌，忽略请求体中的user_id；浏览量只能通过阅读增加
+	article.ID = 0
+	article.UserID = int(userID)
+	article.Views = 0
+	article.User, article.Category = nil, nil
+	requestedSlug := article.Slug
+	article.Slug = ""
+	if !h.applyArticleSlug(c, &article, requestedSlug) || !h.applyArticleTaxonomy(c, &article, req) {
 		return
 	}
 
-	if err := models.CreateArticle(&article); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建文章失败"})
+	if err := h.store.CreateArticle(&article); err != nil {
+		respondStoreError(c, err, "创建文章失败")
 		return
 	}
+	h.suggester.Invalidate()
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "success",
@@ -173,6 +327,8 @@ func CreateArticle(c *gin.Context) {
 
 // UpdateArticle 处理更新文章的请求
 // 此函数处理HTTP PUT请求，根据文章ID更新文章信息
+// 需要经过AuthMiddleware认证，作者可以更新自己的文章，
+// 更新他人的文章需要article:edit_any权限，否则返回403
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -184,39 +340,57 @@ func CreateArticle(c *gin.Context) {
 //
 // 请求体（JSON格式）：
 //
-//	包含要更新的Article模型字段，如Title、Content等
+//	包含要更新的Article模型字段，如Title、Content等，与创建文章相同的方式过滤HTML
+//	slug - 新的slug，不提供时不修改（修改标题也不会改变slug），旧的slug会重定向到新的slug
+//	category_id - 分类ID，为0时改为未分类，不提供时不修改
+//	tags - 标签名称列表，为空数组时移除所有标签，不提供时不修改
 //
 // 返回：
 //
-//	JSON格式的响应，包含更新后的文章数据或错误信息
-func UpdateArticle(c *gin.Context) {
+//	JSON格式的响应，包含更新后的文章数据或错误信息，新的slug已被其他文章使用时返回409
+func (h *Handler) UpdateArticle(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
 		return
 	}
 
-	existingArticle, err := models.GetArticleByID(uint(id))
+	existingArticle, err := h.store.GetArticleByID(uint(id))
 	if err != nil {
+		respondStoreError(c, err, "获取文章失败")
+		return
+	}
+	if existingArticle == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
 		return
 	}
-	var article models.Article
-	if err := c.ShouldBindJSON(&article); err != nil {
+	if !canModifyArticle(c, existingArticle, models.PermArticleEditAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权修改他人的文章"})
+		return
+	}
+	var req articleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
-	if article.Title == "" || article.Content == "" || article.UserID == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "标题、内容和用户ID不能为空"})
+	article := req.Article
+	article.Title, article.Content = sanitize.Text(article.Title), sanitize.Markdown(article.Content)
+	if article.Title == "" || article.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "标题和内容不能为空"})
 		return
 	}
 	existingArticle.Title = article.Title
 	existingArticle.Content = article.Content
 	existingArticle.UpdatedAt = time.Now()
-	if err := models.UpdateArticle(existingArticle); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新文章失败"})
+	if !h.applyArticleSlug(c, existingArticle, article.Slug) || !h.applyArticleTaxonomy(c, existingArticle, req) {
 		return
 	}
+	if err := h.store.UpdateArticle(existingArticle); err != nil {
+		respondStoreError(c, err, "更新文章失败")
+		return
+	}
+	h.suggester.Invalidate()
+	h.recordSnapshot(existingArticle)
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "success",
@@ -226,6 +400,8 @@ func UpdateArticle(c *gin.Context) {
 
 // DeleteArticle 处理删除文章的请求
 // 此函数处理HTTP DELETE请求，根据文章ID删除文章
+// 需要经过AuthMiddleware认证，作者可以删除自己的文章，
+// 删除他人的文章需要article:delete_any权限，否则返回403
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -238,22 +414,34 @@ func UpdateArticle(c *gin.Context) {
 // 返回：
 //
 //	JSON格式的响应，包含删除成功的信息或错误信息
-func DeleteArticle(c *gin.Context) {
+func (h *Handler) DeleteArticle(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
 		return
 	}
 
-	existingArticle, err := models.GetArticleByID(uint(id))
+	existingArticle, err := h.store.GetArticleByID(uint(id))
 	if err != nil {
+		respondStoreError(c, err, "获取文章失败")
+		return
+	}
+	if existingArticle == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
 		return
 	}
-	if err := models.DeleteArticle(existingArticle); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除文章失败"})
+	if !canModifyArticle(c, existingArticle, models.PermArticleDeleteAny) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权删除他人的文章"})
 		return
 	}
+	if err := h.store.DeleteArticle(existingArticle); err != nil {
+		respondStoreError(c, err, "删除文章失败")
+		return
+	}
+	h.suggester.Invalidate()
+	if h.snapshot != nil {
+		h.snapshot.ForgetArticle(existingArticle.ID)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "success",
@@ -261,6 +449,88 @@ func DeleteArticle(c *gin.Context) {
 	})
 }
 
+// isArticleOwner 判断当前登录用户是否为文章作者
+func isArticleOwner(c *gin.Context, article *models.Article) bool {
+	userID, ok := middleware.CurrentUserID(c)
+	return ok && int(userID) == article.UserID
+}
+
+// canViewArticle 判断当前用户能否查看文章
+// 已发布的文章所有人可见，草稿仅作者和拥有article:edit_any权限的用户可见
+func canViewArticle(c *gin.Context, article *models.Article) bool {
+	return article.Status == models.ArticleStatusPublished || canModifyArticle(c, article, models.PermArticleEditAny)
+}
+
+// canModifyArticle 判断当前用户能否对文章执行操作
+// 作者本人需要article:write权限，操作他人的文章需要anyPerm权限
+func canModifyArticle(c *gin.Context, article *models.Article, anyPerm models.Permission) bool {
+	if middleware.HasPermission(c, anyPerm) {
+		return true
+	}
+	return isArticleOwner(c, article) && middleware.HasPermission(c, models.PermArticleWrite)
+}
+
+// SetArticleStatus 处理修改文章状态（发布或撤回）的请求
+// 此函数处理HTTP PUT请求，作者可以发布或撤回自己的文章，
+// 拥有article:publish权限的用户（编辑、管理员）可以发布他人的草稿
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//	   包含了HTTP请求的所有信息（请求头、请求体、URL参数等）
+//
+// URL路径参数：
+//
+//	id - 文章ID
+//
+// 请求体（JSON格式）：
+//
+//	status - 新状态：draft或published
+//
+// 返回：
+//
+//	JSON格式的响应，包含更新后的文章数据或错误信息
+func (h *Handler) SetArticleStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章ID"})
+		return
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidArticleStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文章状态"})
+		return
+	}
+
+	article, err := h.store.GetArticleByID(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "获取文章失败")
+		return
+	}
+	if article == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文章不存在"})
+		return
+	}
+	if !canModifyArticle(c, article, models.PermArticlePublish) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权修改他人文章的状态"})
+		return
+	}
+
+	article.Status = req.Status
+	if err := h.store.UpdateArticle(article); err != nil {
+		respondStoreError(c, err, "更新文章失败")
+		return
+	}
+	h.suggester.Invalidate()
+	h.recordSnapshot(article)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": article,
+	})
+}
+
 // GetUsers 处理获取用户列表的请求
 // 此函数处理HTTP GET请求，返回所有用户的列表
 // 参数：
@@ -271,27 +541,20 @@ func DeleteArticle(c *gin.Context) {
 // 返回：
 //
 //	JSON格式的响应，包含用户列表数据或错误信息
-func GetUsers(c *gin.Context) {
-	users, err := models.GetUsers(10, 0)
-	// 如果获取用户失败，返回模拟数据
-	if err != nil || len(users) == 0 {
-		// 返回模拟用户数据
-		mockUsers := []models.User{
-			{
-				ID:        1,
-				Username:  "admin",
-				Nickname:  "管理员",
-				Email:     "admin@example.com",
-				CreatedAt: time.Now().AddDate(0, 0, -30),
-				UpdatedAt: time.Now().AddDate(0, 0, -30),
-			},
+//	存储不可用时，若启用了快照则返回快照数据并设置X-Degraded-Mode响应头，否则返回503
+func (h *Handler) GetUsers(c *gin.Context) {
+	users, err := h.store.GetUsers(10, 0)
+	if err != nil {
+		if !h.serveFromSnapshot(c, err) {
+			respondStoreError(c, err, "获取用户失败")
+			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"code": 0,
-			"msg":  "success",
-			"data": mockUsers,
-		})
-		return
+		users = h.snapshot.GetUsers(10, 0)
+	} else if h.snapshot != nil {
+		h.snapshot.RecordUsers(users...)
+	}
+	if users == nil {
+		users = []*models.User{}
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
@@ -302,6 +565,8 @@ func GetUsers(c *gin.Context) {
 
 // Login 处理用户登录请求
 // 此函数处理HTTP POST请求，验证用户凭据并返回认证令牌
+// 已启用两步验证的用户只返回短期有效的mfa_token，需调用/api/user/login/mfa换取认证令牌
+// 用户不存在和密码错误返回相同的响应；连续失败过多时返回429，需等待Retry-After秒后重试
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -311,47 +576,63 @@ func GetUsers(c *gin.Context) {
 //
 //	username - 用户的用户名
 //	password - 用户的密码
+//	cookie - 可选，为true时令牌写入HttpOnly会话cookie，响应体中不包含令牌
 //
 // 返回：
 //
-//	JSON格式的响应，包含认证令牌和用户信息，或错误信息
-func Login(c *gin.Context) {
+//	JSON格式的响应，包含访问令牌、刷新令牌和用户信息，或两步验证待定令牌，或错误信息
+func (h *Handler) Login(c *gin.Context) {
 	var longData struct {
 		Username string `json:"username"`
 		Password string `json:"password"`
+		Cookie   bool   `json:"cookie"`
 	}
 	if err := c.ShouldBindJSON(&longData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
-	user, err := models.GetUserByUsername(longData.Username)
-	if err != nil || user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+	if !h.checkCookieSession(c, longData.Cookie) {
 		return
 	}
-	// 使用bcrypt验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(longData.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+	// 只有来自server.trusted_proxies的请求才采信X-Forwarded-For，客户端不能伪造IP绕过按IP的失败计数
+	ip := c.ClientIP()
+	if !h.checkLoginAllowed(c, longData.Username, ip) {
 		return
 	}
-	// 使用JWT生成认证令牌
-	token, err := middleware.GenerateToken(uint(user.ID), user.Username)
+	user, err := h.store.GetUserByUsername(longData.Username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+		respondStoreError(c, err, "查询用户失败")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"code": 0,
-		"msg":  "登录成功",
-		"data": gin.H{
-			"token": token, // 生成的认证令牌
-			"user":  user,  // 用户信息对象
-		},
-	})
+	// 使用bcrypt验证密码；用户不存在时与固定哈希比较，使响应时间与密码错误时一致
+	passwordHash, userID := dummyPasswordHash, 0
+	if user != nil {
+		passwordHash, userID = []byte(user.Password), user.ID
+	}
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(longData.Password)); err != nil || user == nil {
+		h.recordLoginFailure(c, longData.Username, ip, userID)
+		return
+	}
+	mfa, err := h.store.GetUserMFA(uint(user.ID))
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if mfa != nil && mfa.Enabled {
+		// 完成两步验证后才清除失败计数
+		h.respondMFARequired(c, user)
+		return
+	}
+	if err := h.guard.Success(user.Username); err != nil {
+		respondStoreError(c, err, "登录失败")
+		return
+	}
+	// 签发访问令牌和新会话的刷新令牌
+	h.respondTokens(c, user, "", "登录成功", longData.Cookie)
 }
 
 // Register 处理用户注册请求
-// 此函数处理HTTP POST请求，创建新用户账号
+// 此函数处理HTTP POST请求，创建新用户账号，并向注册邮箱发送验证邮件
 // 参数：
 //
 //	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
@@ -362,33 +643,73 @@ func Login(c *gin.Context) {
 //	username - 要注册的用户名
 //	password - 用户密码
 //	nickname - 用户昵称
+//	email - 用户邮箱，不能与其他用户重复
+//	website - 蜜罐字段，前端应隐藏该字段，正常用户不会填写
+//	form_token - 打开注册表单时获取的表单令牌，可选参数
 //
 // 返回：
 //
 //	JSON格式的响应，包含注册成功的用户信息或错误信息
-func Register(c *gin.Context) {
+func (h *Handler) Register(c *gin.Context) {
 	var longData struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Nickname string `json:"nickname"`
+		Username  string `json:"username"`
+		Password  string `json:"password"`
+		Nickname  string `json:"nickname"`
+		Email     string `json:"email"`
+		Website   string `json:"website"`
+		FormToken string `json:"form_token"`
 	}
 	if err := c.ShouldBindJSON(&longData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
 		return
 	}
-	if longData.Username == "" || longData.Password == "" || longData.Nickname == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名、密码和昵称不能为空"})
+	longData.Nickname = sanitize.Text(longData.Nickname)
+	if longData.Username == "" || longData.Password == "" || longData.Nickname == "" || longData.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名、密码、昵称和邮箱不能为空"})
+		return
+	}
+	// 用户名用于登录，不能像昵称一样静默修改，包含标签或需要转义的字符时直接拒绝
+	if sanitize.Text(longData.Username) != longData.Username {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名不能包含HTML标签或<、>、&等字符"})
+		return
+	}
+	email, ok := normalizeEmail(longData.Email)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邮箱格式无效"})
 		return
 	}
-	existingUser, err := models.GetUserByUsername(longData.Username)
+	// 注册没有人工审核，只拒绝被判定为垃圾内容的请求
+	result := h.checkSpam(spam.Submission{
+		Content:   longData.Username,
+		Name:      longData.Nickname,
+		Email:     email,
+		IP:        c.ClientIP(),
+		Honeypot:  longData.Website,
+		FormToken: longData.FormToken,
+	})
+	if result.Action == spam.ActionReject {
+		h.recordAudit(c, models.AuditSpamBlocked, 0, longData.Username, strings.Join(result.Reasons, ","))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "注册请求被拒绝，请稍后再试"})
+		return
+	}
+	existingUser, err := h.store.GetUserByUsername(longData.Username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户失败"})
+		respondStoreError(c, err, "查询用户失败")
 		return
 	}
 	if existingUser != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
 		return
 	}
+	existingUser, err = h.store.GetUserByEmail(email)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if existingUser != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "邮箱已被注册"})
+		return
+	}
 
 	// 哈希密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(longData.Password), bcrypt.DefaultCost)
@@ -401,20 +722,26 @@ func Register(c *gin.Context) {
 	newUser := &models.User{
 		Username:  longData.Username,
 		Password:  string(hashedPassword),
+		Email:     email,
 		Nickname:  longData.Nickname,
+		Role:      models.RoleReader, // 新注册用户默认为读者，由管理员授予更高角色
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := models.CreateUser(newUser); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败"})
+	if err := h.store.CreateUser(newUser); err != nil {
+		respondStoreError(c, err, "创建用户失败")
 		return
 	}
+	// 邮件发送失败不影响注册，用户可以稍后通过找回密码完成验证
+	if err := h.sendVerificationEmail(newUser); err != nil {
+		log.Printf("发送验证邮件失败 user=%d: %v", newUser.ID, err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "注册成功",
-		"data": newUser,
+		"data": newUser.Account(),
 	})
 }
 
@@ -428,12 +755,19 @@ func Register(c *gin.Context) {
 // 返回：
 //
 //	JSON格式的响应，包含首页数据或错误信息
-func GetHome(c *gin.Context) {
+func (h *Handler) GetHome(c *gin.Context) {
 	// 获取最新的几篇文章用于首页展示
-	articles, err := models.GetArticles(5, 0, "published")
+	query := models.ArticleQuery{Status: models.ArticleStatusPublished, Limit: 5}
+	articles, err := h.store.GetArticles(query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取文章失败"})
-		return
+		if !h.serveFromSnapshot(c, err) {
+			respondStoreError(c, err, "获取文章失败")
+			return
+		}
+		articles = h.snapshot.GetArticles(query)
+	}
+	if articles == nil {
+		articles = []*models.Article{}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash 用户不存在时用于比较的固定哈希，避免通过响应时间判断用户名是否存在
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), bcrypt.DefaultCost)
+
+// checkLoginAllowed 检查用户名和来源IP是否处于退避或锁定期
+// 不允许登录时写入429响应并返回false
+func (h *Handler) checkLoginAllowed(c *gin.Context, username, ip string) bool {
+	wait, err := h.guard.Check(username, ip)
+	if err != nil {
+		respondStoreError(c, err, "登录失败")
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "登录尝试次数过多，请稍后再试",
+		"retry_after": seconds,
+	})
+	return false
+}
+
+// recordLoginFailure 记录登录失败并返回统一的凭据无效响应
+func (h *Handler) recordLoginFailure(c *gin.Context, username, ip string, userID int) {
+	if err := h.guard.Failure(username, ip, userID); err != nil {
+		respondStoreError(c, err, "登录失败")
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"gofile/internal/totp"
+	"gofile/middleware"
+	"gofile/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mfaIssuer 验证器应用中显示的签发方名称
+const mfaIssuer = "blog_system"
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// generateRecoveryCodes 生成n个随机恢复码，格式为xxxxx-xxxxx
+// 返回：
+//
+//	[]string - 返回给用户的恢复码原文
+//	[]string - 对应的哈希，保存到存储中
+//	error - 随机数生成失败时返回错误
+func generateRecoveryCodes(n int) ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, n)
+	hashes := make([]string, n)
+	buf := make([]byte, 7)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode 计算恢复码的SHA-256哈希，忽略大小写、空白和连字符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkMFA 校验验证码或恢复码
+// 验证码使用后记录其时间步，同一验证码不能再次使用；恢复码使用后作废
+// 参数：
+//
+//	mfa - 用户的两步验证设置
+//	code - 验证器应用生成的验证码
+//	recoveryCode - 恢复码，code为空时使用
+//
+// 返回：
+//
+//	bool - 校验是否通过
+//	error - 存储出错时返回错误
+func (h *Handler) checkMFA(mfa *models.UserMFA, code, recoveryCode string) (bool, error) {
+	userID := uint(mfa.UserID)
+	if code != "" {
+		step, ok := totp.Validate(mfa.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return h.store.AdvanceMFAStep(userID, step)
+	}
+	if recoveryCode != "" {
+		return h.store.UseRecoveryCode(userID, hashRecoveryCode(recoveryCode), time.Now())
+	}
+	return false, nil
+}
+
+// respondMFARequired 返回两步验证待定令牌，客户端需携带验证码调用LoginMFA
+func (h *Handler) respondMFARequired(c *gin.Context, user *models.User) {
+	token, err := h.tokens.GenerateMFAToken(uint(user.ID), user.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成令牌失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "请输入两步验证码",
+		"data": gin.H{
+			"mfa_required": true,
+			"mfa_token":    token,                                 // 两步验证待定令牌，只能用于/api/user/login/mfa
+			"expires_in":   int(middleware.MFATokenTTL.Seconds()), // 待定令牌有效期（秒）
+		},
+	})
+}
+
+// LoginMFA 处理两步验证登录的第二步
+// 此函数处理HTTP POST请求，使用密码登录返回的mfa_token和验证码（或恢复码）换取认证令牌
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	mfa_token - 密码登录返回的两步验证待定令牌
+//	code - 验证器应用生成的6位验证码
+//	recovery_code - 恢复码，没有验证器时代替code使用
+//	cookie - 可选，为true时令牌写入HttpOnly会话cookie，响应体中不包含令牌
+//
+// 返回：
+//
+//	JSON格式的响应，包含访问令牌、刷新令牌和用户信息，或错误信息
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var req struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+		Cookie       bool   `json:"cookie"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+	if !h.checkCookieSession(c, req.Cookie) {
+		return
+	}
+
+	claims, err := h.tokens.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		if models.IsUnavailable(err) {
+			respondUnavailable(c, err)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "两步验证已过期，请重新登录"})
+		return
+	}
+	ip := c.ClientIP()
+	if !h.checkLoginAllowed(c, claims.Username, ip) {
+		return
+	}
+	user, err := h.store.GetUserByID(claims.UserID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	mfa, err := h.store.GetUserMFA(claims.UserID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil || mfa == nil || !mfa.Enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "两步验证已过期，请重新登录"})
+		return
+	}
+
+	ok, err := h.checkMFA(mfa, req.Code, req.RecoveryCode)
+	if err != nil {
+		respondStoreError(c, err, "校验验证码失败")
+		return
+	}
+	if !ok {
+		// 验证码错误与密码错误共用失败计数，防止暴力猜测验证码
+		if err := h.guard.Failure(claims.Username, ip, user.ID); err != nil {
+			respondStoreError(c, err, "登录失败")
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+	// 待定令牌只能使用一次
+	if err := h.tokens.RevokeToken(claims); err != nil {
+		respondStoreError(c, err, "登录失败")
+		return
+	}
+	if err := h.guard.Success(user.Username); err != nil {
+		respondStoreError(c, err, "登录失败")
+		return
+	}
+	h.respondTokens(c, user, "", "登录成功", req.Cookie)
+}
+
+// EnrollMFA 处理开始绑定两步验证的请求
+// 此函数处理HTTP POST请求，需要经过AuthMiddleware认证
+// 生成新的TOTP密钥并返回otpauth://链接，用户在验证器应用中添加后调用ConfirmMFA启用
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含密钥和otpauth链接，或错误信息
+func (h *Handler) EnrollMFA(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	user, err := h.store.GetUserByID(userID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	existing, err := h.store.GetUserMFA(userID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if existing != nil && existing.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "两步验证已启用"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成密钥失败"})
+		return
+	}
+	if err := h.store.SaveUserMFA(&models.UserMFA{UserID: user.ID, Secret: secret}); err != nil {
+		respondStoreError(c, err, "保存两步验证设置失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "请在验证器应用中添加账号，并提交第一个验证码完成绑定",
+		"data": gin.H{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(mfaIssuer, user.Username, secret),
+		},
+	})
+}
+
+// ConfirmMFA 处理确认启用两步验证的请求
+// 此函数处理HTTP POST请求，需要经过AuthMiddleware认证
+// 第一个验证码校验通过后启用两步验证，并返回一组恢复码，恢复码只在此时返回一次
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	code - 验证器应用生成的6位验证码
+//
+// 返回：
+//
+//	JSON格式的响应，包含恢复码，或错误信息
+func (h *Handler) ConfirmMFA(c *gin.Context) {
+	userID, ok := middleware.CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return
+	}
+
+	mfa, err := h.store.GetUserMFA(userID)
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if mfa == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先开始绑定两步验证"})
+		return
+	}
+	if mfa.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "两步验证已启用"})
+		return
+	}
+	step, ok := totp.Validate(mfa.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+	if err := h.store.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		respondStoreError(c, err, "保存恢复码失败")
+		return
+	}
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.LastStep = step
+	mfa.ConfirmedAt = &now
+	if err := h.store.SaveUserMFA(mfa); err != nil {
+		respondStoreError(c, err, "保存两步验证设置失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "两步验证已启用，请妥善保存恢复码",
+		"data": gin.H{
+			"recovery_codes": codes, // 每个恢复码只能使用一次，不会再次显示
+		},
+	})
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gofile/internal/oidc"
+	"gofile/internal/sanitize"
+	"gofile/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 保存登录状态的cookie名称
+const oidcStateCookie = "oidc_state"
+
+// oidcStateTTL 发起登录到回调之间允许的最长时间
+const oidcStateTTL = 10 * time.Minute
+
+// errOIDCEmailConflict 身份提供方返回的邮箱已被未验证邮箱的本地账号占用
+var errOIDCEmailConflict = errors.New("email belongs to an unverified local account")
+
+// GetOIDCProviders 处理获取第三方登录方式列表的请求
+// 此函数处理HTTP GET请求，返回配置的所有OpenID Connect身份提供方，前端据此显示登录按钮
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含身份提供方名称、显示名称和登录地址
+func (h *Handler) GetOIDCProviders(c *gin.Context) {
+	providers := make([]gin.H, 0, len(h.oidcProviders))
+	for _, p := range h.oidcProviders {
+		providers = append(providers, gin.H{
+			"name":         p.Name(),
+			"display_name": p.DisplayName(),
+			"login_url":    "/api/auth/oidc/" + p.Name() + "/login",
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": providers,
+	})
+}
+
+// oidcProvider 根据路径参数查找身份提供方，不存在时返回404
+func (h *Handler) oidcProvider(c *gin.Context) *oidc.Provider {
+	name := c.Param("provider")
+	for _, p := range h.oidcProviders {
+		if p.Name() == name {
+			return p
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "未知的登录方式"})
+	return nil
+}
+
+// OIDCLogin 处理发起第三方登录的请求
+// 此函数处理HTTP GET请求，生成state、nonce和PKCE校验码并保存在签名cookie中，
+// 然后重定向到身份提供方的授权页面
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	provider - 身份提供方名称
+//
+// 返回：
+//
+//	302重定向到身份提供方，或JSON格式的错误信息
+func (h *Handler) OIDCLogin(c *gin.Context) {
+	provider := h.oidcProvider(c)
+	if provider == nil {
+		return
+	}
+	state, err := oidc.NewLoginState(provider.Name(), oidcStateTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录状态失败"})
+		return
+	}
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		log.Printf("获取身份提供方元数据失败 provider=%s: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "无法连接身份提供方，请稍后重试"})
+		return
+	}
+	sealed, err := state.Seal(h.oidcSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录状态失败"})
+		return
+	}
+	h.setOIDCStateCookie(c, sealed, int(oidcStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// setOIDCStateCookie 设置或清除登录状态cookie
+// 身份提供方通过顶层GET请求跳转回来，SameSite必须为Lax才能带上cookie；Secure与会话cookie的配置一致
+func (h *Handler) setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, maxAge, "/api/auth/oidc", "", h.tokens.CookieSecure(), true)
+}
+
+// OIDCCallback 处理身份提供方的回调
+// 此函数处理HTTP GET请求，校验state后使用授权码和PKCE校验码换取ID令牌，
+// 按外部身份、已验证的邮箱依次查找本地用户，都找不到时自动创建新用户，最后签发本站的令牌
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	provider - 身份提供方名称
+//
+// URL查询参数：
+//
+//	code - 授权码
+//	state - 发起登录时生成的state
+//	error - 用户拒绝授权或身份提供方出错时的错误码
+//
+// 返回：
+//
+//	JSON格式的响应，与密码登录相同，包含访问令牌、刷新令牌和用户信息，或两步验证待定令牌
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	provider := h.oidcProvider(c)
+	if provider == nil {
+		return
+	}
+	sealed, _ := c.Cookie(oidcStateCookie)
+	// 登录状态只能使用一次
+	h.setOIDCStateCookie(c, "", -1)
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "第三方登录未完成: " + errCode})
+		return
+	}
+	state, err := oidc.OpenLoginState(h.oidcSecret, sealed, provider.Name(), c.Query("state"))
+	if err != nil || c.Query("code") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录状态无效或已过期，请重新登录"})
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("第三方登录失败 provider=%s: %v", provider.Name(), err)
+		if errors.Is(err, oidc.ErrDiscovery) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "无法连接身份提供方，请稍后重试"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "第三方登录失败"})
+		return
+	}
+
+	user, err := h.resolveOIDCUser(c, provider.Name(), identity)
+	if err != nil {
+		if errors.Is(err, errOIDCEmailConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "该邮箱已被未验证的账号使用，请先使用密码登录并验证邮箱"})
+			return
+		}
+		respondStoreError(c, err, "第三方登录失败")
+		return
+	}
+
+	mfa, err := h.store.GetUserMFA(uint(user.ID))
+	if err != nil {
+		respondStoreError(c, err, "查询用户失败")
+		return
+	}
+	if mfa != nil && mfa.Enabled {
+		h.respondMFARequired(c, user)
+		return
+	}
+	h.respondTokens(c, user, "", "登录成功", false)
+}
+
+// resolveOIDCUser 查找或创建外部身份对应的本地用户
+// 依次按以下方式确定用户：
+//  1. 已关联的外部身份
+//  2. 身份提供方已验证的邮箱，且本地账号的邮箱也已验证，此时自动关联
+//  3. 创建新用户并关联
+//
+// 本地账号的邮箱未验证时不自动关联，防止他人预先用受害者的邮箱注册账号后接管第三方登录
+func (h *Handler) resolveOIDCUser(c *gin.Context, provider string, identity *oidc.Identity) (*models.User, error) {
+	linked, err := h.store.GetUserIdentity(provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		user, err := h.store.GetUserByID(uint(linked.UserID))
+		if err == nil && user == nil {
+			err = fmt.Errorf("identity %s/%s is linked to missing user %d", provider, identity.Subject, linked.UserID)
+		}
+		return user, err
+	}
+
+	email, emailOK := normalizeEmail(identity.Email)
+	emailOK = emailOK && identity.EmailVerified
+	var user *models.User
+	if emailOK {
+		user, err = h.store.GetUserByEmail(email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil && !user.EmailVerified {
+			return nil, errOIDCEmailConflict
+		}
+	}
+	if user == nil {
+		if user, err = h.provisionOIDCUser(identity, email, emailOK); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.store.CreateUserIdentity(&models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}); err != nil {
+		return nil, err
+	}
+	h.recordAudit(c, models.AuditOIDCLink, user.ID, provider+":"+identity.Subject, identity.Email)
+	return user, nil
+}
+
+// provisionOIDCUser 为外部身份创建新用户
+// 新用户没有密码，只能通过第三方登录或重置密码后登录；邮箱未经身份提供方验证时不保存
+func (h *Handler) provisionOIDCUser(identity *oidc.Identity, email string, emailVerified bool) (*models.User, error) {
+	username, err := h.availableUsername(identity, email)
+	if err != nil {
+		return nil, err
+	}
+	nickname := sanitize.Text(identity.Name)
+	if nickname == "" {
+		nickname = username
+	}
+	user := &models.User{
+		Username: username,
+		Nickname: nickname,
+		Role:     models.RoleReader,
+	}
+	if emailVerified {
+		user.Email = email
+		user.EmailVerified = true
+	}
+	if err := h.store.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// availableUsername 根据外部身份生成未被占用的用户名
+// 优先使用preferred_username，其次是邮箱的本地部分，重名时追加数字后缀
+func (h *Handler) availableUsername(identity *oidc.Identity, email string) (string, error) {
+	base := sanitizeUsername(identity.PreferredUsername)
+	if base == "" {
+		local, _, _ := strings.Cut(email, "@")
+		base = sanitizeUsername(local)
+	}
+	if base == "" {
+		base = "user"
+	}
+	candidate := base
+	for i := 2; ; i++ {
+		existing, err := h.store.GetUserByUsername(candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+		candidate = base + strconv.Itoa(i)
+	}
+}
+
+// sanitizeUsername 只保留字母、数字、下划线、连字符和点，最长32个字符
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	runes := []rune(b.String())
+	if len(runes) > 32 {
+		runes = runes[:32]
+	}
+	return string(runes)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"gofile/internal/markdown"
+	"gofile/internal/sanitize"
+	"gofile/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文章内容的返回格式
+const (
+	formatMarkdown = "markdown" // 作者编写的Markdown原文
+	formatHTML     = "html"     // 渲染后的HTML
+	formatText     = "text"     // 去掉标记的纯文本
+)
+
+// articleView 按指定格式返回的文章，content字段为对应格式的内容
+type articleView struct {
+	*models.Article
+	Format string              `json:"format"`        // 内容格式：markdown、html或text
+	TOC    []*markdown.Heading `json:"toc,omitempty"` // 由标题生成的目录，只在html格式下返回
+}
+
+// newArticleView 把文章内容转换为指定格式，不修改传入的文章
+// 参数：
+//
+//	article - 文章
+//	format - 内容格式：markdown、html或text
+//
+// 返回：
+//
+//	*articleView - 转换后的文章
+//	error - 渲染失败时返回错误
+func (h *Handler) newArticleView(article *models.Article, format string) (*articleView, error) {
+	a := *article
+	view := &articleView{Article: &a, Format: format}
+	switch format {
+	case formatHTML:
+		result, err := h.renderArticle(article)
+		if err != nil {
+			return nil, err
+		}
+		a.Content, view.TOC = result.HTML, result.TOC
+	case formatText:
+		a.Content = markdown.PlainText(article.Content)
+	}
+	return view, nil
+}
+
+// renderArticle 返回文章内容渲染后的HTML和目录
+// 优先使用存储中的渲染缓存，缓存不存在或渲染器版本已变化时重新渲染并写回缓存，写回失败只记录日志
+func (h *Handler) renderArticle(article *models.Article) (*markdown.Result, error) {
+	if article.RenderVersion == markdown.Version {
+		var toc []*markdown.Heading
+		if err := json.Unmarshal([]byte(article.TOC), &toc); err == nil {
+			return &markdown.Result{HTML: article.ContentHTML, TOC: toc}, nil
+		}
+	}
+	result, err := h.markdown.Render(article.Content)
+	if err != nil {
+		return nil, err
+	}
+	// 渲染器原样输出Markdown中的原始HTML，写入时过滤过的内容也要再次过滤，
+	// 以覆盖Markdown链接中的危险URL和过滤规则更新前保存的文章
+	result.HTML = sanitize.Article(result.HTML)
+	toc, err := json.Marshal(result.TOC)
+	if err != nil {
+		return nil, err
+	}
+	cached := *article
+	cached.ContentHTML, cached.TOC, cached.RenderVersion = result.HTML, string(toc), markdown.Version
+	if err := h.store.SaveArticleRender(&cached); err != nil {
+		log.Printf("保存文章渲染缓存失败 article=%d: %v", article.ID, err)
+	}
+	return result, nil
+}
+
+// GetHighlightCSS 处理获取代码高亮样式表的请求
+// 此函数处理HTTP GET请求，返回按配置的配色主题生成的CSS，
+// 前端展示html格式的文章时引用此样式表
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	text/css格式的样式表
+func (h *Handler) GetHighlightCSS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", []byte(h.markdown.StyleSheet()))
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"gofile/internal/markdown"
+	"gofile/internal/search"
+	"gofile/models"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 搜索相关的长度和分页限制
+const (
+	searchQueryMaxLen = 100 // 搜索词的最大长度（字符数）
+	searchTermsMax    = 32  // 搜索词切分出的词数上限，超出部分被忽略
+	searchPageDefault = 10  // 每页结果数量的默认值
+	searchPageMax     = 50  // 每页结果数量上限
+	searchSnippetLen  = 160 // 摘要的最大长度（字符数）
+	suggestDefault    = 5   // 每一类搜索建议数量的默认值
+	suggestMax        = 10  // 每一类搜索建议数量上限
+)
+
+// searchDateLayout 按日期过滤时的日期格式
+const searchDateLayout = "2006-01-02"
+
+// searchResult 一条搜索结果
+type searchResult struct {
+	*models.Article
+	Score     float64         `json:"score"`     // 相关度，只用于同一次搜索的结果之间比较
+	Highlight searchHighlight `json:"highlight"` // 高亮后的标题和摘要
+}
+
+// searchHighlight 搜索结果的高亮，匹配的部分用<mark>标记，内容已转义HTML
+type searchHighlight struct {
+	Title   string `json:"title"`   // 高亮后的标题
+	Snippet string `json:"snippet"` // 正文中包含匹配的摘要
+}
+
+// SearchArticles 处理全文搜索文章的请求
+// 此函数处理HTTP GET请求，在已发布文章的标题和正文中搜索，按相关度降序返回，
+// 标题中的匹配比正文中的权重更高；中文按相邻两字匹配，文章必须包含搜索词中的所有词
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//	   包含了HTTP请求的所有信息（请求头、请求体、URL参数等）
+//
+// URL查询参数：
+//
+//	q - 搜索词，必填，最多100个字符
+//	tag - 标签的slug，可选参数
+//	category - 分类的slug，包括其子分类中的文章，可选参数
+//	author - 作者的用户名，可选参数
+//	from - 创建日期不早于，格式为2006-01-02，可选参数
+//	to - 创建日期不晚于（包括当天），格式为2006-01-02，可选参数
+//	page - 页码，默认为1
+//	limit - 每页数量，默认为10，最多50
+//
+// 返回：
+//
+//	JSON格式的响应，包含搜索结果列表，每条结果附带相关度score和高亮的标题、摘要highlight
+func (h *Handler) SearchArticles(c *gin.Context) {
+	q := c.Query("q")
+	if utf8.RuneCountInString(q) > searchQueryMaxLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索词过长"})
+		return
+	}
+	terms := search.Terms(q)
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "搜索词不能为空"})
+		return
+	}
+	if len(terms) > searchTermsMax {
+		terms = terms[:searchTermsMax]
+	}
+	query := models.SearchQuery{
+		Terms:    terms,
+		Tag:      c.Query("tag"),
+		Category: c.Query("category"),
+	}
+	var ok bool
+	if query.From, ok = searchDate(c, "from"); !ok {
+		return
+	}
+	if query.To, ok = searchDate(c, "to"); !ok {
+		return
+	}
+	if !query.To.IsZero() {
+		query.To = query.To.AddDate(0, 0, 1)
+	}
+	if username := c.Query("author"); username != "" {
+		author, err := h.store.GetUserByUsername(username)
+		if err != nil {
+			respondStoreError(c, err, "搜索文章失败")
+			return
+		}
+		if author == nil {
+			c.JSON(http.StatusOK, gin.H{"code": 0, "msg": "success", "data": []*searchResult{}})
+			return
+		}
+		query.AuthorID = author.ID
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(searchPageDefault)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = searchPageDefault
+	}
+	if limit > searchPageMax {
+		limit = searchPageMax
+	}
+	query.Limit, query.Offset = limit, (page-1)*limit
+
+	hits, err := h.store.SearchArticles(query)
+	if err != nil {
+		respondStoreError(c, err, "搜索文章失败")
+		return
+	}
+	results := make([]*searchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = &searchResult{
+			Article: hit.Article,
+			Score:   hit.Score,
+			Highlight: searchHighlight{
+				Title:   search.Highlight(html.UnescapeString(hit.Article.Title), terms, 0),
+				Snippet: search.Highlight(markdown.PlainText(hit.Article.Content), terms, searchSnippetLen),
+			},
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": results,
+	})
+}
+
+// searchDate 解析日期查询参数，参数为空时返回零值，格式错误时写入400响应并返回false
+func searchDate(c *gin.Context, name string) (time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	date, err := time.ParseInLocation(searchDateLayout, value, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期，格式应为" + searchDateLayout})
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// SuggestSearch 处理搜索框自动补全的请求
+// 此函数处理HTTP GET请求，返回标题、名称与输入匹配的已发布文章、标签和分类，按热度（浏览量）排序，
+// 并对输入中不在词表里的单词按编辑距离给出拼写纠错建议；补全结果没有匹配时改用第一个纠错建议匹配
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//	   包含了HTTP请求的所有信息（请求头、请求体、URL参数等）
+//
+// URL查询参数：
+//
+//	prefix - 用户已经输入的部分，必填，最多100个字符
+//	limit - 每一类建议的数量，默认为5，最多10
+//
+// 返回：
+//
+//	JSON格式的响应，data包含titles、tags、categories和did_you_mean
+func (h *Handler) SuggestSearch(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("prefix"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "输入不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(prefix) > searchQueryMaxLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "输入过长"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(suggestDefault)))
+	if limit < 1 {
+		limit = suggestDefault
+	}
+	if limit > suggestMax {
+		limit = suggestMax
+	}
+
+	result, err := h.suggester.Suggest(prefix, limit)
+	if err != nil {
+		respondStoreError(c, err, "获取搜索建议失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": result,
+	})
+}
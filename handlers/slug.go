@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"gofile/internal/slug"
+	"gofile/models"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// articleSlugFallback 标题中没有可用于slug的文字时使用的slug
+const articleSlugFallback = "article"
+
+// applyArticleSlug 设置文章的slug，校验失败时写入错误响应并返回false
+// requested为空时：文章已有slug则保持不变，否则由标题生成并在重复时加上-2、-3等后缀；
+// requested不为空时必须是合法的slug，且不能被其他文章使用（包括其他文章的历史slug），否则返回409
+func (h *Handler) applyArticleSlug(c *gin.Context, article *models.Article, requested string) bool {
+	if requested == "" {
+		if article.Slug != "" {
+			return true
+		}
+		generated, err := h.uniqueArticleSlug(article)
+		if err != nil {
+			respondStoreError(c, err, "生成文章slug失败")
+			return false
+		}
+		article.Slug = generated
+		return true
+	}
+	if requested == article.Slug {
+		return true
+	}
+	if !slug.Valid(requested) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug只能包含小写字母、数字和单个连字符"})
+		return false
+	}
+	taken, err := h.store.ArticleSlugTaken(requested, article.ID)
+	if err != nil {
+		respondStoreError(c, err, "检查文章slug失败")
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "slug已被其他文章使用"})
+		return false
+	}
+	article.Slug = requested
+	return true
+}
+
+// uniqueArticleSlug 由文章标题生成未被其他文章使用的slug
+// 标题经过sanitize.Text转义，生成slug前先还原HTML实体，避免"Tom & Jerry"生成tom-amp-jerry
+func (h *Handler) uniqueArticleSlug(article *models.Article) (string, error) {
+	base := slug.Make(slug.Transliterate(html.UnescapeString(article.Title)))
+	if base == "" {
+		base = articleSlugFallback
+	}
+	candidate := base
+	for n := 2; ; n++ {
+		taken, err := h.store.ArticleSlugTaken(candidate, article.ID)
+		if err != nil || !taken {
+			return candidate, err
+		}
+		candidate = slug.WithSuffix(base, n)
+	}
+}
+
+// GetArticleBySlug 处理按slug获取文章详情的请求
+// 此函数处理HTTP GET请求，根据文章当前的slug返回文章详细信息并增加已发布文章的浏览量，
+// slug是文章改名前使用的旧slug时返回301，重定向到当前slug对应的地址；草稿的可见范围与按ID获取相同
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//	   包含了HTTP请求的所有信息（请求头、请求体、URL参数等）
+//
+// URL路径参数：
+//
+//	slug - 文章的slug
+//
+// URL查询参数：
+//
+//	format - 内容格式，与按ID获取文章相同，重定向时保留
+//
+// 返回：
+//
+//	JSON格式的响应，包含文章详情数据或错误信息
+//	存储不可用时，若启用了快照则按当前的slug返回快照数据并设置X-Degraded-Mode响应头，否则返回503
+func (h *Handler) GetArticleBySlug(c *gin.Context) {
+	s := c.Param("slug")
+	format, ok := articleFormat(c)
+	if !ok {
+		return
+	}
+
+	article, err := h.store.GetArticleBySlug(s)
+	if err != nil {
+		if !h.serveFromSnapshot(c, err) {
+			respondStoreError(c, err, "获取文章失败")
+			return
+		}
+		article = h.snapshot.GetArticleBySlug(s)
+	} else if article != nil {
+		h.recordSnapshot(article)
+		h.countArticleView(article)
+	} else {
+		current, err := h.store.ResolveArticleSlug(s)
+		if err != nil {
+			respondStoreError(c, err, "获取文章失败")
+			return
+		}
+		if current != "" {
+			location := strings.TrimSuffix(c.FullPath(), ":slug") + url.PathEscape(current)
+			if c.Request.URL.RawQuery != "" {
+				location += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusMovedPermanently, location)
+			return
+		}
+	}
+	h.respondArticle(c, article, format)
+}
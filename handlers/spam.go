@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"gofile/internal/spam"
+	"gofile/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkSpam 用垃圾内容过滤器检查一次提交
+// 未启用过滤器时放行；过滤器出错时只记录日志，按需要人工审核处理
+func (h *Handler) checkSpam(sub spam.Submission) spam.Result {
+	if h.spam == nil {
+		return spam.Result{Action: spam.ActionAllow}
+	}
+	result, err := h.spam.Check(sub)
+	if err != nil {
+		log.Printf("垃圾内容检查失败: %v", err)
+		return spam.Result{Action: spam.ActionReview}
+	}
+	if result.Action != spam.ActionAllow {
+		log.Printf("垃圾内容检查 ip=%s score=%.2f reasons=%s", sub.IP, result.Score, strings.Join(result.Reasons, ","))
+	}
+	return result
+}
+
+// trainComment 按评论的新审核状态更新分类器
+// 通过的评论作为正常样本，垃圾评论作为垃圾样本，改回待审核时撤销之前的训练；
+// 训练失败只记录日志，不影响审核结果
+func (h *Handler) trainComment(comment *models.Comment, previous string) {
+	if h.spam == nil || comment.Trained == previous {
+		return
+	}
+	sub := spam.Submission{Name: comment.GuestName, Content: comment.Content}
+	if err := h.spam.Train(sub, previous, comment.Trained); err != nil {
+		log.Printf("训练垃圾内容分类器失败 comment=%d: %v", comment.ID, err)
+	}
+}
+
+// commentTrainingClass 返回评论审核状态对应的训练类别，待审核的评论不参与训练
+func commentTrainingClass(status string) string {
+	switch status {
+	case models.CommentStatusSpam:
+		return spam.ClassSpam
+	case models.CommentStatusApproved:
+		return spam.ClassHam
+	}
+	return ""
+}
+
+// GetFormToken 处理获取表单令牌的请求
+// 此函数处理HTTP GET请求，前端打开评论或注册表单时调用，提交表单时把令牌放在form_token字段中；
+// 提交过快或没有令牌的请求更容易被判定为垃圾内容。未启用垃圾内容过滤时令牌为空
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含表单令牌及其有效期（秒）
+func (h *Handler) GetFormToken(c *gin.Context) {
+	token, expiresIn := "", 0
+	if h.spam != nil {
+		token, expiresIn = h.spam.IssueFormToken(), int(h.spam.FormTokenTTL().Seconds())
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"form_token": token,
+			"expires_in": expiresIn,
+		},
+	})
+}
+
+// requireSpamFilter 检查是否启用了垃圾内容过滤，未启用时返回404
+func (h *Handler) requireSpamFilter(c *gin.Context) bool {
+	if h.spam == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未启用垃圾内容过滤"})
+		return false
+	}
+	return true
+}
+
+// GetSpamBlocklist 处理获取垃圾内容屏蔽列表的请求
+// 此函数处理HTTP GET请求，需要user:manage权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含屏蔽列表条目或错误信息
+func (h *Handler) GetSpamBlocklist(c *gin.Context) {
+	if !h.requireSpamFilter(c) {
+		return
+	}
+	entries, err := h.spam.Blocklist()
+	if err != nil {
+		respondStoreError(c, err, "获取屏蔽列表失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": entries,
+	})
+}
+
+// CreateSpamBlockEntry 处理添加屏蔽列表条目的请求
+// 此函数处理HTTP POST请求，需要user:manage权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	kind - 条目类型：word（内容或昵称中的词）、ip（IP或CIDR网段）或domain（邮箱和链接的域名，包括子域名）
+//	value - 屏蔽的值，不区分大小写
+//
+// 返回：
+//
+//	JSON格式的响应，包含添加的条目或错误信息
+func (h *Handler) CreateSpamBlockEntry(c *gin.Context) {
+	if !h.requireSpamFilter(c) {
+		return
+	}
+	var req struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !models.ValidSpamBlockKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的屏蔽列表类型"})
+		return
+	}
+	entry, err := h.spam.AddBlockEntry(req.Kind, req.Value)
+	switch {
+	case errors.Is(err, spam.ErrInvalidBlockEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的屏蔽列表条目"})
+		return
+	case errors.Is(err, spam.ErrDuplicateBlockEntry):
+		c.JSON(http.StatusConflict, gin.H{"error": "屏蔽列表条目已存在"})
+		return
+	case err != nil:
+		respondStoreError(c, err, "添加屏蔽列表条目失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": entry,
+	})
+}
+
+// DeleteSpamBlockEntry 处理删除屏蔽列表条目的请求
+// 此函数处理HTTP DELETE请求，需要user:manage权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 条目ID
+//
+// 返回：
+//
+//	JSON格式的响应，包含操作结果或错误信息
+func (h *Handler) DeleteSpamBlockEntry(c *gin.Context) {
+	if !h.requireSpamFilter(c) {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的条目ID"})
+		return
+	}
+	found, err := h.spam.DeleteBlockEntry(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "删除屏蔽列表条目失败")
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "屏蔽列表条目不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// TrainSpam 处理用样本训练垃圾内容分类器的请求
+// 此函数处理HTTP POST请求，需要user:manage权限，用于导入已有的垃圾和正常样本；
+// 审核评论时分类器会自动训练，不需要调用此接口
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	content - 样本正文
+//	name - 样本的昵称，可选参数
+//	class - 样本类别：spam或ham
+//
+// 返回：
+//
+//	JSON格式的响应，包含操作结果或错误信息
+func (h *Handler) TrainSpam(c *gin.Context) {
+	if !h.requireSpamFilter(c) {
+		return
+	}
+	var req struct {
+		Content string `json:"content"`
+		Name    string `json:"name"`
+		Class   string `json:"class"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Content) == "" ||
+		(req.Class != spam.ClassSpam && req.Class != spam.ClassHam) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "样本内容不能为空，类别必须是spam或ham"})
+		return
+	}
+	if err := h.spam.Train(spam.Submission{Name: req.Name, Content: req.Content}, "", req.Class); err != nil {
+		respondStoreError(c, err, "训练分类器失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"gofile/internal/sanitize"
+	"gofile/internal/slug"
+	"gofile/models"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	taxonomyNameMaxLen        = 32  // 标签和分类名称的最大长度（字符数）
+	taxonomyDescriptionMaxLen = 200 // 标签和分类说明的最大长度（字符数）
+	articleMaxTags            = 10  // 一篇文章最多的标签数量
+)
+
+// errInvalidTag 文章的标签名称无效或数量过多
+var errInvalidTag = errors.New("invalid tag")
+
+// articleRequest 创建和更新文章的请求体
+// tags是标签名称列表，覆盖Article.Tags；分类通过category_id指定
+type articleRequest struct {
+	models.Article
+	Tags []string `json:"tags"` // 标签名称，不存在的标签自动创建，为nil表示不修改
+}
+
+// applyArticleTaxonomy 按请求设置文章的分类和标签
+// 分类不存在或标签无效时写入400响应并返回false
+func (h *Handler) applyArticleTaxonomy(c *gin.Context, article *models.Article, req articleRequest) bool {
+	if id := req.CategoryID; id != nil {
+		article.CategoryID, article.Category = nil, nil
+		if *id != 0 {
+			category, err := h.store.GetCategoryByID(uint(*id))
+			if err != nil {
+				respondStoreError(c, err, "获取分类失败")
+				return false
+			}
+			if category == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+				return false
+			}
+			article.CategoryID, article.Category = &category.ID, category
+		}
+	}
+	if req.Tags != nil {
+		tags, err := h.resolveTags(req.Tags)
+		if errors.Is(err, errInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多%d个标签，每个标签不超过%d个字符且包含字母或数字", articleMaxTags, taxonomyNameMaxLen)})
+			return false
+		}
+		if err != nil {
+			respondStoreError(c, err, "保存标签失败")
+			return false
+		}
+		article.Tags = tags
+	}
+	return true
+}
+
+// resolveTags 把标签名称转换为标签，不存在的标签自动创建
+// 空白的名称被忽略，名称相同（不区分大小写）的标签视为同一个标签；
+// 名称不同但生成的slug相同时（如"C"和"C++"）新标签的slug加上数字后缀
+func (h *Handler) resolveTags(names []string) ([]*models.Tag, error) {
+	if len(names) > articleMaxTags {
+		return nil, errInvalidTag
+	}
+	existing, err := h.store.GetTags()
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]*models.Tag, 0, len(names))
+	seen := make(map[int]bool, len(names))
+	for _, name := range names {
+		name = sanitize.Text(name)
+		if name == "" {
+			continue
+		}
+		s := nameSlug(name)
+		if s == "" || utf8.RuneCountInString(name) > taxonomyNameMaxLen {
+			return nil, errInvalidTag
+		}
+		tag := findTag(existing, name)
+		if tag == nil {
+			s = uniqueSlug(s, func(s string) bool { return tagSlugTaken(existing, s, 0) })
+			tag = &models.Tag{Name: name, Slug: s}
+			if err := h.store.CreateTag(tag); err != nil {
+				// 并发创建同名标签时唯一约束冲突，改用已创建的标签
+				if tag, _ = h.store.GetTagBySlug(s); tag == nil || !strings.EqualFold(tag.Name, name) {
+					return nil, err
+				}
+			}
+			existing = append(existing, tag)
+		}
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			tag.ArticleCount = 0
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// findTag 按名称查找标签，不区分大小写
+func findTag(tags []*models.Tag, name string) *models.Tag {
+	for _, tag := range tags {
+		if strings.EqualFold(tag.Name, name) {
+			return tag
+		}
+	}
+	return nil
+}
+
+// tagSlugTaken 判断slug是否已被exceptID以外的标签使用
+func tagSlugTaken(tags []*models.Tag, s string, exceptID int) bool {
+	for _, tag := range tags {
+		if tag.ID != exceptID && tag.Slug == s {
+			return true
+		}
+	}
+	return false
+}
+
+// categorySlugTaken 判断slug是否已被exceptID以外的分类使用
+func categorySlugTaken(categories []*models.Category, s string, exceptID int) bool {
+	for _, category := range categories {
+		if category.ID != exceptID && category.Slug == s {
+			return true
+		}
+	}
+	return false
+}
+
+// nameSlug 由标签或分类名称生成slug
+// 名称经过sanitize.Text转义，先还原HTML实体，避免"Tom & Jerry"生成tom-amp-jerry
+func nameSlug(name string) string {
+	return slug.Make(html.UnescapeString(name))
+}
+
+// uniqueSlug 返回以base为基础且未被使用的slug
+// base已被使用时依次尝试base-2、base-3……，用于名称不同但生成的slug相同的标签和分类
+func uniqueSlug(base string, taken func(s string) bool) string {
+	s := base
+	for n := 2; taken(s); n++ {
+		s = slug.WithSuffix(base, n)
+	}
+	return s
+}
+
+// taxonomyRequest 创建和更新标签、分类的请求体
+type taxonomyRequest struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	ParentID    *int   `json:"parent_id"`
+}
+
+// bindTaxonomyRequest 解析并校验标签或分类的请求体
+// 名称和说明只保留纯文本，slug为空时由调用方根据名称生成；校验失败时写入400响应并返回false
+func bindTaxonomyRequest(c *gin.Context) (*taxonomyRequest, bool) {
+	var req taxonomyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		return nil, false
+	}
+	req.Name, req.Description = sanitize.Text(req.Name), sanitize.Text(req.Description)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > taxonomyNameMaxLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("名称不能为空且不超过%d个字符", taxonomyNameMaxLen)})
+		return nil, false
+	}
+	if utf8.RuneCountInString(req.Description) > taxonomyDescriptionMaxLen {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("说明不能超过%d个字符", taxonomyDescriptionMaxLen)})
+		return nil, false
+	}
+	if (req.Slug == "" && nameSlug(req.Name) == "") || (req.Slug != "" && !slug.Valid(req.Slug)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug只能包含小写字母、数字和单个连字符"})
+		return nil, false
+	}
+	return &req, true
+}
+
+// GetTags 处理获取标签列表的请求
+// 此函数处理HTTP GET请求，返回全部标签，按名称排序
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含标签及其已发布的文章数量article_count
+func (h *Handler) GetTags(c *gin.Context) {
+	tags, err := h.store.GetTags()
+	if err != nil {
+		respondStoreError(c, err, "获取标签失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": tags,
+	})
+}
+
+// CreateTag 处理创建标签的请求
+// 此函数处理HTTP POST请求，需要taxonomy:manage权限；
+// 作者保存文章时使用的新标签会自动创建，不需要先调用此接口
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	name - 标签名称
+//	slug - 标签的slug，可选参数，为空时由名称生成
+//	description - 标签说明，可选参数
+//
+// 返回：
+//
+//	JSON格式的响应，包含创建的标签或错误信息，slug已存在时返回409
+func (h *Handler) CreateTag(c *gin.Context) {
+	req, ok := bindTaxonomyRequest(c)
+	if !ok {
+		return
+	}
+	tag := &models.Tag{Name: req.Name, Description: req.Description}
+	if !h.assignTagSlug(c, tag, req.Slug) {
+		return
+	}
+	if err := h.store.CreateTag(tag); err != nil {
+		respondStoreError(c, err, "创建标签失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": tag,
+	})
+}
+
+// UpdateTag 处理修改标签的请求
+// 此函数处理HTTP PUT请求，需要taxonomy:manage权限，用于重命名标签或修改说明，
+// 文章与标签的关联保持不变
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 标签ID
+//
+// 请求体（JSON格式）：
+//
+//	name - 标签名称
+//	slug - 标签的slug，可选参数，为空时由名称生成
+//	description - 标签说明，可选参数
+//
+// 返回：
+//
+//	JSON格式的响应，包含修改后的标签或错误信息，slug已被其他标签使用时返回409
+func (h *Handler) UpdateTag(c *gin.Context) {
+	tag := h.loadTag(c)
+	if tag == nil {
+		return
+	}
+	req, ok := bindTaxonomyRequest(c)
+	if !ok {
+		return
+	}
+	tag.Name, tag.Description = req.Name, req.Description
+	if !h.assignTagSlug(c, tag, req.Slug) {
+		return
+	}
+	if err := h.store.UpdateTag(tag); err != nil {
+		respondStoreError(c, err, "修改标签失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": tag,
+	})
+}
+
+// DeleteTag 处理删除标签的请求
+// 此函数处理HTTP DELETE请求，需要taxonomy:manage权限，标签会从所有文章中移除
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 标签ID
+//
+// 返回：
+//
+//	JSON格式的响应，包含操作结果或错误信息
+func (h *Handler) DeleteTag(c *gin.Context) {
+	tag := h.loadTag(c)
+	if tag == nil {
+		return
+	}
+	if err := h.store.DeleteTag(tag); err != nil {
+		respondStoreError(c, err, "删除标签失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// loadTag 按URL中的id加载标签，失败时写入响应并返回nil
+func (h *Handler) loadTag(c *gin.Context) *models.Tag {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签ID"})
+		return nil
+	}
+	tag, err := h.store.GetTagByID(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "获取标签失败")
+		return nil
+	}
+	if tag == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "标签不存在"})
+		return nil
+	}
+	return tag
+}
+
+// assignTagSlug 设置标签的slug，标签重复时写入409响应并返回false
+// 请求指定的slug不能被其他标签使用；未指定时由名称生成，名称与其他标签相同（不区分大小写）视为重复，
+// 名称不同但生成的slug相同时加上数字后缀
+func (h *Handler) assignTagSlug(c *gin.Context, tag *models.Tag, requested string) bool {
+	tags, err := h.store.GetTags()
+	if err != nil {
+		respondStoreError(c, err, "获取标签失败")
+		return false
+	}
+	if requested == "" {
+		if other := findTag(tags, tag.Name); other != nil && other.ID != tag.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "标签已存在"})
+			return false
+		}
+		tag.Slug = uniqueSlug(nameSlug(tag.Name), func(s string) bool { return tagSlugTaken(tags, s, tag.ID) })
+		return true
+	}
+	if tagSlugTaken(tags, requested, tag.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "标签已存在"})
+		return false
+	}
+	tag.Slug = requested
+	return true
+}
+
+// GetCategories 处理获取分类列表的请求
+// 此函数处理HTTP GET请求，返回全部分类，按名称排序，层级关系由parent_id表示
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 返回：
+//
+//	JSON格式的响应，包含分类及其已发布的文章数量article_count（包括子分类中的文章）
+func (h *Handler) GetCategories(c *gin.Context) {
+	categories, err := h.store.GetCategories()
+	if err != nil {
+		respondStoreError(c, err, "获取分类失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": categories,
+	})
+}
+
+// CreateCategory 处理创建分类的请求
+// 此函数处理HTTP POST请求，需要taxonomy:manage权限
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// 请求体（JSON格式）：
+//
+//	name - 分类名称
+//	slug - 分类的slug，可选参数，为空时由名称生成
+//	description - 分类说明，可选参数
+//	parent_id - 父分类ID，可选参数，为空时是顶层分类
+//
+// 返回：
+//
+//	JSON格式的响应，包含创建的分类或错误信息，slug已存在时返回409
+func (h *Handler) CreateCategory(c *gin.Context) {
+	req, ok := bindTaxonomyRequest(c)
+	if !ok {
+		return
+	}
+	category := &models.Category{Name: req.Name, Description: req.Description}
+	if !h.setCategoryParent(c, category, req.ParentID) || !h.assignCategorySlug(c, category, req.Slug) {
+		return
+	}
+	if err := h.store.CreateCategory(category); err != nil {
+		respondStoreError(c, err, "创建分类失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": category,
+	})
+}
+
+// UpdateCategory 处理修改分类的请求
+// 此函数处理HTTP PUT请求，需要taxonomy:manage权限，可以重命名分类或移动到其他父分类下
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 分类ID
+//
+// 请求体（JSON格式）：
+//
+//	name - 分类名称
+//	slug - 分类的slug，可选参数，为空时由名称生成
+//	description - 分类说明，可选参数
+//	parent_id - 父分类ID，为空时改为顶层分类，不能是分类自身或其子分类
+//
+// 返回：
+//
+//	JSON格式的响应，包含修改后的分类或错误信息，slug已被其他分类使用时返回409
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	category := h.loadCategory(c)
+	if category == nil {
+		return
+	}
+	req, ok := bindTaxonomyRequest(c)
+	if !ok {
+		return
+	}
+	category.Name, category.Description = req.Name, req.Description
+	if !h.setCategoryParent(c, category, req.ParentID) || !h.assignCategorySlug(c, category, req.Slug) {
+		return
+	}
+	if err := h.store.UpdateCategory(category); err != nil {
+		respondStoreError(c, err, "修改分类失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": category,
+	})
+}
+
+// DeleteCategory 处理删除分类的请求
+// 此函数处理HTTP DELETE请求，需要taxonomy:manage权限；
+// 分类中的文章变为未分类，子分类移到被删除分类的父分类下
+// 参数：
+//
+//	c - Gin框架的上下文对象(*gin.Context)，由Gin框架自动传入
+//
+// URL路径参数：
+//
+//	id - 分类ID
+//
+// 返回：
+//
+//	JSON格式的响应，包含操作结果或错误信息
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	category := h.loadCategory(c)
+	if category == nil {
+		return
+	}
+	if err := h.store.DeleteCategory(category); err != nil {
+		respondStoreError(c, err, "删除分类失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// loadCategory 按URL中的id加载分类，失败时写入响应并返回nil
+func (h *Handler) loadCategory(c *gin.Context) *models.Category {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分类ID"})
+		return nil
+	}
+	category, err := h.store.GetCategoryByID(uint(id))
+	if err != nil {
+		respondStoreError(c, err, "获取分类失败")
+		return nil
+	}
+	if category == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+		return nil
+	}
+	return category
+}
+
+// assignCategorySlug 设置分类的slug，分类重复时写入409响应并返回false
+// 规则与assignTagSlug相同：名称不同但生成的slug相同时加上数字后缀
+func (h *Handler) assignCategorySlug(c *gin.Context, category *models.Category, requested string) bool {
+	categories, err := h.store.GetCategories()
+	if err != nil {
+		respondStoreError(c, err, "获取分类失败")
+		return false
+	}
+	if requested == "" {
+		for _, other := range categories {
+			if other.ID != category.ID && strings.EqualFold(other.Name, category.Name) {
+				c.JSON(http.StatusConflict, gin.H{"error": "分类已存在"})
+				return false
+			}
+		}
+		category.Slug = uniqueSlug(nameSlug(category.Name), func(s string) bool { return categorySlugTaken(categories, s, category.ID) })
+		return true
+	}
+	if categorySlugTaken(categories, requested, category.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "分类已存在"})
+		return false
+	}
+	category.Slug = requested
+	return true
+}
+
+// setCategoryParent 设置分类的父分类
+// 父分类必须存在，且不能是分类自身或其子分类；校验失败时写入响应并返回false
+func (h *Handler) setCategoryParent(c *gin.Context, category *models.Category, parentID *int) bool {
+	category.ParentID = nil
+	if parentID == nil || *parentID == 0 {
+		return true
+	}
+	categories, err := h.store.GetCategories()
+	if err != nil {
+		respondStoreError(c, err, "获取分类失败")
+		return false
+	}
+	var parent *models.Category
+	for _, candidate := range categories {
+		if candidate.ID == *parentID {
+			parent = candidate
+		}
+	}
+	if parent == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "父分类不存在"})
+		return false
+	}
+	if category.ID != 0 {
+		for _, id := range models.CategoryDescendants(categories, category.ID) {
+			if id == parent.ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "不能移动到自身或子分类下"})
+				return false
+			}
+		}
+	}
+	category.ParentID = &parent.ID
+	return true
+}
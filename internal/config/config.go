@@ -2,6 +2,9 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -13,18 +16,30 @@ var AppConfig *Config
 // 包含应用程序的所有配置部分
 // 使用mapstructure标签与viper配合实现配置文件映射
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`   // 服务器相关配置
-	Database DatabaseConfig `mapstructure:"database"` // 数据库相关配置
+	Server    ServerConfig    `mapstructure:"server"`     // 服务器相关配置
+	Database  DatabaseConfig  `mapstructure:"database"`   // 数据库相关配置
+	Snapshot  SnapshotConfig  `mapstructure:"snapshot"`   // 降级模式快照缓存配置
+	Auth      AuthConfig      `mapstructure:"auth"`       // 认证相关配置
+	Mail      MailConfig      `mapstructure:"mail"`       // 邮件发送配置
+	OIDC      OIDCConfig      `mapstructure:"oidc"`       // 第三方登录配置
+	CORS      CORSConfig      `mapstructure:"cors"`       // 跨域资源共享配置
+	Security  SecurityConfig  `mapstructure:"security"`   // 安全响应头和CSRF防护配置
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"` // 按路由组的请求限流配置
+	Spam      SpamConfig      `mapstructure:"spam"`       // 评论和注册的垃圾内容过滤配置
+	Markdown  MarkdownConfig  `mapstructure:"markdown"`   // 文章内容的Markdown渲染配置
 }
+
 // ServerConfig 服务器配置结构体
 // 包含HTTP服务器的配置信息
 type ServerConfig struct {
-	Port string `mapstructure:"port"` // 服务器监听端口
+	Port           string   `mapstructure:"port"`            // 服务器监听端口
+	TrustedProxies []string `mapstructure:"trusted_proxies"` // 可信的反向代理地址或网段，只采信来自这些地址的X-Forwarded-For，为空时不信任任何代理
 }
 
 // DatabaseConfig 数据库配置结构体
 // 包含数据库连接的所有参数
-// 注意：在当前简化实现中，部分字段可能未被实际使用
+// Driver决定使用的存储后端：postgres、sqlite或memory
+// 使用sqlite时DBName表示数据库文件路径
 type DatabaseConfig struct {
 	Driver   string `mapstructure:"driver"`
 	Host     string `mapstructure:"host"`
@@ -36,6 +51,199 @@ type DatabaseConfig struct {
 	TimeZone string `mapstructure:"timezone"`
 }
 
+// DSN 根据驱动类型生成数据库连接字符串
+// postgres返回key=value格式的DSN，sqlite返回数据库文件路径，memory返回空字符串
+func (d DatabaseConfig) DSN() string {
+	switch d.Driver {
+	case "sqlite":
+		return d.DBName
+	case "memory":
+		return ""
+	}
+	// PostgreSQL DSN格式: host=host port=port user=username password=password dbname=dbname
+	sslMode := d.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+	dsn := "host=" + d.Host + " port=" + d.Port + " user=" + d.Username + " password=" + d.Password + " dbname=" + d.DBName + " sslmode=" + sslMode
+	if d.TimeZone != "" {
+		dsn += " TimeZone=" + d.TimeZone
+	}
+	return dsn
+}
+
+// SnapshotConfig 快照缓存配置结构体
+// 启用后，最近一次成功读取的文章和用户数据会持久化到磁盘，
+// 数据库不可用时以只读降级模式返回这些数据
+type SnapshotConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`        // 是否启用快照缓存
+	Path          string        `mapstructure:"path"`           // 快照文件路径
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // 写入磁盘的间隔，如"30s"
+}
+
+// AuthConfig 认证配置结构体
+// 访问令牌短期有效，过期后客户端使用刷新令牌换取新的令牌对
+// 访问令牌使用SigningKey指定的密钥签发，Keys中的所有密钥都可用于校验，
+// 轮换密钥时先加入新密钥并切换SigningKey，待旧令牌过期后再移除旧密钥
+type AuthConfig struct {
+	AccessTokenTTL  time.Duration      `mapstructure:"access_token_ttl"`  // 访问令牌有效期，如"15m"
+	RefreshTokenTTL time.Duration      `mapstructure:"refresh_token_ttl"` // 刷新令牌有效期，如"720h"
+	SigningKey      string             `mapstructure:"signing_key"`       // 用于签发令牌的密钥ID，为空时使用Keys中的第一个
+	Keys            []SigningKeyConfig `mapstructure:"keys"`              // 签名密钥列表
+
+	EmailTokenSecret string        `mapstructure:"email_token_secret"` // 邮箱验证和密码重置令牌的签名密钥
+	EmailVerifyTTL   time.Duration `mapstructure:"email_verify_ttl"`   // 邮箱验证令牌有效期，如"24h"
+	PasswordResetTTL time.Duration `mapstructure:"password_reset_ttl"` // 密码重置令牌有效期，如"1h"
+
+	Lockout LockoutConfig `mapstructure:"lockout"` // 登录失败限制
+	Session SessionConfig `mapstructure:"session"` // 浏览器前端使用的cookie会话
+}
+
+// SessionConfig cookie会话配置结构体
+// 登录时请求cookie会话，访问令牌和刷新令牌写入HttpOnly cookie，不出现在响应体中
+type SessionConfig struct {
+	CookieName        string `mapstructure:"cookie_name"`         // 保存访问令牌的cookie名称
+	RefreshCookieName string `mapstructure:"refresh_cookie_name"` // 保存刷新令牌的cookie名称
+	Domain            string `mapstructure:"domain"`              // cookie的Domain属性，为空时只对当前主机有效
+	Secure            bool   `mapstructure:"secure"`              // 只通过HTTPS发送cookie
+	SameSite          string `mapstructure:"same_site"`           // lax、strict或none
+}
+
+// LockoutConfig 登录失败限制配置结构体
+// 按用户名和来源IP分别统计计数窗口内的连续失败次数
+type LockoutConfig struct {
+	Store  string              `mapstructure:"store"`  // 计数存储：database与其他实例共享，memory只在本进程内有效
+	Window time.Duration       `mapstructure:"window"` // 计数窗口，距上次失败超过此时间后重新计数
+	User   LockoutPolicyConfig `mapstructure:"user"`   // 按用户名计数的策略
+	IP     LockoutPolicyConfig `mapstructure:"ip"`     // 按来源IP计数的策略
+}
+
+// LockoutPolicyConfig 登录失败限制策略
+// 前FreeAttempts次失败不受限制，之后等待时间从BaseDelay开始翻倍直至MaxDelay，
+// 达到LockAfter次时锁定LockDuration
+type LockoutPolicyConfig struct {
+	FreeAttempts int           `mapstructure:"free_attempts"` // 不受限制的失败次数
+	LockAfter    int           `mapstructure:"lock_after"`    // 达到此失败次数时锁定，0表示不锁定
+	BaseDelay    time.Duration `mapstructure:"base_delay"`    // 第一次退避的等待时间，如"1s"
+	MaxDelay     time.Duration `mapstructure:"max_delay"`     // 退避等待时间上限，如"1m"
+	LockDuration time.Duration `mapstructure:"lock_duration"` // 锁定时长，如"15m"
+}
+
+// SigningKeyConfig JWT签名密钥配置结构体
+// HS256使用Secret；RS256和EdDSA从PEM文件读取密钥，只配置公钥的密钥只能用于校验
+type SigningKeyConfig struct {
+	ID             string `mapstructure:"id"`               // 密钥ID，写入JWT头部的kid
+	Algorithm      string `mapstructure:"algorithm"`        // 签名算法：HS256、RS256或EdDSA
+	Secret         string `mapstructure:"secret"`           // HS256共享密钥，至少32字节
+	PrivateKeyFile string `mapstructure:"private_key_file"` // 私钥文件（RSA为PKCS#1或PKCS#8，Ed25519为PKCS#8）
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // 公钥文件（PKIX），提供私钥时可省略
+}
+
+// MailConfig 邮件配置结构体
+// Driver决定邮件的发送方式：smtp通过SMTP服务器发送，file写入Dir目录，log只打印到日志
+// 邮件中的链接由VerifyURL和ResetURL加上token查询参数组成
+type MailConfig struct {
+	Driver    string `mapstructure:"driver"`     // 发送方式：smtp、file或log
+	From      string `mapstructure:"from"`       // 发件人地址
+	Host      string `mapstructure:"host"`       // SMTP服务器地址
+	Port      string `mapstructure:"port"`       // SMTP服务器端口
+	Username  string `mapstructure:"username"`   // SMTP认证用户名，为空时不认证
+	Password  string `mapstructure:"password"`   // SMTP认证密码
+	Dir       string `mapstructure:"dir"`        // file方式保存邮件的目录
+	VerifyURL string `mapstructure:"verify_url"` // 邮箱验证链接地址
+	ResetURL  string `mapstructure:"reset_url"`  // 重置密码页面地址
+}
+
+// OIDCConfig 第三方登录配置结构体
+// 每个Providers元素对应一个OpenID Connect身份提供方，使用授权码流程加PKCE登录
+type OIDCConfig struct {
+	StateSecret string               `mapstructure:"state_secret"` // 登录状态cookie的签名密钥，多实例部署时必须一致
+	Providers   []OIDCProviderConfig `mapstructure:"providers"`    // 身份提供方列表
+}
+
+// OIDCProviderConfig OpenID Connect身份提供方配置
+// 回调地址为/api/auth/oidc/{name}/callback，需要在身份提供方登记
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`          // 名称，出现在登录和回调地址中，如"google"
+	DisplayName  string   `mapstructure:"display_name"`  // 登录按钮上显示的名称
+	Issuer       string   `mapstructure:"issuer"`        // 签发方地址，如"https://accounts.google.com"
+	ClientID     string   `mapstructure:"client_id"`     // 客户端ID
+	ClientSecret string   `mapstructure:"client_secret"` // 客户端密钥
+	RedirectURL  string   `mapstructure:"redirect_url"`  // 完整的回调地址
+	Scopes       []string `mapstructure:"scopes"`        // 申请的scope，openid会自动加入，通常还需要email和profile
+}
+
+// CORSConfig 跨域资源共享配置结构体
+// 作用于/api下的接口；允许的来源会被原样回显，不允许的来源不返回CORS响应头
+type CORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`   // 允许的来源，支持"https://*.example.com"形式的子域名通配，为空时不允许跨域
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`   // 允许的请求方法
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`   // 允许的请求头
+	ExposedHeaders   []string      `mapstructure:"exposed_headers"`   // 允许前端读取的响应头
+	MaxAge           time.Duration `mapstructure:"max_age"`           // 预检结果的缓存时间，如"10m"
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // 是否允许携带cookie，开启时allowed_origins不能包含"*"
+}
+
+// RateLimitConfig 限流配置结构体
+// Groups的键为路由组名称：api作用于所有/api接口，auth作用于登录、注册等认证接口，
+// write作用于文章写操作，comment作用于发表评论
+type RateLimitConfig struct {
+	Store  string                         `mapstructure:"store"`  // 令牌桶存储：database与其他实例共享，memory只在本进程内有效
+	Groups map[string]RateLimitRuleConfig `mapstructure:"groups"` // 各路由组的限流规则，没有配置的路由组不限流
+}
+
+// RateLimitRuleConfig 限流规则配置结构体
+// 令牌桶容量为Burst，每Period恢复Limit个令牌，每个请求消耗一个令牌
+type RateLimitRuleConfig struct {
+	Limit  int           `mapstructure:"limit"`  // 每个周期恢复的令牌数
+	Period time.Duration `mapstructure:"period"` // 恢复周期，如"1m"
+	Burst  int           `mapstructure:"burst"`  // 允许的突发请求数，0表示与limit相同
+	Key    string        `mapstructure:"key"`    // 限流对象：ip、user或api_key
+}
+
+// MarkdownConfig Markdown渲染配置结构体
+type MarkdownConfig struct {
+	HighlightStyle string `mapstructure:"highlight_style"` // 代码高亮的配色主题，如github、monokai、dracula
+}
+
+// SpamConfig 垃圾内容过滤配置结构体
+// 每项检查命中时增加评分：蜜罐字段1.0，提交过快0.6，表单令牌无效0.3，超出数量的每个链接0.3，
+// 命中屏蔽列表1.0，朴素贝叶斯分类器最多1.2；游客评论达到spam_threshold直接标记为垃圾评论，
+// 注册请求达到spam_threshold时被拒绝
+type SpamConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`          // 是否启用垃圾内容过滤
+	FormSecret      string        `mapstructure:"form_secret"`      // 表单令牌的签名密钥，多实例部署时必须一致
+	MinSubmitTime   time.Duration `mapstructure:"min_submit_time"`  // 从获取表单令牌到提交的最短时间，如"3s"
+	MaxFormAge      time.Duration `mapstructure:"max_form_age"`     // 表单令牌的有效期，如"2h"
+	MaxLinks        int           `mapstructure:"max_links"`        // 内容中允许的链接数量，小于0时不检查
+	ReviewThreshold float64       `mapstructure:"review_threshold"` // 需要人工审核的评分
+	SpamThreshold   float64       `mapstructure:"spam_threshold"`   // 判定为垃圾内容的评分
+}
+
+// SecurityConfig 安全配置结构体
+type SecurityConfig struct {
+	Headers SecurityHeadersConfig `mapstructure:"headers"` // 所有响应都会带上的安全响应头
+	CSRF    CSRFConfig            `mapstructure:"csrf"`    // 双重提交cookie方式的CSRF防护
+}
+
+// SecurityHeadersConfig 安全响应头配置结构体，字段为空时不发送对应的响应头
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy string        `mapstructure:"content_security_policy"` // CSP，{nonce}会替换为每个请求随机生成的值
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`            // HSTS有效期，如"8760h"，0表示不发送
+	HSTSIncludeSubdomains bool          `mapstructure:"hsts_include_subdomains"` // HSTS是否包含子域名
+	HSTSPreload           bool          `mapstructure:"hsts_preload"`            // HSTS是否声明preload
+	FrameOptions          string        `mapstructure:"frame_options"`           // X-Frame-Options
+	ReferrerPolicy        string        `mapstructure:"referrer_policy"`         // Referrer-Policy
+	PermissionsPolicy     string        `mapstructure:"permissions_policy"`      // Permissions-Policy
+}
+
+// CSRFConfig CSRF防护配置结构体
+// 只校验使用会话cookie认证的修改类请求，使用Authorization或X-API-Key请求头的请求不受影响
+type CSRFConfig struct {
+	CookieName string `mapstructure:"cookie_name"` // 保存CSRF令牌的cookie名称
+	HeaderName string `mapstructure:"header_name"` // 提交CSRF令牌的请求头名称
+}
+
 // Init 初始化配置
 // 此函数负责：
 // 1. 设置viper配置文件名和类型
@@ -44,19 +252,85 @@ type DatabaseConfig struct {
 // 4. 读取配置文件
 // 5. 将配置解析到AppConfig全局变量
 // 注意：
-//   如果配置文件读取失败，函数会直接panic，终止程序启动
+//
+//	如果配置文件读取失败，函数会直接panic，终止程序启动
 func Init() {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./")
-	
+
 	// 设置默认配置值
 	viper.SetDefault("server.port", "8080")
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("database.driver", "postgres")
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", "3306")
 	viper.SetDefault("database.username", "root")
 	viper.SetDefault("database.password", "root")
 	viper.SetDefault("database.dbname", "blog_db")
+	viper.SetDefault("snapshot.enabled", false)
+	viper.SetDefault("snapshot.path", "data/snapshot.json")
+	viper.SetDefault("snapshot.flush_interval", "30s")
+	viper.SetDefault("auth.access_token_ttl", "15m")
+	viper.SetDefault("auth.refresh_token_ttl", "720h")
+	viper.SetDefault("auth.email_verify_ttl", "24h")
+	viper.SetDefault("auth.password_reset_ttl", "1h")
+	viper.SetDefault("auth.lockout.store", "database")
+	viper.SetDefault("auth.lockout.window", "15m")
+	viper.SetDefault("auth.lockout.user.free_attempts", 3)
+	viper.SetDefault("auth.lockout.user.lock_after", 10)
+	viper.SetDefault("auth.lockout.user.base_delay", "1s")
+	viper.SetDefault("auth.lockout.user.max_delay", "1m")
+	viper.SetDefault("auth.lockout.user.lock_duration", "15m")
+	viper.SetDefault("auth.lockout.ip.free_attempts", 20)
+	viper.SetDefault("auth.lockout.ip.lock_after", 100)
+	viper.SetDefault("auth.lockout.ip.base_delay", "1s")
+	viper.SetDefault("auth.lockout.ip.max_delay", "1m")
+	viper.SetDefault("auth.lockout.ip.lock_duration", "15m")
+	viper.SetDefault("auth.session.cookie_name", "session")
+	viper.SetDefault("auth.session.refresh_cookie_name", "session_refresh")
+	viper.SetDefault("auth.session.secure", true)
+	viper.SetDefault("auth.session.same_site", "lax")
+	viper.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE"})
+	viper.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization", "X-API-Key", "X-CSRF-Token", "X-Requested-With"})
+	viper.SetDefault("cors.exposed_headers", []string{"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"})
+	viper.SetDefault("cors.max_age", "10m")
+	viper.SetDefault("cors.allow_credentials", true)
+	viper.SetDefault("security.headers.content_security_policy", "default-src 'self'; script-src 'self' 'nonce-{nonce}'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; object-src 'none'; base-uri 'self'; frame-ancestors 'none'; form-action 'self'")
+	viper.SetDefault("security.headers.hsts_max_age", "8760h")
+	viper.SetDefault("security.headers.frame_options", "DENY")
+	viper.SetDefault("security.headers.referrer_policy", "strict-origin-when-cross-origin")
+	viper.SetDefault("security.headers.permissions_policy", "camera=(), microphone=(), geolocation=()")
+	viper.SetDefault("rate_limit.store", "database")
+	viper.SetDefault("rate_limit.groups.api.limit", 300)
+	viper.SetDefault("rate_limit.groups.api.period", "1m")
+	viper.SetDefault("rate_limit.groups.api.burst", 100)
+	viper.SetDefault("rate_limit.groups.api.key", "ip")
+	viper.SetDefault("rate_limit.groups.auth.limit", 10)
+	viper.SetDefault("rate_limit.groups.auth.period", "1m")
+	viper.SetDefault("rate_limit.groups.auth.key", "ip")
+	viper.SetDefault("rate_limit.groups.write.limit", 60)
+	viper.SetDefault("rate_limit.groups.write.period", "1m")
+	viper.SetDefault("rate_limit.groups.write.burst", 30)
+	viper.SetDefault("rate_limit.groups.write.key", "api_key")
+	viper.SetDefault("rate_limit.groups.comment.limit", 5)
+	viper.SetDefault("rate_limit.groups.comment.period", "1m")
+	viper.SetDefault("rate_limit.groups.comment.key", "user")
+	viper.SetDefault("markdown.highlight_style", "github")
+	viper.SetDefault("spam.enabled", true)
+	viper.SetDefault("spam.min_submit_time", "3s")
+	viper.SetDefault("spam.max_form_age", "2h")
+	viper.SetDefault("spam.max_links", 2)
+	viper.SetDefault("spam.review_threshold", 0.5)
+	viper.SetDefault("spam.spam_threshold", 1.0)
+	viper.SetDefault("security.csrf.cookie_name", "csrf_token")
+	viper.SetDefault("security.csrf.header_name", "X-CSRF-Token")
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.from", "blog@localhost")
+	viper.SetDefault("mail.port", "25")
+	viper.SetDefault("mail.dir", "data/mail")
+	viper.SetDefault("mail.verify_url", "http://localhost:8080/api/user/verify")
+	viper.SetDefault("mail.reset_url", "http://localhost:8080/reset-password")
 
 	// 尝试读取配置文件，如果失败则打印警告但不panic
 	if err := viper.ReadInConfig(); err != nil {
@@ -68,3 +342,25 @@ func Init() {
 		panic(err)
 	}
 }
+
+// placeholderMarkers 示例配置中提示修改密钥的字样
+// 含有这些字样的密钥随代码仓库公开，任何人都能用它伪造签名
+var placeholderMarkers = []string{"please-change", "change-this", "changeme"}
+
+// IsPlaceholderSecret 判断密钥是否是未修改的示例占位值
+// 参数：
+//
+//	secret - 配置的密钥
+//
+// 返回：
+//
+//	bool - 密钥包含示例中提示修改的字样时返回true
+func IsPlaceholderSecret(secret string) bool {
+	lower := strings.ToLower(secret)
+	for _, marker := range placeholderMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// Package mail 提供发送邮件的Mailer接口及其实现
+// SMTPMailer通过SMTP服务器发送，FileMailer将邮件写入目录，LogMailer只打印到日志，
+// 后两者用于开发和测试环境。
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message 纯文本邮件
+type Message struct {
+	To      string // 收件人地址
+	Subject string // 邮件主题
+	Body    string // 纯文本正文
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	// Send 发送邮件，返回后邮件已交给下游（SMTP服务器、文件或日志）
+	Send(msg *Message) error
+}
+
+// ErrInvalidHeader 收件人或主题包含换行，可能是邮件头注入
+var ErrInvalidHeader = errors.New("mail: header contains line break")
+
+// SMTPMailer 通过SMTP服务器发送邮件
+// 服务器支持STARTTLS时自动启用；本地的SMTP测试服务（如MailHog）可以不设置用户名
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer 创建SMTP邮件发送器
+// 参数：
+//
+//	host - SMTP服务器地址
+//	port - SMTP服务器端口
+//	username - 认证用户名，为空时不进行认证
+//	password - 认证密码
+//	from - 发件人地址
+//
+// 返回：
+//
+//	*SMTPMailer - 邮件发送器
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: host + ":" + port, from: from}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send 通过SMTP发送邮件
+func (m *SMTPMailer) Send(msg *Message) error {
+	data, err := format(m.from, msg)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+}
+
+// FileMailer 将邮件以.eml文件写入目录，每封邮件一个文件
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer 创建文件邮件发送器，目录不存在时自动创建
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Send 将邮件写入文件
+func (m *FileMailer) Send(msg *Message) error {
+	data, err := format(m.from, msg)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := time.Now().Format("20060102-150405.000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
+
+// LogMailer 只将邮件内容打印到日志，不实际发送
+type LogMailer struct{}
+
+// Send 打印邮件内容
+func (LogMailer) Send(msg *Message) error {
+	log.Printf("邮件 to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// format 生成RFC 5322格式的邮件内容，主题按RFC 2047编码，正文使用quoted-printable
+func format(from string, msg *Message) ([]byte, error) {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, ErrInvalidHeader
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(strings.ReplaceAll(msg.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
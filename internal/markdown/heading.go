@@ -0,0 +1,103 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// headingIDs 标题id生成器
+// goldmark默认只保留ASCII字母和数字，中文标题都会变成heading；
+// 这里保留所有语言的字母和数字，空白和连字符转为"-"，重复的id追加序号
+type headingIDs struct {
+	used map[string]bool
+}
+
+// newHeadingIDs 为一篇文档创建标题id生成器
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+// Generate 根据标题文本生成文档内唯一的id
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r) || r == '-':
+			dash = true
+		}
+	}
+	id := b.String()
+	if id == "" {
+		id = "heading"
+		if kind != ast.KindHeading {
+			id = "id"
+		}
+	}
+	unique := id
+	for i := 1; s.used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	s.used[unique] = true
+	return []byte(unique)
+}
+
+// Put 记录文档中已经使用的id
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
+
+// headingAnchors 在标题末尾加上指向自身的锚点链接
+// 标题只输出id属性，忽略{.class style=...}等其他属性
+type headingAnchors struct{}
+
+// Extend 注册标题渲染器，优先级高于默认的HTML渲染器
+func (headingAnchors) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(headingAnchors{}, 100)))
+}
+
+// RegisterFuncs 注册标题节点的渲染函数
+func (headingAnchors) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindHeading, renderHeading)
+}
+
+// renderHeading 渲染带锚点链接的标题
+func renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Heading)
+	var id []byte
+	if value, ok := n.AttributeString("id"); ok {
+		id, _ = value.([]byte)
+	}
+	if entering {
+		_, _ = w.WriteString("<h")
+		_ = w.WriteByte("0123456"[n.Level])
+		if id != nil {
+			_, _ = w.WriteString(` id="`)
+			_, _ = w.Write(util.EscapeHTML(id))
+			_ = w.WriteByte('"')
+		}
+		_ = w.WriteByte('>')
+		return ast.WalkContinue, nil
+	}
+	if id != nil {
+		_, _ = w.WriteString(`<a class="heading-anchor" href="#`)
+		_, _ = w.Write(util.EscapeHTML(util.URLEscape(id, false)))
+		_, _ = w.WriteString(`" aria-hidden="true">#</a>`)
+	}
+	_, _ = w.WriteString("</h")
+	_ = w.WriteByte("0123456"[n.Level])
+	_, _ = w.WriteString(">\n")
+	return ast.WalkContinue, nil
+}
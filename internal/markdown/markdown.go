@@ -0,0 +1,305 @@
+// Package markdown 将文章的Markdown内容渲染为HTML、目录和纯文本
+// 支持CommonMark及GFM扩展（表格、删除线、任务列表、自动链接）和脚注。
+// 原始HTML按原样输出，渲染结果必须经过sanitize包的文章策略过滤后才能展示。
+// 围栏代码块在服务端高亮为基于CSS类的HTML，配色由StyleSheet生成的样式表决定；
+// 标题带有锚点链接，可以用{#id}指定固定的锚点。
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Version 渲染结果的版本，渲染规则或HTML过滤策略变化时递增，使已缓存的渲染结果失效
+// 代码高亮只输出CSS类，渲染结果与配色主题无关，更换主题不需要递增版本
+const Version = 3
+
+// DefaultStyle 默认的代码高亮配色主题
+const DefaultStyle = "github"
+
+// plain 只用于解析的Markdown转换器，生成纯文本时不需要代码高亮
+var plain = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithHeadingAttribute()),
+)
+
+// Options 渲染选项
+type Options struct {
+	Style string // 代码高亮的配色主题，如github、monokai，为空时使用DefaultStyle
+}
+
+// Renderer Markdown渲染器，可以并发使用
+type Renderer struct {
+	md  goldmark.Markdown
+	css string
+}
+
+// New 创建渲染器
+// 参数：
+//
+//	opts - 渲染选项
+//
+// 返回：
+//
+//	*Renderer - 渲染器实例
+//	error - 配色主题不存在时返回错误
+func New(opts Options) (*Renderer, error) {
+	name := opts.Style
+	if name == "" {
+		name = DefaultStyle
+	}
+	style, ok := styles.Registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的代码高亮主题: %s", name)
+	}
+
+	// 行号和高亮行由代码块的属性控制，例如```go {linenos=table,hl_lines=[2,"4-6"],linenostart=10}
+	formatOptions := []chromahtml.Option{chromahtml.WithClasses(true)}
+	var css bytes.Buffer
+	if err := chromahtml.New(formatOptions...).WriteCSS(&css, style); err != nil {
+		return nil, err
+	}
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			highlighting.NewHighlighting(
+				highlighting.WithCustomStyle(style),
+				highlighting.WithFormatOptions(formatOptions...),
+			),
+			headingAnchors{},
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithHeadingAttribute()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	return &Renderer{md: md, css: css.String()}, nil
+}
+
+// Default 返回使用默认配色主题的渲染器
+func Default() *Renderer {
+	r, err := New(Options{})
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// StyleSheet 返回代码高亮使用的CSS样式表
+func (r *Renderer) StyleSheet() string {
+	return r.css
+}
+
+// Heading 目录中的一个标题
+type Heading struct {
+	Level int    `json:"level"` // 标题级别，1到6
+	ID    string `json:"id"`    // 标题元素的id，可以作为页面锚点
+	Text  string `json:"text"`  // 标题的纯文本
+}
+
+// Result 渲染结果
+type Result struct {
+	HTML string     // 渲染后的HTML
+	TOC  []*Heading // 按出现顺序排列的全部标题
+}
+
+// Render 把Markdown渲染为HTML，并从标题生成目录
+// 参数：
+//
+//	source - Markdown文本
+//
+// 返回：
+//
+//	*Result - 渲染结果
+//	error - 渲染失败时返回错误
+func (r *Renderer) Render(source string) (*Result, error) {
+	src := []byte(source)
+	doc := parse(r.md, src)
+
+	toc := []*Heading{}
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		toc = append(toc, &Heading{
+			Level: heading.Level,
+			ID:    string(idBytes),
+			Text:  strings.TrimSpace(nodeText(heading, src)),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+	return &Result{HTML: buf.String(), TOC: toc}, nil
+}
+
+// PlainText 去掉Markdown标记，返回纯文本
+// 块之间用换行分隔，图片保留替代文本，原始HTML被丢弃
+func PlainText(source string) string {
+	src := []byte(source)
+	var buf strings.Builder
+	newline := func() {
+		if buf.Len() > 0 && !strings.HasSuffix(buf.String(), "\n") {
+			buf.WriteByte('\n')
+		}
+	}
+	_ = ast.Walk(parse(plain, src), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			switch n.(type) {
+			case *extast.TableCell:
+				buf.WriteByte('\t')
+			case *ast.Document:
+			default:
+				if n.Type() == ast.TypeBlock {
+					newline()
+				}
+			}
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Text:
+			buf.Write(node.Segment.Value(src))
+			if node.HardLineBreak() || node.SoftLineBreak() {
+				buf.WriteByte('\n')
+			}
+		case *ast.String:
+			buf.Write(node.Value)
+		case *ast.AutoLink:
+			buf.Write(node.Label(src))
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			newline()
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				line := lines.At(i)
+				buf.Write(line.Value(src))
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *extast.FootnoteBacklink:
+			return ast.WalkSkipChildren, nil
+		case *extast.FootnoteLink:
+			buf.WriteString("[" + strconv.Itoa(node.Index) + "]")
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(strings.ReplaceAll(buf.String(), "\t\n", "\n"))
+}
+
+// parse 解析Markdown，标题id使用支持中文的生成规则
+func parse(md goldmark.Markdown, src []byte) ast.Node {
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	return md.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+}
+
+// nodeText 返回节点下所有文本拼接成的纯文本
+func nodeText(n ast.Node, src []byte) string {
+	var buf strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := child.(type) {
+		case *ast.Text:
+			buf.Write(node.Segment.Value(src))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(node.Value)
+		case *ast.AutoLink:
+			buf.Write(node.Label(src))
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+// RewriteRawHTML 用rewrite替换Markdown中每一段原始HTML，其余内容保持不变
+// 代码块和行内代码中的HTML不是原始HTML，不会被替换
+// 参数：
+//
+//	source - Markdown文本
+//	rewrite - 接收一段原始HTML，返回替换后的内容
+//
+// 返回：
+//
+//	string - 替换后的Markdown文本
+func RewriteRawHTML(source string, rewrite func(string) string) string {
+	src := []byte(source)
+	type span struct{ start, stop int }
+	var spans []span
+	_ = ast.Walk(parse(plain, src), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.HTMLBlock:
+			lines := node.Lines()
+			if lines.Len() == 0 {
+				return ast.WalkSkipChildren, nil
+			}
+			if node.HasClosure() {
+				lines.Append(node.ClosureLine)
+			}
+			// 引用和列表中的HTML块每行都带有容器的前缀，只能逐行替换
+			if _, top := node.Parent().(*ast.Document); !top {
+				for i := 0; i < lines.Len(); i++ {
+					spans = append(spans, span{lines.At(i).Start, lines.At(i).Stop})
+				}
+				return ast.WalkSkipChildren, nil
+			}
+			// 保留结尾的换行，避免替换结果吞掉换行后与下一个块连在一起
+			s := span{lines.At(0).Start, lines.At(lines.Len() - 1).Stop}
+			for s.stop > s.start && (src[s.stop-1] == '\n' || src[s.stop-1] == '\r') {
+				s.stop--
+			}
+			spans = append(spans, s)
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			if node.Segments.Len() > 0 {
+				spans = append(spans, span{node.Segments.At(0).Start, node.Segments.At(node.Segments.Len() - 1).Stop})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(spans) == 0 {
+		return source
+	}
+	var buf strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		buf.Write(src[last:s.start])
+		buf.WriteString(rewrite(string(src[s.start:s.stop])))
+		last = s.stop
+	}
+	buf.Write(src[last:])
+	return buf.String()
+}
@@ -0,0 +1,325 @@
+// Package migrate 实现基于版本号的数据库结构迁移
+// 迁移文件来自migrations包，已应用的版本记录在schema_migrations表中。
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gofile/migrations"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrSchemaBehind 数据库结构版本落后于代码期望的版本
+var ErrSchemaBehind = errors.New("数据库结构版本落后")
+
+// Migration 单个版本的迁移
+type Migration struct {
+	Version int    // 版本号，从1开始连续递增
+	Name    string // 迁移名称，取自文件名
+	Up      string // 升级SQL
+	Down    string // 回滚SQL
+}
+
+// Status 单个迁移的应用状态
+type Status struct {
+	Migration
+	Applied   bool      // 是否已应用
+	AppliedAt time.Time // 应用时间，未应用时为零值
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+
+	// DryRun 为true时只将要执行的SQL输出到Out，不修改数据库
+	DryRun bool
+	// Out 迁移过程和DryRun SQL的输出目标，为nil时不输出
+	Out io.Writer
+}
+
+// postUp 执行升级SQL后在同一事务中运行的Go代码，按迁移名称登记
+// 用于SQL难以表达、需要与程序使用同一套规则的数据转换
+var postUp = map[string]func(m *Migrator, tx *sql.Tx) error{
+	"normalize_taxonomy_slugs": normalizeTaxonomySlugs,
+}
+
+// fileNamePattern 迁移文件名格式：0001_create_users.up.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// New 创建迁移执行器，加载与方言对应的嵌入迁移文件
+// 参数：
+//
+//	db - 数据库连接
+//	dialect - GORM方言名称：postgres或sqlite
+//
+// 返回：
+//
+//	*Migrator - 迁移执行器
+//	error - 方言不支持或迁移文件不完整时返回错误
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+	list, err := Load(migrations.FS, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: list}, nil
+}
+
+// Load 从文件系统的指定目录加载迁移文件，并校验版本号连续且up/down成对
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("不支持的数据库方言 %q: %w", dir, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := fileNamePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		raw, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("迁移版本 %d 的名称不一致: %s 与 %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(raw)
+		} else {
+			mig.Down = string(raw)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, mig := range list {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("迁移版本号不连续: 期望 %d，实际 %d", i+1, mig.Version)
+		}
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("迁移 %04d_%s 缺少up或down文件", mig.Version, mig.Name)
+		}
+	}
+	return list, nil
+}
+
+// Latest 返回代码中最新的迁移版本号
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// ensureTable 创建schema_migrations表（如不存在）
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`)
+	return err
+}
+
+// applied 读取已应用的迁移版本及其应用时间
+// DryRun模式下不创建schema_migrations表，表不存在时视为没有已应用的版本
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	result := make(map[int]time.Time)
+	if !m.DryRun {
+		if err := m.ensureTable(); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := m.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		if m.DryRun {
+			return result, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		result[version] = appliedAt
+	}
+	return result, rows.Err()
+}
+
+// Current 返回数据库当前的结构版本，即已连续应用的最大版本号
+func (m *Migrator) Current() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			break
+		}
+		current = mig.Version
+	}
+	return current, nil
+}
+
+// Status 返回所有迁移的应用状态
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		appliedAt, ok := applied[mig.Version]
+		list = append(list, Status{Migration: mig, Applied: ok, AppliedAt: appliedAt})
+	}
+	return list, nil
+}
+
+// Up 应用所有未应用的迁移
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down 回滚最近应用的steps个迁移
+func (m *Migrator) Down(steps int) error {
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	target := current - steps
+	if target < 0 {
+		target = 0
+	}
+	return m.To(target)
+}
+
+// To 将数据库迁移到指定版本，目标版本高于当前版本时升级，低于时回滚
+func (m *Migrator) To(version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("无效的目标版本 %d，可用范围 0-%d", version, m.Latest())
+	}
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+
+	for v := current + 1; v <= version; v++ {
+		if err := m.apply(m.migrations[v-1], true); err != nil {
+			return err
+		}
+	}
+	for v := current; v > version; v-- {
+		if err := m.apply(m.migrations[v-1], false); err != nil {
+			return err
+		}
+	}
+	if current == version {
+		m.logf("数据库已处于版本 %d，无需迁移\n", version)
+	}
+	return nil
+}
+
+// apply 在事务中执行单个迁移并更新schema_migrations
+func (m *Migrator) apply(mig Migration, up bool) error {
+	direction, script := "up", mig.Up
+	record := m.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`)
+	args := []any{mig.Version, mig.Name, time.Now().UTC()}
+	if !up {
+		direction, script = "down", mig.Down
+		record = m.rebind(`DELETE FROM schema_migrations WHERE version = ?`)
+		args = args[:1]
+	}
+
+	hook := postUp[mig.Name]
+	if !up {
+		hook = nil
+	}
+
+	if m.DryRun {
+		m.logf("-- %04d_%s.%s.sql\n%s\n", mig.Version, mig.Name, direction, script)
+		if hook != nil {
+			m.logf("-- 执行SQL后运行 %s 的数据转换程序\n", mig.Name)
+		}
+		m.logf("%s; -- %v\n\n", record, args)
+		return nil
+	}
+
+	m.logf("%s %04d_%s\n", direction, mig.Version, mig.Name)
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("执行迁移 %04d_%s.%s 失败: %w", mig.Version, mig.Name, direction, err)
+	}
+	if hook != nil {
+		if err := hook(m, tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("执行迁移 %04d_%s.%s 失败: %w", mig.Version, mig.Name, direction, err)
+		}
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebind 将?占位符转换为方言对应的格式
+func (m *Migrator) rebind(query string) string {
+	if m.dialect != "postgres" {
+		return query
+	}
+	n := 0
+	return regexp.MustCompile(`\?`).ReplaceAllStringFunc(query, func(string) string {
+		n++
+		return "$" + strconv.Itoa(n)
+	})
+}
+
+// logf 向Out输出迁移信息
+func (m *Migrator) logf(format string, args ...any) {
+	if m.Out != nil {
+		fmt.Fprintf(m.Out, format, args...)
+	}
+}
+
+// CheckCurrent 检查数据库结构是否已是最新版本
+// 版本落后时返回包装了ErrSchemaBehind的错误，服务器据此拒绝启动
+// 检查过程只读，不会创建schema_migrations表
+func CheckCurrent(db *sql.DB, dialect string) error {
+	m, err := New(db, dialect)
+	if err != nil {
+		return err
+	}
+	m.DryRun = true
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	if current < m.Latest() {
+		return fmt.Errorf("%w: 当前版本 %d，期望版本 %d", ErrSchemaBehind, current, m.Latest())
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gofile/internal/slug"
+	"gofile/models"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// openTestDB 在临时目录中创建空的SQLite数据库
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	store, err := models.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	db, err := store.DB().DB()
+	if err != nil {
+		t.Fatalf("get sql db: %v", err)
+	}
+	return db
+}
+
+// newTestMigrator 创建使用SQLite迁移文件的迁移执行器
+func newTestMigrator(t *testing.T, db *sql.DB) *Migrator {
+	t.Helper()
+	m, err := New(db, "sqlite")
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	return m
+}
+
+// tables 返回数据库中除schema_migrations和SQLite内部表以外的表名
+func tables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master
+WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations' ORDER BY name`)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan table name: %v", err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// assertCurrent 检查数据库当前的结构版本
+func assertCurrent(t *testing.T, m *Migrator, want int) {
+	t.Helper()
+	current, err := m.Current()
+	if err != nil {
+		t.Fatalf("current version: %v", err)
+	}
+	if current != want {
+		t.Fatalf("current version = %d, want %d", current, want)
+	}
+}
+
+func TestUpDownAndReapply(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db)
+	if m.Latest() == 0 {
+		t.Fatal("no migrations loaded")
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	assertCurrent(t, m, m.Latest())
+	schema := tables(t, db)
+	if len(schema) == 0 {
+		t.Fatal("no tables after migrating up")
+	}
+
+	// 逐个回滚，每一步的down都必须能执行
+	for v := m.Latest(); v > 0; v-- {
+		if err := m.Down(1); err != nil {
+			t.Fatalf("down from version %d: %v", v, err)
+		}
+		assertCurrent(t, m, v-1)
+	}
+	if left := tables(t, db); len(left) != 0 {
+		t.Fatalf("tables left after rolling back everything: %v", left)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	assertCurrent(t, m, m.Latest())
+	if got := tables(t, db); strings.Join(got, ",") != strings.Join(schema, ",") {
+		t.Fatalf("tables after re-apply = %v, want %v", got, schema)
+	}
+
+	if err := m.To(3); err != nil {
+		t.Fatalf("migrate to 3: %v", err)
+	}
+	assertCurrent(t, m, 3)
+	if err := m.To(m.Latest() + 1); err == nil {
+		t.Fatal("migrating past the latest version succeeded")
+	}
+}
+
+func TestCheckCurrent(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := CheckCurrent(db, "sqlite"); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("check empty database = %v, want ErrSchemaBehind", err)
+	}
+	// 检查过程只读，不创建schema_migrations表
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'`).Scan(&count); err != nil || count != 0 {
+		t.Fatalf("schema_migrations tables after check = %d, %v, want 0", count, err)
+	}
+
+	m := newTestMigrator(t, db)
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := CheckCurrent(db, "sqlite"); err != nil {
+		t.Fatalf("check migrated database: %v", err)
+	}
+	if err := m.Down(1); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if err := CheckCurrent(db, "sqlite"); !errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("check database one version behind = %v, want ErrSchemaBehind", err)
+	}
+	if err := CheckCurrent(db, "oracle"); err == nil || errors.Is(err, ErrSchemaBehind) {
+		t.Fatalf("check unsupported dialect = %v, want load error", err)
+	}
+}
+
+func TestDryRunDoesNotModifyDatabase(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db)
+	var out strings.Builder
+	m.DryRun, m.Out = true, &out
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("dry run up: %v", err)
+	}
+	if !strings.Contains(out.String(), "0001_create_users_and_articles.up.sql") {
+		t.Fatalf("dry run output does not list the first migration:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "normalize_taxonomy_slugs 的数据转换") {
+		t.Fatalf("dry run output does not mention the slug normalization:\n%s", out.String())
+	}
+	if got := tables(t, db); len(got) != 0 {
+		t.Fatalf("dry run created tables: %v", got)
+	}
+	m.DryRun, m.Out = false, io.Discard
+	assertCurrent(t, m, 0)
+}
+
+func TestLoadValidatesFiles(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"gap", fstest.MapFS{
+			"d/0001_a.up.sql": file, "d/0001_a.down.sql": file,
+			"d/0003_c.up.sql": file, "d/0003_c.down.sql": file,
+		}, "不连续"},
+		{"missing down", fstest.MapFS{"d/0001_a.up.sql": file}, "缺少up或down"},
+		{"name mismatch", fstest.MapFS{"d/0001_a.up.sql": file, "d/0001_b.down.sql": file}, "名称不一致"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(tt.files, "d"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: load error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	list, err := Load(fstest.MapFS{
+		"d/0002_b.up.sql": file, "d/0002_b.down.sql": file,
+		"d/0001_a.up.sql": file, "d/0001_a.down.sql": file,
+		"d/README.md": file,
+	}, "d")
+	if err != nil || len(list) != 2 || list[0].Name != "a" || list[1].Version != 2 {
+		t.Fatalf("load = %+v, %v, want versions 1 and 2 in order", list, err)
+	}
+}
+
+// queryStrings 执行返回单列文本的查询
+func queryStrings(t *testing.T, db *sql.DB, query string, args ...any) []string {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("query %q: %v", query, err)
+	}
+	defer rows.Close()
+	var list []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+func TestNormalizeTaxonomySlugs(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db)
+	if err := m.To(13); err != nil {
+		t.Fatalf("migrate to 13: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, username, created_at, updated_at) VALUES (1, 'alice', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	// 0014只把名称转为小写并替换空格，标点会留在slug中；
+	// 文章1和文章2的标签和分类名称不同，规范化后的slug相同，不能合并
+	articles := []struct {
+		id             int
+		tags, category string
+	}{
+		{1, "Node.js, C++, go/gin", "Web/前端"},
+		{2, "node js，Go Gin, c", "web 前端"},
+		{3, "Vue.js, +++", "C#"},
+	}
+	for _, a := range articles {
+		if _, err := db.Exec(`INSERT INTO articles (id, title, content, slug, status, user_id, tags, category, created_at, updated_at)
+VALUES (?, 'title', 'content', ?, 'published', 1, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+			a.id, fmt.Sprintf("a%d", a.id), a.tags, a.category); err != nil {
+			t.Fatalf("insert article %d: %v", a.id, err)
+		}
+	}
+
+	// 名称只有大小写不同的标签和分类是同一个，应当合并
+	if err := m.To(16); err != nil {
+		t.Fatalf("migrate to 16: %v", err)
+	}
+	for _, query := range []string{
+		`INSERT INTO tags (id, name, slug, created_at, updated_at) VALUES (100, 'NODE.JS', 'NODE.JS', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		`INSERT INTO article_tags (article_id, tag_id) VALUES (3, 100)`,
+		`INSERT INTO categories (id, name, slug, created_at, updated_at) VALUES (100, 'WEB/前端', 'WEB/前端', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		`INSERT INTO articles (id, title, content, slug, status, user_id, category_id, created_at, updated_at)
+VALUES (4, 'title', 'content', 'a4', 'published', 1, 100, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("insert duplicate: %v", err)
+		}
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	tagID := queryStrings(t, db, `SELECT id FROM tags WHERE name = '+++'`)
+	if len(tagID) != 1 {
+		t.Fatalf("tag +++ = %v, want one tag", tagID)
+	}
+	slugs := map[string]map[string]string{
+		"tags": {
+			"c": "c", "C++": "c-2", "Go Gin": "go-gin", "go/gin": "go-gin-2",
+			"node js": "node-js", "Node.js": "node-js-2", "Vue.js": "vue-js", "+++": "tag-" + tagID[0],
+		},
+		"categories": {"web 前端": "web-前端", "Web/前端": "web-前端-2", "C#": "c"},
+	}
+	for table, want := range slugs {
+		got := map[string]string{}
+		for _, row := range queryStrings(t, db, `SELECT name || '=' || slug FROM `+table) {
+			name, s, _ := strings.Cut(row, "=")
+			got[name] = s
+			if !slug.Valid(s) {
+				t.Errorf("%s slug %q is not valid", table, s)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s slugs = %v, want %v", table, got, want)
+		}
+	}
+
+	tests := []struct {
+		article  int
+		tags     string
+		category string
+	}{
+		{1, "c-2,go-gin-2,node-js-2", "web-前端-2"},
+		{2, "c,go-gin,node-js", "web-前端"},
+		{3, "node-js-2,tag-" + tagID[0] + ",vue-js", "c"},
+		{4, "", "web-前端-2"},
+	}
+	for _, tt := range tests {
+		tags := queryStrings(t, db, `SELECT t.slug FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE at.article_id = ? ORDER BY t.slug`, tt.article)
+		if got := strings.Join(tags, ","); got != tt.tags {
+			t.Errorf("article %d tags = %s, want %s", tt.article, got, tt.tags)
+		}
+		category := queryStrings(t, db, `SELECT c.slug FROM articles a JOIN categories c ON c.id = a.category_id WHERE a.id = ?`, tt.article)
+		if len(category) != 1 || category[0] != tt.category {
+			t.Errorf("article %d category = %v, want %s", tt.article, category, tt.category)
+		}
+	}
+}
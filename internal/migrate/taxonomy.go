@@ -0,0 +1,126 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"gofile/internal/slug"
+	"strings"
+)
+
+// taxonomyTable 需要规范化slug的表
+type taxonomyTable struct {
+	name     string                                             // 表名
+	fallback string                                             // 名称无法生成slug时使用的前缀，如tag-<id>
+	merge    func(m *Migrator, tx *sql.Tx, keep, dup int) error // 把名称相同的重复行的关联转移到保留的行
+}
+
+// taxonomyTables 标签和分类的规范化规则
+var taxonomyTables = []taxonomyTable{
+	{name: "tags", fallback: "tag", merge: mergeTag},
+	{name: "categories", fallback: "category", merge: mergeCategory},
+}
+
+// normalizeTaxonomySlugs 0017迁移的数据转换：把标签和分类不合法的slug改为slug.Make(名称)
+// 与创建标签时的规则一致：合法的slug保持不变；名称相同（不区分大小写）的行合并到先登记的一行；
+// 名称不同但生成的slug已被使用时加上数字后缀；名称中没有字母和数字时使用tag-<id>或category-<id>
+func normalizeTaxonomySlugs(m *Migrator, tx *sql.Tx) error {
+	for _, table := range taxonomyTables {
+		if err := m.normalizeSlugs(tx, table); err != nil {
+			return fmt.Errorf("规范化%s的slug失败: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+// normalizeSlugs 规范化单个表的slug
+func (m *Migrator) normalizeSlugs(tx *sql.Tx, table taxonomyTable) error {
+	type row struct {
+		id         int
+		name, slug string
+	}
+	rows, err := tx.Query(`SELECT id, name, slug FROM ` + table.name + ` ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	var list []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.name, &r.slug); err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 合法的slug保持不变，先登记；不合法的slug按id顺序改写
+	owner := make(map[string]int, len(list))
+	byName := make(map[string]int, len(list))
+	for _, r := range list {
+		if slug.Valid(r.slug) {
+			owner[r.slug] = r.id
+			if _, ok := byName[strings.ToLower(r.name)]; !ok {
+				byName[strings.ToLower(r.name)] = r.id
+			}
+		}
+	}
+	for _, r := range list {
+		if slug.Valid(r.slug) {
+			continue
+		}
+		// 名称相同（不区分大小写）的是同一个标签或分类，合并到已经登记的一行
+		if keep, ok := byName[strings.ToLower(r.name)]; ok {
+			if err := table.merge(m, tx, keep, r.id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(m.rebind(`DELETE FROM `+table.name+` WHERE id = ?`), r.id); err != nil {
+				return err
+			}
+			continue
+		}
+		// 名称不同但生成的slug相同时加上数字后缀，如"C"和"C++"分别为c和c-2
+		base := slug.Make(r.name)
+		if base == "" {
+			base = fmt.Sprintf("%s-%d", table.fallback, r.id)
+		}
+		s := base
+		for n := 2; owner[s] != 0; n++ {
+			s = slug.WithSuffix(base, n)
+		}
+		if _, err := tx.Exec(m.rebind(`UPDATE `+table.name+` SET slug = ? WHERE id = ?`), s, r.id); err != nil {
+			return err
+		}
+		owner[s] = r.id
+		byName[strings.ToLower(r.name)] = r.id
+	}
+	return nil
+}
+
+// mergeTag 把重复标签的文章关联转移到保留的标签，已关联保留标签的文章不重复关联
+// 参数在SELECT列表中，PostgreSQL无法推断类型，需要显式转换
+func mergeTag(m *Migrator, tx *sql.Tx, keep, dup int) error {
+	_, err := tx.Exec(m.rebind(`INSERT INTO article_tags (article_id, tag_id)
+SELECT d.article_id, CAST(? AS BIGINT) FROM article_tags d WHERE d.tag_id = ?
+AND NOT EXISTS (SELECT 1 FROM article_tags k WHERE k.article_id = d.article_id AND k.tag_id = ?)`), keep, dup, keep)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(m.rebind(`DELETE FROM article_tags WHERE tag_id = ?`), dup)
+	return err
+}
+
+// mergeCategory 把重复分类下的文章和子分类转移到保留的分类
+func mergeCategory(m *Migrator, tx *sql.Tx, keep, dup int) error {
+	if _, err := tx.Exec(m.rebind(`UPDATE articles SET category_id = ? WHERE category_id = ?`), keep, dup); err != nil {
+		return err
+	}
+	// 保留的分类原本是重复分类的子分类时，合并后不能成为自己的父分类
+	if _, err := tx.Exec(m.rebind(`UPDATE categories SET parent_id = NULL WHERE id = ? AND parent_id = ?`), keep, dup); err != nil {
+		return err
+	}
+	_, err := tx.Exec(m.rebind(`UPDATE categories SET parent_id = ? WHERE parent_id = ?`), keep, dup)
+	return err
+}
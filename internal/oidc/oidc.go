@@ -0,0 +1,172 @@
+// Package oidc 实现OpenID Connect授权码登录的客户端
+// 每个Provider对应配置文件中的一个身份提供方，使用授权码流程加PKCE（S256）换取ID令牌，
+// 并校验ID令牌的签名、签发方、受众、有效期和nonce。
+// 身份提供方的元数据在第一次使用时通过/.well-known/openid-configuration获取，
+// 身份提供方暂时不可用不会影响服务启动。
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrDiscovery 无法获取身份提供方的元数据
+var ErrDiscovery = errors.New("oidc: provider discovery failed")
+
+// Config 身份提供方配置
+type Config struct {
+	Name         string   // 身份提供方名称，用于URL和关联记录，如"google"
+	DisplayName  string   // 登录按钮上显示的名称，为空时使用Name
+	Issuer       string   // 签发方地址，需与元数据中的issuer完全一致
+	ClientID     string   // 客户端ID
+	ClientSecret string   // 客户端密钥，公开客户端可以为空
+	RedirectURL  string   // 回调地址，需在身份提供方登记
+	Scopes       []string // 申请的scope，openid会自动加入
+}
+
+// Identity ID令牌中的用户信息
+type Identity struct {
+	Subject           string `json:"sub"`                // 身份提供方中的用户标识
+	Email             string `json:"email"`              // 邮箱
+	EmailVerified     bool   `json:"email_verified"`     // 邮箱是否已由身份提供方验证
+	PreferredUsername string `json:"preferred_username"` // 用户偏好的用户名
+	Name              string `json:"name"`               // 显示名称
+}
+
+// Provider OpenID Connect身份提供方
+type Provider struct {
+	cfg Config
+
+	mu       sync.Mutex
+	provider *gooidc.Provider
+}
+
+// NewProvider 创建身份提供方，此时不会访问网络
+// 参数：
+//
+//	cfg - 身份提供方配置，Name、Issuer、ClientID和RedirectURL不能为空
+//
+// 返回：
+//
+//	*Provider - 身份提供方
+//	error - 配置不完整时返回错误
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.Name == "" || cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc provider %q: name, issuer, client_id and redirect_url are required", cfg.Name)
+	}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+	return &Provider{cfg: cfg}, nil
+}
+
+// Name 返回身份提供方名称
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// DisplayName 返回身份提供方的显示名称
+func (p *Provider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// discover 获取并缓存身份提供方的元数据，失败时下次调用会重试
+func (p *Provider) discover(ctx context.Context) (*gooidc.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, nil
+	}
+	provider, err := gooidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	p.provider = provider
+	return provider, nil
+}
+
+// oauth2Config 生成授权码流程的OAuth 2.0配置
+func (p *Provider) oauth2Config(provider *gooidc.Provider) *oauth2.Config {
+	scopes := []string{gooidc.ScopeOpenID}
+	for _, scope := range p.cfg.Scopes {
+		if scope != gooidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址
+// 参数：
+//
+//	ctx - 请求的context
+//	state - 防止CSRF的随机值，回调时原样返回
+//	nonce - 写入ID令牌的随机值，防止ID令牌重放
+//	verifier - PKCE校验码，地址中只包含其S256摘要
+//
+// 返回：
+//
+//	string - 授权地址
+//	error - 无法获取身份提供方元数据时返回ErrDiscovery
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider).AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 使用授权码换取ID令牌并校验
+// 参数：
+//
+//	ctx - 请求的context
+//	code - 回调中的授权码
+//	verifier - 生成授权地址时使用的PKCE校验码
+//	nonce - 生成授权地址时使用的nonce
+//
+// 返回：
+//
+//	*Identity - ID令牌中的用户信息
+//	error - 授权码无效、ID令牌校验失败或nonce不匹配时返回错误
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	provider, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	idToken, err := provider.Verifier(&gooidc.Config{ClientID: p.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("oidc: id_token nonce mismatch")
+	}
+
+	var identity Identity
+	if err := idToken.Claims(&identity); err != nil {
+		return nil, fmt.Errorf("oidc: decode claims: %w", err)
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("oidc: id_token has no subject")
+	}
+	return &identity, nil
+}
@@ -0,0 +1,116 @@
+package oidc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidState 登录状态缺失、被篡改、已过期或与回调不匹配
+var ErrInvalidState = errors.New("oidc: invalid login state")
+
+// LoginState 发起登录时生成、回调时校验的一次性数据
+// 保存在带HMAC签名的cookie中，服务端不需要保存任何登录中间状态
+type LoginState struct {
+	Provider  string `json:"p"`   // 身份提供方名称
+	State     string `json:"s"`   // state参数
+	Nonce     string `json:"n"`   // ID令牌的nonce
+	Verifier  string `json:"v"`   // PKCE校验码
+	ExpiresAt int64  `json:"exp"` // 过期时间（Unix秒）
+}
+
+// NewLoginState 为身份提供方生成新的登录状态
+func NewLoginState(provider string, ttl time.Duration) (*LoginState, error) {
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	return &LoginState{
+		Provider:  provider,
+		State:     state,
+		Nonce:     nonce,
+		Verifier:  oauth2.GenerateVerifier(),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+// Seal 将登录状态编码并签名，结果可以直接作为cookie的值
+func (s *LoginState) Seal(secret []byte) (string, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(secret, encoded)), nil
+}
+
+// OpenLoginState 校验cookie中的登录状态
+// 参数：
+//
+//	secret - 签名密钥
+//	sealed - cookie的值
+//	provider - 回调地址中的身份提供方名称
+//	state - 回调地址中的state参数
+//
+// 返回：
+//
+//	*LoginState - 登录状态
+//	error - 校验失败时返回ErrInvalidState
+func OpenLoginState(secret []byte, sealed, provider, state string) (*LoginState, error) {
+	encoded, sig, ok := strings.Cut(sealed, ".")
+	if !ok {
+		return nil, ErrInvalidState
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, sign(secret, encoded)) {
+		return nil, ErrInvalidState
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	var s LoginState
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, ErrInvalidState
+	}
+	if s.Provider != provider || !hmac.Equal([]byte(s.State), []byte(state)) || time.Now().Unix() > s.ExpiresAt {
+		return nil, ErrInvalidState
+	}
+	return &s, nil
+}
+
+// GenerateStateSecret 生成随机的登录状态签名密钥，用于未配置密钥的开发环境
+func GenerateStateSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// sign 计算HMAC-SHA256签名
+func sign(secret []byte, data string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("oidc-state\x00" + data))
+	return mac.Sum(nil)
+}
+
+// randomString 生成32字节的随机字符串
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
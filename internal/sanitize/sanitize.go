@@ -0,0 +1,103 @@
+// Package sanitize 基于白名单策略过滤用户提交的HTML，防止存储型XSS
+// 不同来源的内容使用不同严格程度的策略：
+//   - 文章：允许常见排版标签和Markdown渲染器生成的代码高亮、锚点、脚注和任务列表
+//   - 评论：只允许少量行内标签和http(s)链接，链接一律带nofollow
+//   - 标题和用户资料：不允许任何标签，只保留文本
+//
+// 所有策略只允许http、https和mailto链接（评论不允许mailto），
+// 指向外部站点的链接会加上target="_blank"和rel="noopener"。
+// 过滤结果是可以直接插入页面的HTML片段，再次过滤不会改变结果。
+package sanitize
+
+import (
+	"gofile/internal/markdown"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// classNames 代码高亮、锚点和脚注使用的CSS类名
+	classNames = regexp.MustCompile(`^[A-Za-z0-9_\- ]+$`)
+	// elementID 标题和脚注的id，标题id可能包含中文
+	elementID = regexp.MustCompile(`^[\p{L}\p{N}_:\-]+$`)
+	// ariaRoles 脚注使用的ARIA角色
+	ariaRoles = regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)
+)
+
+var (
+	articlePolicy = newArticlePolicy()
+	commentPolicy = newCommentPolicy()
+	textPolicy    = bluemonday.StrictPolicy()
+)
+
+// textEscaper 纯文本中需要转义的字符，引号只在属性中有特殊含义，保留原样便于展示
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// newArticlePolicy 创建文章策略
+// 在UGC策略的基础上允许Markdown渲染器输出的class、id等属性
+func newArticlePolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.AllowRelativeURLs(true)
+	// 文章由作者编写，站内外链接都不需要nofollow
+	p.RequireNoFollowOnLinks(false)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	p.AllowElements("kbd", "mark")
+	// 表格的对齐方式
+	p.AllowStyles("text-align").MatchingEnum("left", "right", "center").OnElements("th", "td")
+	p.AllowAttrs("class").Matching(classNames).Globally()
+	p.AllowAttrs("id").Matching(elementID).OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("role").Matching(ariaRoles).OnElements("a", "div")
+	p.AllowAttrs("aria-hidden").Matching(regexp.MustCompile(`^true$`)).OnElements("a")
+	p.AllowAttrs("tabindex").Matching(regexp.MustCompile(`^0$`)).OnElements("pre")
+	// 任务列表的复选框
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	return p
+}
+
+// newCommentPolicy 创建评论策略
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "b", "strong", "i", "em", "del", "code", "pre", "blockquote")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Article 按文章策略过滤HTML，用于Markdown渲染后的文章内容
+func Article(s string) string {
+	return articlePolicy.Sanitize(s)
+}
+
+// Markdown 按文章策略过滤Markdown中的原始HTML，代码块和其余Markdown内容保持不变
+// 原始HTML是逐段过滤的，去掉一段后前后的文本可能拼成新的标签（如"<scr<script>ipt>"），
+// 因此重复过滤直到结果不再变化；每次变化都会去掉一些字符，或只给链接加一次属性，一定会结束
+func Markdown(s string) string {
+	for {
+		next := markdown.RewriteRawHTML(s, Article)
+		if next == s {
+			return s
+		}
+		s = next
+	}
+}
+
+// Comment 按评论策略过滤评论内容
+func Comment(s string) string {
+	return strings.TrimSpace(commentPolicy.Sanitize(s))
+}
+
+// Text 去掉所有标签，用于文章标题、昵称等只能是纯文本的字段
+// 结果中的&、<和>被转义，可以直接插入页面
+func Text(s string) string {
+	return strings.TrimSpace(textEscaper.Replace(html.UnescapeString(textPolicy.Sanitize(s))))
+}
@@ -0,0 +1,99 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+// xssPayloads 常见的XSS攻击向量
+var xssPayloads = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=//example.com/x.js></SCRIPT>`,
+	`<img src=x onerror=alert(1)>`,
+	`<svg onload=alert(1)></svg>`,
+	`<a href="JaVaScRiPt:alert(1)">a</a>`,
+	`<a href="  javascript:alert(1)">b</a>`,
+	`<a href="java&#x09;script:alert(1)">c</a>`,
+	`<iframe src="javascript:alert(1)"></iframe>`,
+	`<img src="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">`,
+	`<div style="background:url(javascript:alert(1))" onclick="alert(1)">d</div>`,
+	`<object data="x.swf"></object><embed src="x.swf">`,
+	`<form action="javascript:alert(1)"><input type="submit"></form>`,
+	`<body onload=alert(1)>`,
+	`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+	`<scr<script>ipt>alert(1)</scr</script>ipt>`,
+	`<p title="</p><img src=x onerror=alert(1)>">e</p>`,
+}
+
+// htmlFuncs 输出HTML片段的过滤函数
+var htmlFuncs = []struct {
+	name string
+	fn   func(string) string
+}{
+	{"Article", Article},
+	{"Markdown", Markdown},
+	{"Comment", Comment},
+}
+
+// assertNoXSS 检查HTML中不包含可执行脚本的标签、属性或URL
+func assertNoXSS(t *testing.T, label, content string) {
+	t.Helper()
+	lower := strings.ToLower(content)
+	for _, bad := range []string{"<script", "onerror", "onload", "onclick", "javascript:", "<iframe", "<svg", "data:text/html", "<object", "<embed", "<form", "<style", "style=", "<body"} {
+		if strings.Contains(lower, bad) {
+			t.Errorf("%s contains %q: %s", label, bad, content)
+		}
+	}
+}
+
+func TestSanitizeXSSPayloads(t *testing.T) {
+	for _, f := range htmlFuncs {
+		for _, payload := range xssPayloads {
+			once := f.fn(payload)
+			assertNoXSS(t, f.name+"("+payload+")", once)
+			// 再次过滤不改变结果
+			if twice := f.fn(once); twice != once {
+				t.Errorf("%s(%q) not idempotent: %q -> %q", f.name, payload, once, twice)
+			}
+		}
+	}
+
+	// 纯文本不包含任何标签，<和>都被转义
+	for _, payload := range xssPayloads {
+		once := Text(payload)
+		if strings.ContainsAny(once, "<>") {
+			t.Errorf("Text(%q) = %q, contains a tag", payload, once)
+		}
+		if twice := Text(once); twice != once {
+			t.Errorf("Text(%q) not idempotent: %q -> %q", payload, once, twice)
+		}
+	}
+}
+
+func TestSanitizeKeepsSafeContent(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"Article", Article, `<p><kbd>Ctrl</kbd> <a href="/about">站内</a></p>`, `<p><kbd>Ctrl</kbd> <a href="/about">站内</a></p>`},
+		{"Article", Article, `<a href="https://example.com">站外</a>`, `<a href="https://example.com" target="_blank" rel="noopener">站外</a>`},
+		{"Article", Article, `<a href="mailto:a@example.com">邮件</a>`, `<a href="mailto:a@example.com">邮件</a>`},
+		{"Markdown", Markdown, "```html\n<script>alert(1)</script>\n```\n", "```html\n<script>alert(1)</script>\n```\n"},
+		{"Markdown", Markdown, "**粗体** <kbd onclick=\"alert(1)\">Ctrl</kbd>\n", "**粗体** <kbd>Ctrl</kbd>\n"},
+		{"Comment", Comment, ` <p><b>好</b> <a href="https://example.com">链接</a></p> `, `<p><b>好</b> <a href="https://example.com" rel="nofollow noopener" target="_blank">链接</a></p>`},
+		{"Comment", Comment, `<a href="mailto:a@example.com">邮件</a>`, `邮件`},
+		{"Text", Text, ` <b>Tom</b> & Jerry: a < b `, `Tom &amp; Jerry: a &lt; b`},
+		{"Text", Text, `"引号" 'quote'`, `"引号" 'quote'`},
+	}
+	for _, tt := range tests {
+		got := tt.fn(tt.in)
+		if got != tt.want {
+			t.Errorf("%s(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+		if again := tt.fn(got); again != got {
+			t.Errorf("%s(%q) not idempotent: %q -> %q", tt.name, tt.in, got, again)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+// ellipsis 摘要被截断处的省略号
+const ellipsis = "…"
+
+// Highlight 用<mark>标记文本中与搜索词匹配的部分，结果已转义HTML，可以直接插入页面
+// 连续的空白合并为一个空格；maxLen大于0且文本超过maxLen个字符时，
+// 截取从第一个匹配之前不远处开始的摘要，截断处加上省略号
+// 参数：
+//
+//	text - 纯文本
+//	terms - 由Terms切分出的词
+//	maxLen - 摘要的最大长度（字符数），小于等于0表示不截取
+//
+// 返回：
+//
+//	string - 高亮后的HTML片段
+func Highlight(text string, terms []string, maxLen int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	marks := matches(text, terms)
+
+	start, end := 0, len(runes)
+	if maxLen > 0 && len(runes) > maxLen {
+		if len(marks) > 0 {
+			start = max(0, marks[0].Start-maxLen/4)
+		}
+		end = min(len(runes), start+maxLen)
+		start = max(0, end-maxLen)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+	pos := start
+	for _, m := range marks {
+		if m.End <= pos || m.Start >= end {
+			continue
+		}
+		m.Start, m.End = max(m.Start, pos), min(m.End, end)
+		b.WriteString(html.EscapeString(string(runes[pos:m.Start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[m.Start:m.End])))
+		b.WriteString("</mark>")
+		pos = m.End
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString(ellipsis)
+	}
+	return b.String()
+}
+
+// matches 返回文本中与搜索词匹配的位置，重叠或相邻的位置合并为一个，按起始位置排序
+func matches(text string, terms []string) []Token {
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+	var marks []Token
+	for _, token := range tokenize(text, true) {
+		if !wanted[token.Term] {
+			continue
+		}
+		if n := len(marks); n > 0 && token.Start <= marks[n-1].End {
+			marks[n-1].End = max(marks[n-1].End, token.End)
+			continue
+		}
+		marks = append(marks, token)
+	}
+	return marks
+}
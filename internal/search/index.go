@@ -0,0 +1,135 @@
+package search
+
+import (
+	"math"
+	"sync"
+)
+
+// TitleWeight 标题中的词相对正文的权重，计算词频和文档长度时标题中的每个词按此倍数计算
+const TitleWeight = 3
+
+// BM25参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Index 内存中的倒排索引，按BM25计算相关度，可以并发使用
+type Index struct {
+	mu          sync.RWMutex
+	postings    map[string]map[int]int // 词 -> 文档ID -> 加权词频
+	docs        map[int]document       // 文档ID -> 文档信息
+	totalLength int
+}
+
+// document 索引中的文档
+type document struct {
+	length int      // 加权长度
+	terms  []string // 文档包含的词，移除文档时使用
+}
+
+// NewIndex 创建空的倒排索引
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[int]int),
+		docs:     make(map[int]document),
+	}
+}
+
+// Add 把文档加入索引，文档已存在时替换原有内容
+// 参数：
+//
+//	id - 文档ID
+//	title - 标题（纯文本）
+//	content - 正文（纯文本）
+func (ix *Index) Add(id int, title, content string) {
+	freqs := make(map[string]int)
+	for _, term := range IndexTerms(title) {
+		freqs[term] += TitleWeight
+	}
+	for _, term := range IndexTerms(content) {
+		freqs[term]++
+	}
+	doc := document{terms: make([]string, 0, len(freqs))}
+	for term, freq := range freqs {
+		doc.length += freq
+		doc.terms = append(doc.terms, term)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+	for term, freq := range freqs {
+		docs, ok := ix.postings[term]
+		if !ok {
+			docs = make(map[int]int)
+			ix.postings[term] = docs
+		}
+		docs[id] = freq
+	}
+	ix.docs[id] = doc
+	ix.totalLength += doc.length
+}
+
+// Remove 从索引中移除文档
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// remove 从索引中移除文档，调用方需持有写锁
+func (ix *Index) remove(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range doc.terms {
+		docs := ix.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, id)
+	ix.totalLength -= doc.length
+}
+
+// Search 查找包含所有词的文档
+// 参数：
+//
+//	terms - 由Terms切分出的词
+//
+// 返回：
+//
+//	map[int]float64 - 匹配的文档ID及其BM25相关度，terms为空时返回空结果
+func (ix *Index) Search(terms []string) map[int]float64 {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores := make(map[int]float64)
+	if len(terms) == 0 || len(ix.docs) == 0 {
+		return scores
+	}
+	n := float64(len(ix.docs))
+	avgLength := float64(ix.totalLength) / n
+	for i, term := range terms {
+		docs := ix.postings[term]
+		if len(docs) == 0 {
+			return map[int]float64{}
+		}
+		idf := math.Log(1 + (n-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+		next := make(map[int]float64, len(docs))
+		for id, freq := range docs {
+			prev, ok := scores[id]
+			if i > 0 && !ok {
+				continue
+			}
+			tf := float64(freq)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(ix.docs[id].length)/avgLength)
+			next[id] = prev + idf*tf*(bm25K1+1)/(tf+norm)
+		}
+		scores = next
+	}
+	return scores
+}
@@ -0,0 +1,119 @@
+// Package search 实现文章全文搜索使用的分词、倒排索引和高亮摘要，以及搜索建议使用的前缀匹配和拼写纠错词表
+// 拉丁字母和数字按单词切分并转为小写；中日韩文字没有空格分隔，按相邻两字切分（bigram），
+// 建立索引时同时保留单字，使只有一个汉字的搜索词也能匹配。
+// PostgreSQL把同样的分词结果写入tsvector列，内存和SQLite存储使用本包的Index，
+// 两者的匹配规则一致：文章必须包含搜索词切分出的所有词。
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxWordLen 单词的最大长度（字符数），更长的部分被截断
+const maxWordLen = 64
+
+// Token 文本中的一个词
+type Token struct {
+	Term  string // 小写的词
+	Start int    // 在文本中的起始位置（字符数）
+	End   int    // 在文本中的结束位置（字符数，不包含）
+}
+
+// tokenize 切分文本
+// index为true时中日韩文字额外生成单字，用于建立索引和高亮；
+// 为false时只有单独一个字的中日韩文字生成单字，用于解析搜索词
+func tokenize(text string, index bool) []Token {
+	var tokens []Token
+	var word []rune
+	wordStart := 0
+	var cjk []rune
+	cjkStart := 0
+	flushWord := func() {
+		if len(word) > 0 {
+			end := wordStart + len(word)
+			if len(word) > maxWordLen {
+				word = word[:maxWordLen]
+			}
+			tokens = append(tokens, Token{Term: string(word), Start: wordStart, End: end})
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			if index || len(cjk) == 1 {
+				tokens = append(tokens, Token{Term: string(cjk[i]), Start: cjkStart + i, End: cjkStart + i + 1})
+			}
+			if i+1 < len(cjk) {
+				tokens = append(tokens, Token{Term: string(cjk[i : i+2]), Start: cjkStart + i, End: cjkStart + i + 2})
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	pos := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		switch {
+		case isCJK(r):
+			flushWord()
+			if len(cjk) == 0 {
+				cjkStart = pos
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			if len(word) == 0 {
+				wordStart = pos
+			}
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+		pos++
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// isCJK 判断字符是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Terms 把搜索词切分为需要匹配的词，结果已去重并保持原有顺序
+// 参数：
+//
+//	query - 用户输入的搜索词
+//
+// 返回：
+//
+//	[]string - 切分出的词，搜索词中没有字母和数字时为空
+func Terms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range tokenize(query, false) {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+// IndexTerms 把文本切分为建立索引使用的词，保留重复的词用于计算词频
+func IndexTerms(text string) []string {
+	tokens := tokenize(text, true)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// IndexText 返回以空格连接的索引词，用于生成PostgreSQL的tsvector
+func IndexText(text string) string {
+	return strings.Join(IndexTerms(text), " ")
+}
@@ -0,0 +1,261 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// correctMinLen 参与拼写纠错的单词的最短长度（字符数），更短的单词不纠正
+const correctMinLen = 3
+
+// maxEdits 返回长度为n的单词允许的最大编辑距离：5个字符以内为1，更长为2
+func maxEdits(n int) int {
+	if n <= 5 {
+		return 1
+	}
+	return 2
+}
+
+// Vocabulary 由文章中的单词组成的词表，用于拼写纠错
+// 只收录拉丁字母等以空格分隔的单词，中日韩文字和纯数字不参与纠错。
+// 单词按字典序排列用于前缀查找，并组织为BK树按编辑距离查找相近的单词。
+// 词表建立后只读，可以并发使用
+type Vocabulary struct {
+	words []string       // 按字典序排列的单词
+	freq  map[string]int // 单词出现的次数
+	tree  *bkNode        // 按编辑距离组织的BK树
+}
+
+// bkNode BK树的节点，子节点按与本节点单词的编辑距离索引
+type bkNode struct {
+	word     string
+	children map[int]*bkNode
+}
+
+// NewVocabulary 由文本建立词表
+// 参数：
+//
+//	texts - 文章的标题、正文等纯文本
+//
+// 返回：
+//
+//	*Vocabulary - 词表
+func NewVocabulary(texts ...string) *Vocabulary {
+	v := &Vocabulary{freq: make(map[string]int)}
+	for _, text := range texts {
+		for _, token := range tokenize(text, false) {
+			if isWord(token.Term) {
+				v.freq[token.Term]++
+			}
+		}
+	}
+	v.words = make([]string, 0, len(v.freq))
+	for word := range v.freq {
+		v.words = append(v.words, word)
+	}
+	sort.Strings(v.words)
+	for _, word := range v.words {
+		v.insert(word)
+	}
+	return v
+}
+
+// isWord 判断词是否参与纠错：不是中日韩文字，且至少包含一个字母
+func isWord(term string) bool {
+	for _, r := range term {
+		if isCJK(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// insert 把单词插入BK树
+func (v *Vocabulary) insert(word string) {
+	if v.tree == nil {
+		v.tree = &bkNode{word: word}
+		return
+	}
+	node := v.tree
+	for {
+		d := editDistance(word, node.word)
+		child, ok := node.children[d]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[d] = &bkNode{word: word}
+			return
+		}
+		node = child
+	}
+}
+
+// Len 返回词表中的单词数量
+func (v *Vocabulary) Len() int {
+	return len(v.words)
+}
+
+// Known 判断单词在词表中，或者是词表中某个单词的前缀（用户可能还没有输入完）
+func (v *Vocabulary) Known(term string) bool {
+	i := sort.SearchStrings(v.words, term)
+	return i < len(v.words) && strings.HasPrefix(v.words[i], term)
+}
+
+// Corrections 查找与单词相近的单词
+// 参数：
+//
+//	term - 小写的单词
+//	limit - 返回的最大数量
+//
+// 返回：
+//
+//	[]string - 编辑距离在允许范围内的单词，按编辑距离升序、出现次数降序排列
+func (v *Vocabulary) Corrections(term string, limit int) []string {
+	if v.tree == nil || len([]rune(term)) < correctMinLen {
+		return nil
+	}
+	type candidate struct {
+		word     string
+		distance int
+	}
+	maxDistance := maxEdits(len([]rune(term)))
+	var candidates []candidate
+	stack := []*bkNode{v.tree}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := editDistance(term, node.word)
+		if d > 0 && d <= maxDistance {
+			candidates = append(candidates, candidate{node.word, d})
+		}
+		// 三角不等式：只有与本节点距离在[d-max, d+max]内的子树可能包含结果
+		for k := d - maxDistance; k <= d+maxDistance; k++ {
+			if child, ok := node.children[k]; ok {
+				stack = append(stack, child)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if v.freq[a.word] != v.freq[b.word] {
+			return v.freq[a.word] > v.freq[b.word]
+		}
+		return a.word < b.word
+	})
+	words := make([]string, 0, min(limit, len(candidates)))
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		words = append(words, c.word)
+	}
+	return words
+}
+
+// Suggest 对搜索词做拼写纠错，把不在词表中的单词替换为最接近的单词，其余文字保持不变
+// 第一个被纠正的单词依次使用各个候选生成多个建议，其余被纠正的单词使用最接近的候选
+// 参数：
+//
+//	query - 用户输入的搜索词
+//	limit - 返回的最大数量
+//
+// 返回：
+//
+//	[]string - 纠正后的搜索词（小写），没有需要纠正的单词时返回nil
+func (v *Vocabulary) Suggest(query string, limit int) []string {
+	runes := lowerRunes(query)
+	type fix struct {
+		token      Token
+		candidates []string
+	}
+	var fixes []fix
+	for _, token := range tokenize(query, false) {
+		if !isWord(token.Term) || v.Known(token.Term) {
+			continue
+		}
+		if candidates := v.Corrections(token.Term, limit); len(candidates) > 0 {
+			fixes = append(fixes, fix{token, candidates})
+		}
+	}
+	if len(fixes) == 0 {
+		return nil
+	}
+
+	suggestions := make([]string, 0, len(fixes[0].candidates))
+	for _, first := range fixes[0].candidates {
+		var b strings.Builder
+		pos := 0
+		for i, f := range fixes {
+			word := f.candidates[0]
+			if i == 0 {
+				word = first
+			}
+			b.WriteString(string(runes[pos:f.token.Start]))
+			b.WriteString(word)
+			pos = f.token.End
+		}
+		b.WriteString(string(runes[pos:]))
+		suggestions = append(suggestions, b.String())
+	}
+	return suggestions
+}
+
+// editDistance 计算两个单词之间的Levenshtein编辑距离（按字符计算）
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// MatchPrefix 判断文本中是否有词以prefix开头，不区分大小写
+// 中日韩文字的每个字都可以作为开头，因此相当于包含prefix；其他文字必须从单词开头匹配
+// 参数：
+//
+//	text - 标题、名称等纯文本
+//	prefix - 用户已经输入的部分，首尾的空白被忽略
+//
+// 返回：
+//
+//	bool - 是否匹配，prefix为空时返回false
+func MatchPrefix(text, prefix string) bool {
+	p := lowerRunes(strings.TrimSpace(prefix))
+	if len(p) == 0 {
+		return false
+	}
+	runes := lowerRunes(text)
+	for _, token := range tokenize(text, true) {
+		if token.Start+len(p) <= len(runes) && string(runes[token.Start:token.Start+len(p)]) == string(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerRunes 逐个字符转为小写，字符位置与tokenize的结果一致
+func lowerRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
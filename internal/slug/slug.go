@@ -0,0 +1,109 @@
+// Package slug 生成和校验用于URL的slug
+// slug由小写字母、数字（包括中文等非ASCII文字）和单个连字符组成，不以连字符开头或结尾。
+// 文章的slug先经过Transliterate把汉字转为拼音，标签和分类的slug直接保留中文。
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mozillazg/go-pinyin"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength slug的最大长度（字符数）
+const MaxLength = 64
+
+// Make 把名称转换为slug
+// 字母转为小写，字母和数字保留，其余连续的字符替换为一个连字符，超过MaxLength的部分被截断
+// 参数：
+//
+//	s - 名称
+//
+// 返回：
+//
+//	string - 生成的slug，名称中没有字母和数字时为空
+func Make(s string) string {
+	var b strings.Builder
+	n, dash := 0, false
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			dash = b.Len() > 0
+			continue
+		}
+		size := 1
+		if dash {
+			size = 2
+		}
+		if n+size > MaxLength {
+			break
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+		n += size
+	}
+	return b.String()
+}
+
+// Valid 判断s是否是合法的slug，即Make(s)不会改变s
+func Valid(s string) bool {
+	return s != "" && utf8.RuneCountInString(s) <= MaxLength && Make(s) == s
+}
+
+// pinyinArgs 汉字转拼音的参数：不带声调，多音字取第一个读音
+var pinyinArgs = pinyin.NewArgs()
+
+// Transliterate 把文字转写为拉丁字母，用于生成文章的slug
+// 汉字转为不带声调的拼音，每个字的拼音前后加空格使其成为独立的单词；
+// 带变音符号的拉丁字母去掉变音符号（如é转为e），其余字符保持不变
+// 参数：
+//
+//	s - 原文
+//
+// 返回：
+//
+//	string - 转写后的文字，通常再交给Make生成slug
+func Transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// NFD分解出的变音符号
+		case unicode.Is(unicode.Han, r):
+			if py := pinyin.SinglePinyin(r, pinyinArgs); len(py) > 0 {
+				b.WriteByte(' ')
+				b.WriteString(py[0])
+				b.WriteByte(' ')
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
+
+// WithSuffix 在slug后加上数字后缀，用于解决slug冲突
+// 加上后缀后超过MaxLength时截短原slug，保证结果仍是合法的slug
+// 参数：
+//
+//	s - 合法的slug
+//	n - 后缀数字
+//
+// 返回：
+//
+//	string - 形如s-n的slug
+func WithSuffix(s string, n int) string {
+	suffix := "-" + strconv.Itoa(n)
+	limit := MaxLength - len(suffix)
+	if runes := []rune(s); len(runes) > limit {
+		s = strings.TrimRight(string(runes[:limit]), "-")
+	}
+	return s + suffix
+}
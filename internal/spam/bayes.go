@@ -0,0 +1,24 @@
+package spam
+
+import (
+	"gofile/models"
+	"math"
+)
+
+// spamProbability 用朴素贝叶斯估计样本是垃圾内容的概率
+// 每个词的条件概率按包含该词的文档比例计算并做拉普拉斯平滑，两类的先验概率视为相等，
+// 避免训练样本不均衡时偏向样本多的一类。从未在训练中出现过的词不参与计算
+func spamProbability(tokens []string, counts map[string]models.SpamToken) float64 {
+	total := counts[""]
+	logRatio := 0.0
+	for _, token := range tokens {
+		count, ok := counts[token]
+		if !ok || token == "" {
+			continue
+		}
+		pSpam := float64(count.Spam+1) / float64(total.Spam+2)
+		pHam := float64(count.Ham+1) / float64(total.Ham+2)
+		logRatio += math.Log(pSpam) - math.Log(pHam)
+	}
+	return 1 / (1 + math.Exp(-logRatio))
+}
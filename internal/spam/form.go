@@ -0,0 +1,54 @@
+package spam
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errInvalidFormToken 表单令牌缺失、被篡改或已过期
+var errInvalidFormToken = errors.New("spam: invalid form token")
+
+// IssueFormToken 签发表单令牌
+// 前端打开评论或注册表单时获取令牌并随表单提交，过滤器据此判断从打开表单到提交经过的时间
+func (f *Filter) IssueFormToken() string {
+	encoded := strconv.FormatInt(time.Now().UnixMilli(), 36)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(f.signFormToken(encoded))
+}
+
+// FormTokenTTL 返回表单令牌的有效期
+func (f *Filter) FormTokenTTL() time.Duration {
+	return f.cfg.MaxFormAge
+}
+
+// openFormToken 校验表单令牌，返回签发时间
+func (f *Filter) openFormToken(token string) (time.Time, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return time.Time{}, errInvalidFormToken
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, f.signFormToken(encoded)) {
+		return time.Time{}, errInvalidFormToken
+	}
+	millis, err := strconv.ParseInt(encoded, 36, 64)
+	if err != nil {
+		return time.Time{}, errInvalidFormToken
+	}
+	issued := time.UnixMilli(millis)
+	if time.Since(issued) > f.cfg.MaxFormAge {
+		return time.Time{}, errInvalidFormToken
+	}
+	return issued, nil
+}
+
+// signFormToken 计算表单令牌的HMAC-SHA256签名
+func (f *Filter) signFormToken(data string) []byte {
+	mac := hmac.New(sha256.New, f.cfg.FormSecret)
+	mac.Write([]byte("spam-form\x00" + data))
+	return mac.Sum(nil)
+}
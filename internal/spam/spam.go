@@ -0,0 +1,365 @@
+// Package spam 提供评论和注册的本地垃圾内容过滤
+// 过滤器综合蜜罐字段、提交耗时、链接数量、管理员维护的屏蔽列表和可训练的朴素贝叶斯分类器给出评分，
+// 不依赖任何外部服务。分类器模型保存在数据库中，审核人标记垃圾或正常内容时随之更新。
+package spam
+
+import (
+	"errors"
+	"fmt"
+	"gofile/models"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 训练样本的类别
+const (
+	ClassSpam = "spam" // 垃圾内容
+	ClassHam  = "ham"  // 正常内容
+)
+
+// 各项检查命中时增加的评分
+const (
+	weightHoneypot     = 1.0 // 填写了蜜罐字段，只有机器人会这样做
+	weightBlocklist    = 1.0 // 命中屏蔽列表
+	weightTooFast      = 0.6 // 从获取表单到提交的时间过短
+	weightInvalidToken = 0.3 // 表单令牌缺失、无效或已过期
+	weightPerLink      = 0.3 // 超出数量限制的每个链接
+	weightBayes        = 1.2 // 分类器判定为垃圾内容的概率为1时的评分，概率0.5及以下不计分
+)
+
+// 分类器至少需要每类这么多训练样本才参与评分，避免少量样本导致误判
+const minTrainingDocs = 5
+
+// blocklistTTL 屏蔽列表缓存的有效期，多个实例共享数据库时其他实例的修改在此时间内生效
+const blocklistTTL = time.Minute
+
+// 屏蔽列表条目相关的错误
+var (
+	ErrInvalidBlockEntry   = errors.New("spam: invalid blocklist entry")
+	ErrDuplicateBlockEntry = errors.New("spam: duplicate blocklist entry")
+)
+
+// Config 过滤器配置
+type Config struct {
+	FormSecret      []byte        // 表单令牌的签名密钥
+	MinSubmitTime   time.Duration // 从获取表单令牌到提交的最短时间，为0时不检查
+	MaxFormAge      time.Duration // 表单令牌的有效期
+	MaxLinks        int           // 内容中允许的链接数量，超出的每个链接都会增加评分，小于0时不检查
+	ReviewThreshold float64       // 评分达到此值时需要人工审核
+	SpamThreshold   float64       // 评分达到此值时直接判定为垃圾内容
+}
+
+// Submission 待检查的一次提交
+type Submission struct {
+	Content   string // 正文
+	Name      string // 昵称或用户名
+	Email     string // 邮箱
+	IP        string // 来源IP
+	Honeypot  string // 蜜罐字段的值，正常用户看不到该字段，应为空
+	FormToken string // 获取表单时签发的令牌
+}
+
+// Action 根据评分采取的处理方式
+type Action string
+
+// 支持的处理方式
+const (
+	ActionAllow  Action = "allow"  // 正常内容
+	ActionReview Action = "review" // 需要人工审核
+	ActionReject Action = "reject" // 垃圾内容
+)
+
+// Result 检查结果
+type Result struct {
+	Score   float64  // 各项检查的评分之和
+	Reasons []string // 命中的检查项，用于日志和审核
+	Action  Action   // 处理方式
+}
+
+// Filter 垃圾内容过滤器，可以在多个goroutine中并发使用
+type Filter struct {
+	store models.SpamStore
+	cfg   Config
+
+	mu        sync.Mutex
+	blocklist *blocklist
+	loadedAt  time.Time
+}
+
+// NewFilter 创建过滤器
+// 参数：
+//
+//	store - 屏蔽列表和分类器模型的存储
+//	cfg - 过滤器配置
+//
+// 返回：
+//
+//	*Filter - 过滤器实例
+//	error - 配置无效时返回错误
+func NewFilter(store models.SpamStore, cfg Config) (*Filter, error) {
+	if len(cfg.FormSecret) == 0 {
+		return nil, errors.New("表单令牌签名密钥不能为空")
+	}
+	if cfg.MaxFormAge <= cfg.MinSubmitTime {
+		return nil, errors.New("表单令牌有效期必须大于最短提交时间")
+	}
+	if cfg.ReviewThreshold <= 0 || cfg.SpamThreshold < cfg.ReviewThreshold {
+		return nil, fmt.Errorf("垃圾内容评分阈值无效: review=%v spam=%v", cfg.ReviewThreshold, cfg.SpamThreshold)
+	}
+	return &Filter{store: store, cfg: cfg}, nil
+}
+
+// Check 检查一次提交
+// 存储出错时返回错误，调用方可以选择放行或转人工审核
+func (f *Filter) Check(sub Submission) (Result, error) {
+	var result Result
+	add := func(score float64, reason string) {
+		result.Score += score
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	if strings.TrimSpace(sub.Honeypot) != "" {
+		add(weightHoneypot, "honeypot")
+	}
+	if issued, err := f.openFormToken(sub.FormToken); err != nil {
+		add(weightInvalidToken, "form_token")
+	} else if time.Since(issued) < f.cfg.MinSubmitTime {
+		add(weightTooFast, "too_fast")
+	}
+
+	hosts := linkHosts(sub.Content)
+	if f.cfg.MaxLinks >= 0 && len(hosts) > f.cfg.MaxLinks {
+		add(weightPerLink*float64(len(hosts)-f.cfg.MaxLinks), "links")
+	}
+
+	list, err := f.loadBlocklist()
+	if err != nil {
+		return Result{}, err
+	}
+	if reason := list.match(sub, hosts); reason != "" {
+		add(weightBlocklist, reason)
+	}
+
+	tokens := Tokenize(sub.Name + "\n" + sub.Content)
+	counts, err := f.store.GetSpamTokens(append(tokens, ""))
+	if err != nil {
+		return Result{}, err
+	}
+	if total := counts[""]; total.Spam >= minTrainingDocs && total.Ham >= minTrainingDocs {
+		if p := spamProbability(tokens, counts); p > 0.5 {
+			add(weightBayes*(p-0.5)*2, fmt.Sprintf("bayes:%.2f", p))
+		}
+	}
+
+	switch {
+	case result.Score >= f.cfg.SpamThreshold:
+		result.Action = ActionReject
+	case result.Score >= f.cfg.ReviewThreshold:
+		result.Action = ActionReview
+	default:
+		result.Action = ActionAllow
+	}
+	return result, nil
+}
+
+// Train 用一次提交更新分类器
+// 参数：
+//
+//	sub - 训练样本，只使用昵称和正文
+//	from - 样本之前被训练成的类别，为空表示未训练过，会先撤销旧的训练
+//	to - 新的类别，为空表示只撤销训练
+//
+// 返回：
+//
+//	error - 存储出错时返回错误
+func (f *Filter) Train(sub Submission, from, to string) error {
+	if from == to {
+		return nil
+	}
+	spam, ham := classDelta(to, 1)
+	fromSpam, fromHam := classDelta(from, -1)
+	spam += fromSpam
+	ham += fromHam
+	return f.store.AddSpamTokens(Tokenize(sub.Name+"\n"+sub.Content), spam, ham)
+}
+
+// classDelta 返回训练类别对应的垃圾和正常计数变化
+func classDelta(class string, n int) (int, int) {
+	switch class {
+	case ClassSpam:
+		return n, 0
+	case ClassHam:
+		return 0, n
+	}
+	return 0, 0
+}
+
+// Blocklist 获取全部屏蔽列表条目
+func (f *Filter) Blocklist() ([]*models.SpamBlockEntry, error) {
+	return f.store.GetSpamBlocklist()
+}
+
+// AddBlockEntry 添加屏蔽列表条目
+// 参数：
+//
+//	kind - 条目类型：word、ip或domain
+//	value - 屏蔽的词、IP（或CIDR网段）或域名
+//
+// 返回：
+//
+//	*models.SpamBlockEntry - 添加的条目
+//	error - 条目无效时返回ErrInvalidBlockEntry，已存在时返回ErrDuplicateBlockEntry
+func (f *Filter) AddBlockEntry(kind, value string) (*models.SpamBlockEntry, error) {
+	value, err := normalizeBlockValue(kind, value)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := f.store.GetSpamBlocklist()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Kind == kind && entry.Value == value {
+			return nil, ErrDuplicateBlockEntry
+		}
+	}
+	entry := &models.SpamBlockEntry{Kind: kind, Value: value}
+	if err := f.store.CreateSpamBlockEntry(entry); err != nil {
+		return nil, err
+	}
+	f.invalidateBlocklist()
+	return entry, nil
+}
+
+// DeleteBlockEntry 删除屏蔽列表条目，返回条目是否存在
+func (f *Filter) DeleteBlockEntry(id uint) (bool, error) {
+	found, err := f.store.DeleteSpamBlockEntry(id)
+	if err != nil {
+		return false, err
+	}
+	f.invalidateBlocklist()
+	return found, nil
+}
+
+// loadBlocklist 返回缓存的屏蔽列表，过期时从存储重新加载
+func (f *Filter) loadBlocklist() (*blocklist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.blocklist != nil && time.Since(f.loadedAt) < blocklistTTL {
+		return f.blocklist, nil
+	}
+	entries, err := f.store.GetSpamBlocklist()
+	if err != nil {
+		return nil, err
+	}
+	f.blocklist = newBlocklist(entries)
+	f.loadedAt = time.Now()
+	return f.blocklist, nil
+}
+
+// invalidateBlocklist 使屏蔽列表缓存失效，下次检查时重新加载
+func (f *Filter) invalidateBlocklist() {
+	f.mu.Lock()
+	f.blocklist = nil
+	f.mu.Unlock()
+}
+
+// blocklist 解析后的屏蔽列表
+type blocklist struct {
+	words   []string
+	nets    []*net.IPNet
+	domains []string
+}
+
+// newBlocklist 解析屏蔽列表条目，无法解析的条目被忽略
+func newBlocklist(entries []*models.SpamBlockEntry) *blocklist {
+	list := &blocklist{}
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.SpamBlockWord:
+			list.words = append(list.words, entry.Value)
+		case models.SpamBlockIP:
+			if ipNet := parseIPNet(entry.Value); ipNet != nil {
+				list.nets = append(list.nets, ipNet)
+			}
+		case models.SpamBlockDomain:
+			list.domains = append(list.domains, entry.Value)
+		}
+	}
+	return list
+}
+
+// match 返回提交命中的屏蔽列表类型，没有命中时返回空字符串
+func (l *blocklist) match(sub Submission, hosts []string) string {
+	text := strings.ToLower(sub.Name + "\n" + sub.Content)
+	for _, word := range l.words {
+		if strings.Contains(text, word) {
+			return "blocklist:" + models.SpamBlockWord
+		}
+	}
+	if ip := net.ParseIP(sub.IP); ip != nil {
+		for _, ipNet := range l.nets {
+			if ipNet.Contains(ip) {
+				return "blocklist:" + models.SpamBlockIP
+			}
+		}
+	}
+	if _, domain, ok := strings.Cut(sub.Email, "@"); ok {
+		hosts = append(hosts, strings.ToLower(domain))
+	}
+	for _, host := range hosts {
+		for _, domain := range l.domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return "blocklist:" + models.SpamBlockDomain
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeBlockValue 校验并规范化屏蔽列表条目的值
+func normalizeBlockValue(kind, value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", ErrInvalidBlockEntry
+	}
+	switch kind {
+	case models.SpamBlockWord:
+		return value, nil
+	case models.SpamBlockIP:
+		ipNet := parseIPNet(value)
+		if ipNet == nil {
+			return "", ErrInvalidBlockEntry
+		}
+		if !strings.Contains(value, "/") {
+			return net.ParseIP(value).String(), nil
+		}
+		return ipNet.String(), nil
+	case models.SpamBlockDomain:
+		value = strings.TrimPrefix(strings.TrimSuffix(value, "."), "*.")
+		if strings.ContainsAny(value, " /@:") || !strings.Contains(value, ".") {
+			return "", ErrInvalidBlockEntry
+		}
+		return value, nil
+	}
+	return "", ErrInvalidBlockEntry
+}
+
+// parseIPNet 把IP或CIDR网段解析为网段，单个IP视为只包含自身的网段
+func parseIPNet(value string) *net.IPNet {
+	if _, ipNet, err := net.ParseCIDR(value); err == nil {
+		return ipNet
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	bits := 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
@@ -0,0 +1,96 @@
+package spam
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 每个样本最多使用的词数，避免超长内容拖慢检查和训练
+const maxTokens = 200
+
+// 英文单词的长度范围，过短或过长的词几乎没有区分度
+const (
+	minWordLen = 2
+	maxWordLen = 30
+)
+
+// linkPattern 匹配正文中的链接
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'()\[\]]+`)
+
+// Tokenize 把文本切分为分类器使用的词，结果已去重
+// 拉丁字母和数字按单词切分并转为小写，中日韩文字按相邻两字切分，
+// 链接额外生成"domain:"前缀的域名词
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if len(tokens) < maxTokens && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, host := range linkHosts(text) {
+		if host != "" {
+			add("domain:" + host)
+		}
+	}
+
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if n := len(word); n >= minWordLen && n <= maxWordLen {
+			add(string(word))
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			add(string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			add(string(cjk[i : i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// isCJK 判断字符是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// linkHosts 返回正文中每个链接的主机名（小写），链接无法解析时主机名为空
+func linkHosts(text string) []string {
+	links := linkPattern.FindAllString(text, -1)
+	hosts := make([]string, 0, len(links))
+	for _, link := range links {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		host := ""
+		if u, err := url.Parse(link); err == nil {
+			host = strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
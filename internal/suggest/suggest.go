@@ -0,0 +1,207 @@
+// Package suggest 提供搜索框的自动补全和"您是不是要找"拼写纠错建议
+// 补全候选包括已发布文章的标题、标签和分类，按热度排序：文章的热度是浏览量，
+// 标签和分类的热度是其中已发布文章（分类包括子分类）的浏览量之和。
+// 拼写纠错使用由已发布文章的标题、正文和标签建立的词表，按编辑距离查找相近的单词。
+// 候选和词表由存储中的数据建立后缓存，文章修改后调用Invalidate使缓存失效。
+package suggest
+
+import (
+	"gofile/internal/markdown"
+	"gofile/internal/search"
+	"gofile/models"
+	"html"
+	"sort"
+	"sync"
+	"time"
+)
+
+// indexTTL 缓存的有效期，浏览量的变化、标签和分类的修改以及其他实例上的文章修改在此时间内生效
+const indexTTL = time.Minute
+
+// Store 建立建议索引所需的存储
+type Store interface {
+	models.ArticleStore
+	models.TagStore
+	models.CategoryStore
+}
+
+// Item 一条补全建议
+type Item struct {
+	ID    int    `json:"id"`    // 文章、标签或分类的ID
+	Text  string `json:"text"`  // 文章标题或标签、分类名称，已转义HTML
+	Slug  string `json:"slug"`  // 文章、标签或分类的slug
+	Views int    `json:"views"` // 热度
+}
+
+// Result 补全和纠错建议
+type Result struct {
+	Titles     []*Item  `json:"titles"`       // 标题匹配的文章
+	Tags       []*Item  `json:"tags"`         // 名称匹配的标签
+	Categories []*Item  `json:"categories"`   // 名称匹配的分类
+	DidYouMean []string `json:"did_you_mean"` // 拼写纠错后的搜索词，没有需要纠正的单词时为空
+}
+
+// Suggester 搜索建议，可以在多个goroutine中并发使用
+type Suggester struct {
+	store Store
+
+	mu       sync.Mutex
+	index    *index
+	loadedAt time.Time
+}
+
+// index 缓存的补全候选和词表，建立后只读
+type index struct {
+	titles     []*Item
+	tags       []*Item
+	categories []*Item
+	vocabulary *search.Vocabulary
+}
+
+// New 创建搜索建议
+// 参数：
+//
+//	store - 读取已发布文章、标签和分类的存储
+//
+// 返回：
+//
+//	*Suggester - 搜索建议实例
+func New(store Store) *Suggester {
+	return &Suggester{store: store}
+}
+
+// Suggest 返回与输入匹配的补全建议和拼写纠错建议
+// 标题、标签和分类中有词以prefix开头（不区分大小写，中文可以从任意位置开始）即为匹配；
+// 没有任何匹配但拼写纠错有结果时，按第一个纠错建议匹配
+// 参数：
+//
+//	prefix - 用户已经输入的部分
+//	limit - 每一类建议的最大数量
+//
+// 返回：
+//
+//	*Result - 建议
+//	error - 读取存储失败时返回错误
+func (s *Suggester) Suggest(prefix string, limit int) (*Result, error) {
+	idx, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{DidYouMean: idx.vocabulary.Suggest(prefix, limit)}
+	if result.DidYouMean == nil {
+		result.DidYouMean = []string{}
+	}
+	if !idx.complete(result, prefix, limit) && len(result.DidYouMean) > 0 {
+		idx.complete(result, result.DidYouMean[0], limit)
+	}
+	return result, nil
+}
+
+// Invalidate 使缓存失效，下次请求时重新建立
+func (s *Suggester) Invalidate() {
+	s.mu.Lock()
+	s.index = nil
+	s.mu.Unlock()
+}
+
+// load 返回缓存的索引，过期时由存储中的数据重新建立
+func (s *Suggester) load() (*index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.index != nil && time.Since(s.loadedAt) < indexTTL {
+		return s.index, nil
+	}
+	idx, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	s.index = idx
+	s.loadedAt = time.Now()
+	return idx, nil
+}
+
+// build 由已发布文章、标签和分类建立索引
+func (s *Suggester) build() (*index, error) {
+	articles, err := s.store.GetArticles(models.ArticleQuery{Status: models.ArticleStatusPublished})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.store.GetTags()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.store.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &index{}
+	tagViews := make(map[int]int)
+	categoryViews := make(map[int]int)
+	texts := make([]string, 0, len(articles)*2)
+	for _, article := range articles {
+		idx.titles = append(idx.titles, &Item{ID: article.ID, Text: article.Title, Slug: article.Slug, Views: article.Views})
+		texts = append(texts, html.UnescapeString(article.Title), markdown.PlainText(article.Content))
+		for _, tag := range article.Tags {
+			tagViews[tag.ID] += article.Views
+			texts = append(texts, html.UnescapeString(tag.Name))
+		}
+		if article.CategoryID != nil {
+			categoryViews[*article.CategoryID] += article.Views
+		}
+	}
+	// 没有已发布文章的标签和分类不作为建议
+	for _, tag := range tags {
+		if tag.ArticleCount > 0 {
+			idx.tags = append(idx.tags, &Item{ID: tag.ID, Text: tag.Name, Slug: tag.Slug, Views: tagViews[tag.ID]})
+		}
+	}
+	for _, category := range categories {
+		if category.ArticleCount == 0 {
+			continue
+		}
+		views := 0
+		for _, id := range models.CategoryDescendants(categories, category.ID) {
+			views += categoryViews[id]
+		}
+		idx.categories = append(idx.categories, &Item{ID: category.ID, Text: category.Name, Slug: category.Slug, Views: views})
+	}
+	for _, items := range [][]*Item{idx.titles, idx.tags, idx.categories} {
+		sortItems(items)
+	}
+	idx.vocabulary = search.NewVocabulary(texts...)
+	return idx, nil
+}
+
+// sortItems 按热度降序排列候选，热度相同时按文字排序
+func sortItems(items []*Item) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Views != items[j].Views {
+			return items[i].Views > items[j].Views
+		}
+		return items[i].Text < items[j].Text
+	})
+}
+
+// complete 把与输入匹配的标题、标签和分类填入result，返回是否有任何匹配
+func (idx *index) complete(result *Result, prefix string, limit int) bool {
+	result.Titles = matchItems(idx.titles, prefix, limit)
+	result.Tags = matchItems(idx.tags, prefix, limit)
+	result.Categories = matchItems(idx.categories, prefix, limit)
+	return len(result.Titles)+len(result.Tags)+len(result.Categories) > 0
+}
+
+// matchItems 返回与输入匹配的前limit个候选，候选已按热度排序
+func matchItems(items []*Item, prefix string, limit int) []*Item {
+	matched := []*Item{}
+	for _, item := range items {
+		if len(matched) == limit {
+			break
+		}
+		if search.MatchPrefix(html.UnescapeString(item.Text), prefix) {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
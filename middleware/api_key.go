@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gofile/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 除Authorization: Bearer外，也可以通过此请求头提交API密钥
+const APIKeyHeader = "X-API-Key"
+
+// ContextAPIKey 使用API密钥认证时密钥记录在gin上下文中的键
+const ContextAPIKey = "apiKey"
+
+// apiKeyTag API密钥的固定开头，用于和JWT区分，也便于密钥扫描工具识别泄露的密钥
+const apiKeyTag = "blog_"
+
+// apiKeyPrefixLen 密钥中作为可见前缀保存的长度（含apiKeyTag）
+const apiKeyPrefixLen = len(apiKeyTag) + 8
+
+// apiKeyTouchInterval 最近使用时间的更新间隔，避免每个请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+// ErrAPIKeyInvalid API密钥不存在、已吊销、已过期或所属用户不存在
+var ErrAPIKeyInvalid = errors.New("无效的API密钥")
+
+// APIKeys 创建和校验个人API密钥
+// 密钥是带固定开头的随机字符串，只在创建时返回一次，服务器保存其SHA-256哈希；
+// 使用密钥时按用户当前的角色授权，并且只能使用创建时选择的权限
+type APIKeys struct {
+	store models.APIKeyStore
+	users models.UserStore
+}
+
+// NewAPIKeys 创建API密钥管理器
+// 参数：
+//
+//	store - API密钥存储
+//	users - 用户存储，校验密钥时读取所属用户的当前角色
+//
+// 返回：
+//
+//	*APIKeys - API密钥管理器
+func NewAPIKeys(store models.APIKeyStore, users models.UserStore) *APIKeys {
+	return &APIKeys{store: store, users: users}
+}
+
+// IsAPIKey 判断凭据是否为API密钥格式
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyTag)
+}
+
+// hashAPIKey 计算API密钥的SHA-256哈希
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create 为用户创建API密钥
+// 参数：
+//
+//	user - 密钥所属用户
+//	name - 密钥名称
+//	scopes - 密钥允许使用的权限，调用方应已校验
+//	expiresAt - 过期时间，nil表示永不过期
+//
+// 返回：
+//
+//	string - 密钥原文，只在此时返回一次
+//	*models.APIKey - 保存的密钥记录
+//	error - 生成或保存失败时返回错误
+func (k *APIKeys) Create(user *models.User, name string, scopes []models.Permission, expiresAt *time.Time) (string, *models.APIKey, error) {
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := apiKeyTag + secret
+	key := &models.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    raw[:apiKeyPrefixLen],
+		KeyHash:   hashAPIKey(raw),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := k.store.CreateAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// Authenticate 校验API密钥并返回密钥记录和所属用户，同时记录最近使用时间
+// 返回：
+//
+//	*models.APIKey - 密钥记录
+//	*models.User - 密钥所属用户的当前数据
+//	error - 密钥无效返回ErrAPIKeyInvalid，存储出错时返回存储错误
+func (k *APIKeys) Authenticate(raw string) (*models.APIKey, *models.User, error) {
+	key, err := k.store.GetAPIKeyByHash(hashAPIKey(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if key == nil || !key.Active(now) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	user, err := k.users.GetUserByID(uint(key.UserID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		// 最近使用时间只用于展示，更新失败不影响本次请求
+		if err := k.store.TouchAPIKey(key.ID, now); err != nil {
+			log.Printf("更新API密钥使用时间失败 key=%d: %v", key.ID, err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, user, nil
+}
+
+// CurrentAPIKey 获取AuthMiddleware存入上下文的API密钥，使用访问令牌认证时返回nil
+func CurrentAPIKey(c *gin.Context) *models.APIKey {
+	value, ok := c.Get(ContextAPIKey)
+	if !ok {
+		return nil
+	}
+	key, _ := value.(*models.APIKey)
+	return key
+}
+
+// RequireSession 要求请求使用访问令牌认证的中间件
+// 必须放在AuthMiddleware之后使用。退出登录、两步验证、管理API密钥等账号操作
+// 不允许使用API密钥完成，避免泄露的密钥被用来创建新密钥或接管账号
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentAPIKey(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code": 403,
+				"msg":  "该接口不支持使用API密钥访问",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"gofile/models"
 	"net/http"
 	"strings"
 	"time"
@@ -12,85 +16,287 @@ import (
 
 // JWTClaims 定义JWT的声明结构
 type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID   uint        `json:"user_id"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"` // 签发令牌时用户的角色，角色变更在刷新令牌后生效
+	// 微秒精度的签发时间；iat只精确到秒，无法区分"退出所有会话"同一秒内前后签发的令牌
+	IssuedAtMicro int64 `json:"iat_us"`
 	jwt.StandardClaims
 }
 
-var jwtSecret = []byte("blog_secret_key") // 在生产环境中应该从配置中读取
+// 认证通过后当前用户信息在gin上下文中的键
+const (
+	ContextUserID = "userID"
+	ContextRole   = "role"
+	ContextClaims = "claims"
+)
+
+// ErrTokenRevoked 访问令牌已被吊销
+var ErrTokenRevoked = errors.New("令牌已被吊销")
+
+// TokenManager 负责签发和校验访问令牌、轮换刷新令牌
+// 访问令牌是短期有效的JWT，通过jti声明支持吊销，头部的kid指明签名密钥；
+// 刷新令牌是不透明的随机字符串，只在服务器保存哈希
+type TokenManager struct {
+	store      models.TokenStore
+	keys       *KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	apiKeys    *APIKeys
+	session    *SessionCookiePolicy
+}
+
+// NewTokenManager 创建令牌管理器
+// 参数：
+//
+//	store - 保存刷新令牌和吊销列表的存储
+//	keys - 签发和校验访问令牌的密钥集合
+//	accessTTL - 访问令牌有效期
+//	refreshTTL - 刷新令牌有效期
+//
+// 返回：
+//
+//	*TokenManager - 令牌管理器实例
+func NewTokenManager(store models.TokenStore, keys *KeySet, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{store: store, keys: keys, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+// UseAPIKeys 让AuthMiddleware同时接受个人API密钥，未调用时只接受访问令牌
+func (m *TokenManager) UseAPIKeys(keys *APIKeys) {
+	m.apiKeys = keys
+}
+
+// Keys 返回签名密钥集合
+func (m *TokenManager) Keys() *KeySet {
+	return m.keys
+}
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(userID uint, username string) (string, error) {
-	claims := JWTClaims{
-		UserID:   userID,
-		Username: username,
+// AccessTTL 返回访问令牌有效期
+func (m *TokenManager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// GenerateToken 使用活动密钥生成JWT访问令牌
+func (m *TokenManager) GenerateToken(userID uint, username string, role models.Role) (string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := &JWTClaims{
+		UserID:        userID,
+		Username:      username,
+		Role:          role,
+		IssuedAtMicro: now.UnixMicro(),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(), // 24小时过期
-			IssuedAt:  time.Now().Unix(),
+			Id:        jti,
+			ExpiresAt: now.Add(m.accessTTL).Unix(),
+			IssuedAt:  now.Unix(),
 			Issuer:    "blog_system",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
-	return tokenString, err
+	return m.sign(claims)
 }
 
-// ParseToken 解析JWT令牌
-func ParseToken(tokenString string) (*JWTClaims, error) {
+// sign 使用活动密钥签名令牌，头部写入kid
+func (m *TokenManager) sign(claims *JWTClaims) (string, error) {
+	key := m.keys.Active()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// ParseToken 解析JWT访问令牌，并检查令牌是否已被吊销
+// 两步验证待定令牌不是访问令牌，会被拒绝
+func (m *TokenManager) ParseToken(tokenString string) (*JWTClaims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Audience != "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+// parse 校验令牌签名和有效期，并检查令牌是否已被吊销
+// 根据头部的kid选择校验密钥，令牌声明的算法必须与该密钥的算法一致
+func (m *TokenManager) parse(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key := m.keys.Lookup(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key: %q", kid)
+		}
+		if token.Method.Alg() != key.Algorithm() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
+		return key.verifyKey, nil
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid || claims.Id == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	revoked, err := m.store.IsAccessTokenRevoked(claims.Id, claims.UserID, time.UnixMicro(claims.IssuedAtMicro))
+	if err != nil {
+		return nil, err
 	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
 
-	return nil, fmt.Errorf("invalid token")
+// RevokeToken 吊销访问令牌，令牌过期前都会被ParseToken拒绝
+func (m *TokenManager) RevokeToken(claims *JWTClaims) error {
+	return m.store.RevokeAccessToken(&models.RevokedToken{
+		JTI:       claims.Id,
+		UserID:    int(claims.UserID),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		RevokedAt: time.Now(),
+	})
 }
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// OptionalAuthMiddleware 可选认证中间件
+// 请求携带Authorization、X-API-Key请求头或会话cookie时与AuthMiddleware相同，认证失败返回401；
+// 没有任何认证信息时作为匿名请求继续处理，CurrentUserID返回false
+func (m *TokenManager) OptionalAuthMiddleware() gin.HandlerFunc {
+	auth := m.AuthMiddleware()
 	return func(c *gin.Context) {
-		auth := c.GetHeader("Authorization")
-		if auth == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code": 401,
-				"msg":  "未提供认证令牌",
-			})
-			c.Abort()
+		if c.GetHeader(APIKeyHeader) == "" && c.GetHeader("Authorization") == "" && m.sessionToken(c) == "" {
+			c.Next()
 			return
 		}
+		auth(c)
+	}
+}
+
+// AuthMiddleware 认证中间件
+// 接受Authorization: Bearer携带的JWT访问令牌；启用API密钥后，
+// 也接受Authorization: Bearer或X-API-Key请求头携带的API密钥；
+// 启用cookie会话后，没有这两个请求头的请求使用会话cookie中的访问令牌
+func (m *TokenManager) AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential := c.GetHeader(APIKeyHeader)
+		isAPIKey := credential != ""
+		auth := c.GetHeader("Authorization")
+		switch {
+		case isAPIKey:
+		case auth != "":
+			parts := strings.SplitN(auth, " ", 2)
+			if !(len(parts) == 2 && parts[0] == "Bearer") {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code": 401,
+					"msg":  "认证格式无效",
+				})
+				c.Abort()
+				return
+			}
+			credential = parts[1]
+			isAPIKey = IsAPIKey(credential)
+		default:
+			// cookie中只保存访问令牌，不接受API密钥
+			credential = m.sessionToken(c)
+			if credential == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code": 401,
+					"msg":  "未提供认证令牌",
+				})
+				c.Abort()
+				return
+			}
+		}
 
-		parts := strings.SplitN(auth, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code": 401,
-				"msg":  "认证格式无效",
-			})
-			c.Abort()
+		if isAPIKey {
+			if m.apiKeys == nil {
+				abortAuthError(c, ErrAPIKeyInvalid, "不支持API密钥认证")
+				return
+			}
+			m.authenticateAPIKey(c, credential)
 			return
 		}
 
-		claims, err := ParseToken(parts[1])
+		claims, err := m.ParseToken(credential)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code": 401,
-				"msg":  "无效的认证令牌",
-			})
-			c.Abort()
+			abortAuthError(c, err, "无效的认证令牌")
 			return
 		}
 
 		// 将用户信息存储到上下文中
-		c.Set("userID", claims.UserID)
+		c.Set(ContextUserID, claims.UserID)
+		c.Set(ContextRole, claims.Role)
+		c.Set(ContextClaims, claims)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// authenticateAPIKey 校验API密钥并将所属用户信息存入上下文
+// 角色取自用户当前数据，角色变更对API密钥立即生效
+func (m *TokenManager) authenticateAPIKey(c *gin.Context, credential string) {
+	key, user, err := m.apiKeys.Authenticate(credential)
+	if err != nil {
+		abortAuthError(c, err, "无效的API密钥")
+		return
+	}
+	c.Set(ContextUserID, uint(user.ID))
+	c.Set(ContextRole, user.Role)
+	c.Set(ContextAPIKey, key)
+	c.Next()
+}
+
+// abortAuthError 认证失败时中止请求，存储不可用返回503，其余返回401
+func abortAuthError(c *gin.Context, err error, msg string) {
+	if models.IsUnavailable(err) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code":  503,
+			"msg":   "存储服务暂不可用，请稍后重试",
+			"error": "store_unavailable",
+		})
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"code": 401,
+		"msg":  msg,
+	})
+	c.Abort()
+}
+
+// CurrentUserID 获取AuthMiddleware存入上下文的当前用户ID
+// 返回：
+//
+//	uint - 当前用户ID
+//	bool - 请求未经过认证时返回false
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	value, ok := c.Get(ContextUserID)
+	if !ok {
+		return 0, false
+	}
+	userID, ok := value.(uint)
+	return userID, ok && userID != 0
+}
+
+// CurrentClaims 获取AuthMiddleware存入上下文的访问令牌声明，请求未经过认证时返回nil
+func CurrentClaims(c *gin.Context) *JWTClaims {
+	value, ok := c.Get(ContextClaims)
+	if !ok {
+		return nil
+	}
+	claims, _ := value.(*JWTClaims)
+	return claims
+}
+
+// randomHex 生成n字节的随机数并以十六进制字符串返回
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
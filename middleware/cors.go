@@ -1,22 +1,204 @@
 package middleware
 
 import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORSMiddleware 处理跨域资源共享
-func CORSMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+// CORSPolicy 跨域资源共享策略
+type CORSPolicy struct {
+	// AllowedOrigins 允许的来源，支持完整来源（https://blog.example.com）、
+	// 子域名通配（https://*.example.com，不包括example.com本身）和"*"（任意来源，不能与AllowCredentials同时使用）
+	AllowedOrigins   []string
+	AllowedMethods   []string      // 允许的请求方法
+	AllowedHeaders   []string      // 允许的请求头
+	ExposedHeaders   []string      // 允许浏览器读取的响应头
+	MaxAge           time.Duration // 预检结果的缓存时间，0表示不设置
+	AllowCredentials bool          // 是否允许携带cookie等凭据
+}
+
+// CORS 按策略处理跨域请求
+// 允许的来源会被原样回显在Access-Control-Allow-Origin中，并设置Vary: Origin，
+// 不允许的来源不返回任何CORS响应头，由浏览器拦截
+type CORS struct {
+	anyOrigin   bool
+	exact       map[string]bool
+	suffixes    []originSuffix
+	methods     map[string]bool
+	headers     map[string]bool
+	allowMethod string
+	allowHeader string
+	expose      string
+	maxAge      string
+	credentials bool
+}
+
+// originSuffix 子域名通配来源，如https://*.example.com拆分为scheme "https://"和后缀".example.com"
+type originSuffix struct {
+	scheme string
+	suffix string
+}
+
+// NewCORS 根据策略创建跨域处理器
+// 参数：
+//
+//	policy - 跨域策略，AllowedOrigins为空时不允许任何跨域请求
+//
+// 返回：
+//
+//	*CORS - 跨域处理器
+//	error - 来源格式无效，或"*"与AllowCredentials同时使用时返回错误
+func NewCORS(policy CORSPolicy) (*CORS, error) {
+	c := &CORS{
+		exact:       make(map[string]bool),
+		methods:     make(map[string]bool),
+		headers:     make(map[string]bool),
+		credentials: policy.AllowCredentials,
+	}
+	for _, origin := range policy.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		scheme, host, ok := strings.Cut(origin, "://")
+		switch {
+		case origin == "*":
+			if policy.AllowCredentials {
+				return nil, fmt.Errorf("cors: origin \"*\" cannot be used with credentials")
+			}
+			c.anyOrigin = true
+		case !ok || scheme == "" || host == "" || strings.ContainsAny(host, "/?#"):
+			return nil, fmt.Errorf("cors: invalid origin %q", origin)
+		case strings.HasPrefix(host, "*."):
+			if strings.Contains(host[2:], "*") {
+				return nil, fmt.Errorf("cors: invalid origin %q", origin)
+			}
+			c.suffixes = append(c.suffixes, originSuffix{scheme: scheme + "://", suffix: host[1:]})
+		case strings.Contains(host, "*"):
+			return nil, fmt.Errorf("cors: invalid origin %q", origin)
+		default:
+			c.exact[origin] = true
+		}
+	}
+
+	methods := make([]string, 0, len(policy.AllowedMethods))
+	for _, m := range policy.AllowedMethods {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		c.methods[m] = true
+		methods = append(methods, m)
+	}
+	headers := make([]string, 0, len(policy.AllowedHeaders))
+	for _, h := range policy.AllowedHeaders {
+		h = http.CanonicalHeaderKey(strings.TrimSpace(h))
+		c.headers[strings.ToLower(h)] = true
+		headers = append(headers, h)
+	}
+	c.allowMethod = strings.Join(methods, ", ")
+	c.allowHeader = strings.Join(headers, ", ")
+	c.expose = strings.Join(policy.ExposedHeaders, ", ")
+	if policy.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
+	return c, nil
+}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+// allowOrigin 判断来源是否允许，返回应写入Access-Control-Allow-Origin的值
+func (c *CORS) allowOrigin(origin string) (string, bool) {
+	if c.anyOrigin {
+		return "*", true
+	}
+	lower := strings.ToLower(origin)
+	if c.exact[lower] {
+		return origin, true
+	}
+	for _, s := range c.suffixes {
+		if strings.HasPrefix(lower, s.scheme) && strings.HasSuffix(lower, s.suffix) && len(lower) > len(s.scheme)+len(s.suffix) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// allowHeaders 判断预检请求中的Access-Control-Request-Headers是否都被允许
+func (c *CORS) allowHeaders(requested string) bool {
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" && !c.headers[h] {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware 跨域处理中间件
+// 预检请求（带Access-Control-Request-Method的OPTIONS请求）在此处直接应答：
+// 来源、方法和请求头都允许时返回204，否则返回403且不带CORS响应头。
+// 由于gin只对匹配到的路由执行分组中间件，使用此中间件的路由组还需要注册OPTIONS路由，见CORSPreflight
+func (c *CORS) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		header := ctx.Writer.Header()
+		if !c.anyOrigin {
+			header.Add("Vary", "Origin")
+		}
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" {
+			ctx.Next()
 			return
 		}
 
-		c.Next()
+		allowed, ok := c.allowOrigin(origin)
+		if preflight {
+			method := strings.ToUpper(ctx.GetHeader("Access-Control-Request-Method"))
+			if !ok || !c.methods[method] || !c.allowHeaders(ctx.GetHeader("Access-Control-Request-Headers")) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"code": 403,
+					"msg":  "跨域请求不被允许",
+				})
+				return
+			}
+			header.Set("Access-Control-Allow-Origin", allowed)
+			header.Set("Access-Control-Allow-Methods", c.allowMethod)
+			if c.allowHeader != "" {
+				header.Set("Access-Control-Allow-Headers", c.allowHeader)
+			}
+			if c.maxAge != "" {
+				header.Set("Access-Control-Max-Age", c.maxAge)
+			}
+			if c.credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if ok {
+			header.Set("Access-Control-Allow-Origin", allowed)
+			if c.expose != "" {
+				header.Set("Access-Control-Expose-Headers", c.expose)
+			}
+			if c.credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		ctx.Next()
 	}
-}
\ No newline at end of file
+}
+
+// PublicCORS 返回公开只读资源使用的跨域处理器：允许任意来源的GET请求，不允许携带凭据
+func PublicCORS() *CORS {
+	c, _ := NewCORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{http.MethodGet}})
+	return c
+}
+
+// CORSPreflight 注册到路由组"/*path"上的OPTIONS处理函数
+// 预检请求已由Middleware应答；走到这里的是不带Access-Control-Request-Method的普通OPTIONS请求
+func CORSPreflight(ctx *gin.Context) {
+	ctx.Status(http.StatusNoContent)
+}
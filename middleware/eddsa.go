@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEdDSA 基于Ed25519的JWT签名算法（alg为EdDSA）
+// jwt-go v3未内置该算法，这里按其SigningMethod接口实现并在init中注册
+var SigningMethodEdDSA = &signingMethodEd25519{}
+
+// ErrEdDSAVerification Ed25519签名校验失败
+var ErrEdDSAVerification = errors.New("ed25519: verification error")
+
+type signingMethodEd25519 struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+// Alg 返回算法标识
+func (m *signingMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+// Verify 使用ed25519.PublicKey校验签名
+func (m *signingMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok || len(publicKey) != ed25519.PublicKeySize {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return ErrEdDSAVerification
+	}
+	return nil
+}
+
+// Sign 使用ed25519.PrivateKey生成签名
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
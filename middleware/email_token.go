@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"gofile/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 邮件令牌的用途，不同用途的令牌不能混用
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// 邮件令牌相关错误
+var (
+	// ErrEmailTokenInvalid 令牌格式错误、签名不匹配、已被使用或用户不存在
+	ErrEmailTokenInvalid = errors.New("无效的令牌")
+	// ErrEmailTokenExpired 令牌已过期
+	ErrEmailTokenExpired = errors.New("令牌已过期")
+)
+
+// emailTokenPayload 邮件令牌携带的数据
+type emailTokenPayload struct {
+	UserID      int    `json:"uid"`
+	Purpose     string `json:"pur"`
+	ExpiresAt   int64  `json:"exp"`
+	Fingerprint string `json:"fp"` // 签发时用户状态的指纹
+}
+
+// EmailTokens 签发和校验邮箱验证、密码重置令牌
+// 令牌是带HMAC签名的无状态字符串，其中包含签发时用户状态的指纹：
+// 验证邮箱令牌绑定邮箱和验证状态，重置密码令牌绑定密码哈希。
+// 令牌使用后对应状态随之改变，指纹不再匹配，因此每个令牌只能使用一次
+type EmailTokens struct {
+	users  models.UserStore
+	secret []byte
+	ttl    map[string]time.Duration
+}
+
+// NewEmailTokens 创建邮件令牌签发器
+// 参数：
+//
+//	users - 用户存储，校验令牌时读取用户当前状态
+//	secret - HMAC签名密钥
+//	verifyTTL - 邮箱验证令牌有效期
+//	resetTTL - 密码重置令牌有效期
+//
+// 返回：
+//
+//	*EmailTokens - 邮件令牌签发器
+func NewEmailTokens(users models.UserStore, secret []byte, verifyTTL, resetTTL time.Duration) *EmailTokens {
+	return &EmailTokens{
+		users:  users,
+		secret: secret,
+		ttl: map[string]time.Duration{
+			PurposeVerifyEmail:   verifyTTL,
+			PurposeResetPassword: resetTTL,
+		},
+	}
+}
+
+// GenerateEmailTokenSecret 生成随机的邮件令牌签名密钥，用于未配置密钥的开发环境
+func GenerateEmailTokenSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Issue 为用户签发指定用途的令牌
+func (t *EmailTokens) Issue(purpose string, user *models.User) (string, error) {
+	ttl, ok := t.ttl[purpose]
+	if !ok {
+		return "", errors.New("unknown email token purpose: " + purpose)
+	}
+	payload, err := json.Marshal(emailTokenPayload{
+		UserID:      user.ID,
+		Purpose:     purpose,
+		ExpiresAt:   time.Now().Add(ttl).Unix(),
+		Fingerprint: t.fingerprint(purpose, user),
+	})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(encoded)), nil
+}
+
+// Verify 校验令牌并返回令牌所属的用户
+// 参数：
+//
+//	purpose - 期望的令牌用途
+//	token - 客户端提交的令牌
+//
+// 返回：
+//
+//	*models.User - 令牌所属用户的当前数据
+//	error - 令牌无效返回ErrEmailTokenInvalid，过期返回ErrEmailTokenExpired，存储出错时返回存储错误
+func (t *EmailTokens) Verify(purpose, token string) (*models.User, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrEmailTokenInvalid
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, t.sign(encoded)) {
+		return nil, ErrEmailTokenInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrEmailTokenInvalid
+	}
+	var payload emailTokenPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Purpose != purpose {
+		return nil, ErrEmailTokenInvalid
+	}
+	if time.Now().Unix() > payload.ExpiresAt {
+		return nil, ErrEmailTokenExpired
+	}
+
+	user, err := t.users.GetUserByID(uint(payload.UserID))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !hmac.Equal([]byte(payload.Fingerprint), []byte(t.fingerprint(purpose, user))) {
+		return nil, ErrEmailTokenInvalid
+	}
+	return user, nil
+}
+
+// sign 计算签名
+func (t *EmailTokens) sign(data string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// fingerprint 计算与令牌用途相关的用户状态指纹
+func (t *EmailTokens) fingerprint(purpose string, user *models.User) string {
+	var state string
+	switch purpose {
+	case PurposeVerifyEmail:
+		state = user.Email + "\x00" + strconv.FormatBool(user.EmailVerified)
+	case PurposeResetPassword:
+		state = user.Password
+	}
+	sum := t.sign(purpose + "\x00" + state)
+	return hex.EncodeToString(sum[:16])
+}
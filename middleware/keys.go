@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// 支持的JWT签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minHMACSecretLen HS256密钥的最小长度（字节）
+const minHMACSecretLen = 32
+
+// SigningKey JWT签名密钥
+// 每个密钥有唯一的ID，签发令牌时写入JWT头部的kid，校验时据此选择密钥
+// 只有公钥的密钥只能用于校验，用于密钥轮换期间继续接受旧密钥签发的令牌
+type SigningKey struct {
+	ID        string
+	method    jwt.SigningMethod
+	signKey   interface{} // 签名用的密钥，只能校验时为nil
+	verifyKey interface{} // 校验用的密钥
+}
+
+// Algorithm 返回密钥使用的签名算法
+func (k *SigningKey) Algorithm() string {
+	return k.method.Alg()
+}
+
+// CanSign 判断密钥是否可以用于签发令牌
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// NewHMACKey 创建HS256密钥
+// 参数：
+//
+//	id - 密钥ID
+//	secret - 共享密钥，至少32字节
+//
+// 返回：
+//
+//	*SigningKey - 签名密钥
+//	error - 密钥过短时返回错误
+func NewHMACKey(id string, secret []byte) (*SigningKey, error) {
+	if len(secret) < minHMACSecretLen {
+		return nil, fmt.Errorf("密钥%s: HS256密钥至少需要%d字节", id, minHMACSecretLen)
+	}
+	return &SigningKey{ID: id, method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// NewRSAKey 创建RS256密钥，private为nil时只能用于校验
+func NewRSAKey(id string, private *rsa.PrivateKey, public *rsa.PublicKey) (*SigningKey, error) {
+	key := &SigningKey{ID: id, method: jwt.SigningMethodRS256}
+	if private != nil {
+		key.signKey = private
+		public = &private.PublicKey
+	}
+	if public == nil {
+		return nil, fmt.Errorf("密钥%s: 缺少RSA公钥", id)
+	}
+	key.verifyKey = public
+	return key, nil
+}
+
+// NewEdDSAKey 创建EdDSA(Ed25519)密钥，private为nil时只能用于校验
+func NewEdDSAKey(id string, private ed25519.PrivateKey, public ed25519.PublicKey) (*SigningKey, error) {
+	key := &SigningKey{ID: id, method: SigningMethodEdDSA}
+	if private != nil {
+		key.signKey = private
+		public = private.Public().(ed25519.PublicKey)
+	}
+	if public == nil {
+		return nil, fmt.Errorf("密钥%s: 缺少Ed25519公钥", id)
+	}
+	key.verifyKey = public
+	return key, nil
+}
+
+// LoadSigningKey 根据配置加载签名密钥
+// 参数：
+//
+//	id - 密钥ID，写入JWT头部的kid
+//	algorithm - 签名算法：HS256、RS256或EdDSA
+//	secret - HS256使用的共享密钥
+//	privateKeyFile - RS256/EdDSA私钥PEM文件路径，为空表示只用于校验
+//	publicKeyFile - RS256/EdDSA公钥PEM文件路径，提供私钥时可省略
+//
+// 返回：
+//
+//	*SigningKey - 签名密钥
+//	error - 配置无效或密钥文件无法解析时返回错误
+func LoadSigningKey(id, algorithm, secret, privateKeyFile, publicKeyFile string) (*SigningKey, error) {
+	if id == "" {
+		return nil, errors.New("签名密钥缺少id")
+	}
+	if algorithm == AlgHS256 {
+		return NewHMACKey(id, []byte(secret))
+	}
+	if privateKeyFile == "" && publicKeyFile == "" {
+		return nil, fmt.Errorf("密钥%s: %s需要private_key_file或public_key_file", id, algorithm)
+	}
+
+	var privatePEM, publicPEM []byte
+	var err error
+	if privateKeyFile != "" {
+		if privatePEM, err = os.ReadFile(privateKeyFile); err != nil {
+			return nil, fmt.Errorf("密钥%s: 读取私钥失败: %w", id, err)
+		}
+	} else if publicPEM, err = os.ReadFile(publicKeyFile); err != nil {
+		return nil, fmt.Errorf("密钥%s: 读取公钥失败: %w", id, err)
+	}
+
+	switch algorithm {
+	case AlgRS256:
+		if privatePEM != nil {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("密钥%s: 解析RSA私钥失败: %w", id, err)
+			}
+			return NewRSAKey(id, private, nil)
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return nil, fmt.Errorf("密钥%s: 解析RSA公钥失败: %w", id, err)
+		}
+		return NewRSAKey(id, nil, public)
+	case AlgEdDSA:
+		if privatePEM != nil {
+			parsed, err := parsePEM(privatePEM, x509.ParsePKCS8PrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("密钥%s: 解析Ed25519私钥失败: %w", id, err)
+			}
+			private, ok := parsed.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("密钥%s: 私钥不是Ed25519密钥", id)
+			}
+			return NewEdDSAKey(id, private, nil)
+		}
+		parsed, err := parsePEM(publicPEM, x509.ParsePKIXPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("密钥%s: 解析Ed25519公钥失败: %w", id, err)
+		}
+		public, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("密钥%s: 公钥不是Ed25519密钥", id)
+		}
+		return NewEdDSAKey(id, nil, public)
+	default:
+		return nil, fmt.Errorf("密钥%s: 不支持的签名算法: %s", id, algorithm)
+	}
+}
+
+// parsePEM 解码PEM块并使用parse解析其中的DER数据
+func parsePEM(data []byte, parse func([]byte) (any, error)) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的PEM数据")
+	}
+	return parse(block.Bytes)
+}
+
+// GenerateHMACKey 生成随机的HS256密钥，用于未配置签名密钥的开发环境
+// 进程重启后之前签发的访问令牌全部失效
+func GenerateHMACKey(id string) (*SigningKey, error) {
+	secret := make([]byte, minHMACSecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return NewHMACKey(id, secret)
+}
+
+// KeySet 签名密钥集合
+// 新令牌始终使用活动密钥签发；集合中的其他密钥仍用于校验，
+// 轮换时先加入新密钥并切换活动密钥，待旧令牌全部过期后再移除旧密钥
+type KeySet struct {
+	active *SigningKey
+	keys   []*SigningKey
+	byID   map[string]*SigningKey
+}
+
+// NewKeySet 创建密钥集合
+// 参数：
+//
+//	activeID - 用于签发令牌的密钥ID，为空时使用第一个密钥
+//	keys - 所有有效的密钥
+//
+// 返回：
+//
+//	*KeySet - 密钥集合
+//	error - 密钥ID重复、活动密钥不存在或不能签名时返回错误
+func NewKeySet(activeID string, keys ...*SigningKey) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("至少需要一个签名密钥")
+	}
+	set := &KeySet{keys: keys, byID: make(map[string]*SigningKey, len(keys))}
+	for _, key := range keys {
+		if _, ok := set.byID[key.ID]; ok {
+			return nil, fmt.Errorf("签名密钥id重复: %s", key.ID)
+		}
+		set.byID[key.ID] = key
+	}
+	if activeID == "" {
+		activeID = keys[0].ID
+	}
+	set.active = set.byID[activeID]
+	if set.active == nil {
+		return nil, fmt.Errorf("活动签名密钥不存在: %s", activeID)
+	}
+	if !set.active.CanSign() {
+		return nil, fmt.Errorf("活动签名密钥%s缺少私钥", activeID)
+	}
+	return set, nil
+}
+
+// Active 返回用于签发令牌的密钥
+func (s *KeySet) Active() *SigningKey {
+	return s.active
+}
+
+// Lookup 根据kid查找密钥，不存在时返回nil
+func (s *KeySet) Lookup(id string) *SigningKey {
+	return s.byID[id]
+}
+
+// JWK JSON Web Key（RFC 7517），只包含公钥参数
+type JWK struct {
+	Kty string `json:"kty"`           // 密钥类型：RSA或OKP
+	Kid string `json:"kid"`           // 密钥ID
+	Use string `json:"use"`           // 用途，固定为sig
+	Alg string `json:"alg"`           // 签名算法
+	N   string `json:"n,omitempty"`   // RSA模数
+	E   string `json:"e,omitempty"`   // RSA公共指数
+	Crv string `json:"crv,omitempty"` // OKP曲线，固定为Ed25519
+	X   string `json:"x,omitempty"`   // Ed25519公钥
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回集合中所有非对称密钥的公钥，HS256密钥不会公开
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: AlgRS256,
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: AlgEdDSA,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return set
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"fmt"
+	"gofile/models"
+	"log"
+	"strings"
+	"time"
+)
+
+// LockoutPolicy 登录失败的限制策略
+// 前FreeAttempts次失败不受限制；之后每次失败的等待时间从BaseDelay开始翻倍，最长MaxDelay；
+// 失败次数达到LockAfter时锁定LockDuration，并写入审计记录
+type LockoutPolicy struct {
+	FreeAttempts int           // 不受限制的失败次数
+	LockAfter    int           // 达到此失败次数时锁定，0表示不锁定
+	BaseDelay    time.Duration // 第一次退避的等待时间
+	MaxDelay     time.Duration // 退避等待时间上限
+	LockDuration time.Duration // 锁定时长
+}
+
+// delay 返回第n次失败后需要等待的时间，以及是否达到锁定
+func (p LockoutPolicy) delay(n int) (time.Duration, bool) {
+	if p.LockAfter > 0 && n >= p.LockAfter {
+		return p.LockDuration, true
+	}
+	if n <= p.FreeAttempts || p.BaseDelay <= 0 {
+		return 0, false
+	}
+	shift := n - p.FreeAttempts - 1
+	if shift > 30 {
+		shift = 30
+	}
+	d := p.BaseDelay << shift
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d, false
+}
+
+// LoginGuard 防止暴力破解登录
+// 按用户名和来源IP分别统计连续失败次数，超过阈值后指数退避直至临时锁定。
+// 用户名计数与用户是否存在无关，不会泄露用户名是否已注册
+type LoginGuard struct {
+	attempts models.LoginAttemptStore
+	audit    models.AuditStore
+	window   time.Duration
+	user     LockoutPolicy
+	ip       LockoutPolicy
+}
+
+// NewLoginGuard 创建登录保护
+// 参数：
+//
+//	attempts - 失败计数存储
+//	audit - 记录锁定事件的审计存储
+//	window - 计数窗口，距上次失败超过此时间后计数重新开始
+//	user - 按用户名计数的策略
+//	ip - 按来源IP计数的策略，通常比用户名策略宽松以免误伤共享出口IP的用户
+//
+// 返回：
+//
+//	*LoginGuard - 登录保护实例
+func NewLoginGuard(attempts models.LoginAttemptStore, audit models.AuditStore, window time.Duration, user, ip LockoutPolicy) *LoginGuard {
+	return &LoginGuard{attempts: attempts, audit: audit, window: window, user: user, ip: ip}
+}
+
+func userAttemptKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check 检查是否允许登录
+// 返回：
+//
+//	time.Duration - 需要等待的时间，为0表示允许登录
+//	error - 存储出错时返回错误
+func (g *LoginGuard) Check(username, ip string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range []string{userAttemptKey(username), ipAttemptKey(ip)} {
+		attempt, err := g.attempts.GetLoginAttempt(key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt != nil && attempt.BlockedUntil.After(now) {
+			if d := attempt.BlockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait, nil
+}
+
+// Failure 记录一次登录失败，达到阈值时设置等待时间，锁定时写入审计记录
+// 参数：
+//
+//	username - 登录时提交的用户名
+//	ip - 请求来源IP
+//	userID - 用户名对应的用户ID，用户不存在时为0
+func (g *LoginGuard) Failure(username, ip string, userID int) error {
+	now := time.Now()
+	checks := []struct {
+		key    string
+		policy LockoutPolicy
+	}{
+		{userAttemptKey(username), g.user},
+		{ipAttemptKey(ip), g.ip},
+	}
+	for _, check := range checks {
+		attempt, err := g.attempts.RecordLoginFailure(check.key, now, now.Add(-g.window))
+		if err != nil {
+			return err
+		}
+		delay, locked := check.policy.delay(attempt.Failures)
+		if delay <= 0 {
+			continue
+		}
+		if err := g.attempts.BlockLogin(check.key, now.Add(delay)); err != nil {
+			return err
+		}
+		if locked {
+			g.recordLockout(check.key, ip, userID, attempt.Failures, delay)
+		}
+	}
+	return nil
+}
+
+// Success 登录成功后清除用户名的失败计数
+// IP计数不清除，避免攻击者用自己的账号登录来重置IP计数
+func (g *LoginGuard) Success(username string) error {
+	return g.attempts.ClearLoginAttempt(userAttemptKey(username))
+}
+
+// recordLockout 写入锁定审计记录，写入失败只记录日志，不影响登录流程
+func (g *LoginGuard) recordLockout(key, ip string, userID, failures int, duration time.Duration) {
+	entry := &models.AuditLog{
+		Action: models.AuditLoginLockout,
+		Target: key,
+		IP:     ip,
+		Detail: fmt.Sprintf("连续%d次登录失败，锁定%s", failures, duration),
+	}
+	if userID != 0 && strings.HasPrefix(key, "user:") {
+		entry.UserID = &userID
+	}
+	log.Printf("登录锁定 target=%s ip=%s failures=%d duration=%s", key, ip, failures, duration)
+	if err := g.audit.CreateAuditLog(entry); err != nil {
+		log.Printf("写入审计记录失败: %v", err)
+	}
+}
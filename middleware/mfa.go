@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// mfaAudience 两步验证待定令牌的aud声明，用于和访问令牌区分
+const mfaAudience = "mfa_pending"
+
+// MFATokenTTL 两步验证待定令牌有效期
+const MFATokenTTL = 5 * time.Minute
+
+// GenerateMFAToken 生成两步验证待定令牌
+// 密码校验通过但尚未输入验证码时签发，只能用于换取访问令牌，不能访问其他接口
+func (m *TokenManager) GenerateMFAToken(userID uint, username string) (string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	return m.sign(&JWTClaims{
+		UserID:        userID,
+		Username:      username,
+		IssuedAtMicro: now.UnixMicro(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Audience:  mfaAudience,
+			ExpiresAt: now.Add(MFATokenTTL).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    "blog_system",
+		},
+	})
+}
+
+// ParseMFAToken 解析两步验证待定令牌
+// 令牌使用后应通过RevokeToken吊销，保证只能换取一次访问令牌
+func (m *TokenManager) ParseMFAToken(tokenString string) (*JWTClaims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Audience != mfaAudience {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
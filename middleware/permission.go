@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"gofile/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentRole 获取AuthMiddleware存入上下文的当前用户角色
+// 请求未经过认证时返回空角色，空角色不拥有任何权限
+func CurrentRole(c *gin.Context) models.Role {
+	value, ok := c.Get(ContextRole)
+	if !ok {
+		return ""
+	}
+	role, _ := value.(models.Role)
+	return role
+}
+
+// HasPermission 判断当前用户是否拥有指定权限
+// 使用API密钥认证时，权限还必须在密钥的Scopes中
+func HasPermission(c *gin.Context, perm models.Permission) bool {
+	if key := CurrentAPIKey(c); key != nil && !key.Allows(perm) {
+		return false
+	}
+	return CurrentRole(c).Can(perm)
+}
+
+// RequirePermission 权限校验中间件
+// 必须放在AuthMiddleware之后使用，当前用户缺少任一指定权限时返回403
+// 参数：
+//
+//	perms - 访问该路由需要同时拥有的权限
+func RequirePermission(perms ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, perm := range perms {
+			if !HasPermission(c, perm) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":       403,
+					"msg":        "权限不足",
+					"permission": perm,
+				})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"fmt"
+	"gofile/models"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey 限流计数的对象
+type RateLimitKey string
+
+// 支持的限流对象
+const (
+	RateLimitByIP     RateLimitKey = "ip"      // 按来源IP
+	RateLimitByUser   RateLimitKey = "user"    // 按登录用户，未登录时按来源IP
+	RateLimitByAPIKey RateLimitKey = "api_key" // 按API密钥，使用访问令牌时按用户，未登录时按来源IP
+)
+
+// rateLimitPurgeInterval 清理已满令牌桶的最小间隔
+const rateLimitPurgeInterval = 10 * time.Minute
+
+// RateLimitRule 一个路由组的限流规则
+// 令牌桶容量为Burst，每Period恢复Limit个令牌，每个请求消耗一个令牌
+type RateLimitRule struct {
+	Limit  int           // 每个周期恢复的令牌数
+	Period time.Duration // 恢复周期
+	Burst  int           // 令牌桶容量，即允许的突发请求数，0表示与Limit相同
+	Key    RateLimitKey  // 限流对象，为空时按来源IP
+}
+
+// interval 返回恢复一个令牌的间隔
+func (r RateLimitRule) interval() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+// RateLimiter 按路由组配置的令牌桶限流器
+type RateLimiter struct {
+	store models.RateLimitStore
+	rules map[string]RateLimitRule
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewRateLimiter 创建限流器
+// 参数：
+//
+//	store - 令牌桶存储，使用数据库存储时多个实例共享限额
+//	rules - 路由组名称到限流规则的映射，没有规则的路由组不限流
+//
+// 返回：
+//
+//	*RateLimiter - 限流器实例
+//	error - 规则无效时返回错误
+func NewRateLimiter(store models.RateLimitStore, rules map[string]RateLimitRule) (*RateLimiter, error) {
+	normalized := make(map[string]RateLimitRule, len(rules))
+	for group, rule := range rules {
+		if rule.Limit <= 0 || rule.Period <= 0 || rule.Burst < 0 {
+			return nil, fmt.Errorf("路由组%s的限流规则无效", group)
+		}
+		if rule.Burst == 0 {
+			rule.Burst = rule.Limit
+		}
+		switch rule.Key {
+		case "":
+			rule.Key = RateLimitByIP
+		case RateLimitByIP, RateLimitByUser, RateLimitByAPIKey:
+		default:
+			return nil, fmt.Errorf("路由组%s的限流对象无效: %s", group, rule.Key)
+		}
+		normalized[group] = rule
+	}
+	return &RateLimiter{store: store, rules: normalized}, nil
+}
+
+// Middleware 返回指定路由组的限流中间件
+// 响应带有RateLimit-Limit、RateLimit-Remaining和RateLimit-Reset头，超出限额时返回429和Retry-After；
+// 按用户或API密钥限流时需要放在AuthMiddleware之后。
+// 令牌桶存储出错时只记录日志并放行请求，避免存储故障导致整个服务不可用
+func (l *RateLimiter) Middleware(group string) gin.HandlerFunc {
+	var rule RateLimitRule
+	ok := false
+	if l != nil {
+		rule, ok = l.rules[group]
+	}
+	if !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+	interval := rule.interval()
+	return func(c *gin.Context) {
+		now := time.Now()
+		key := group + ":" + rateLimitSubject(c, rule.Key)
+		tat, allowed, err := l.store.TakeRateLimit(key, now, interval, rule.Burst)
+		if err != nil {
+			log.Printf("限流计数失败 key=%s: %v", key, err)
+			c.Next()
+			return
+		}
+		l.purge(now)
+
+		remaining := int(now.Add(time.Duration(rule.Burst)*interval).Sub(tat) / interval)
+		if remaining < 0 || !allowed {
+			remaining = 0
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(rule.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(tat.Sub(now))))
+		if !allowed {
+			// 再等一个间隔，令牌桶中就会恢复一个令牌
+			retryAfter := ceilSeconds(tat.Sub(now) - time.Duration(rule.Burst-1)*interval)
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code": 429,
+				"msg":  "请求过于频繁，请稍后再试",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// purge 定期删除已满的令牌桶，避免存储无限增长
+func (l *RateLimiter) purge(now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastPurge) < rateLimitPurgeInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPurge = now
+	l.mu.Unlock()
+
+	if err := l.store.PurgeRateLimits(now); err != nil {
+		log.Printf("清理限流计数失败: %v", err)
+	}
+}
+
+// rateLimitSubject 返回请求对应的限流对象标识
+func rateLimitSubject(c *gin.Context, key RateLimitKey) string {
+	if key == RateLimitByAPIKey {
+		if apiKey := CurrentAPIKey(c); apiKey != nil {
+			return "api_key:" + strconv.Itoa(apiKey.ID)
+		}
+	}
+	if key == RateLimitByUser || key == RateLimitByAPIKey {
+		if userID, ok := CurrentUserID(c); ok {
+			return "user:" + strconv.FormatUint(uint64(userID), 10)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds 将时长向上取整为秒，负数视为0
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gofile/models"
+	"time"
+)
+
+// 刷新令牌相关错误
+var (
+	// ErrRefreshTokenInvalid 刷新令牌不存在、已过期或已被吊销
+	ErrRefreshTokenInvalid = errors.New("无效的刷新令牌")
+	// ErrRefreshTokenReused 已轮换过的刷新令牌被再次使用，整个令牌家族已被吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌被重复使用")
+)
+
+// hashRefreshToken 计算刷新令牌的SHA-256哈希，服务器只保存哈希
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueRefreshToken 为用户签发新的刷新令牌
+// 参数：
+//
+//	userID - 令牌所属用户
+//	familyID - 令牌家族ID，为空时创建新的家族（新的登录会话）
+//
+// 返回：
+//
+//	string - 返回给客户端的刷新令牌原文
+//	error - 生成或保存失败时返回错误
+func (m *TokenManager) IssueRefreshToken(userID uint, familyID string) (string, error) {
+	raw, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	if familyID == "" {
+		if familyID, err = randomHex(16); err != nil {
+			return "", err
+		}
+	}
+	err = m.store.CreateRefreshToken(&models.RefreshToken{
+		UserID:    int(userID),
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(raw),
+		ExpiresAt: time.Now().Add(m.refreshTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// RotateRefreshToken 使用刷新令牌换取同一家族的新刷新令牌
+// 旧令牌被标记为已使用；如果旧令牌此前已被使用过，说明令牌可能泄露，
+// 此时吊销整个家族并返回ErrRefreshTokenReused
+// 参数：
+//
+//	raw - 客户端提交的刷新令牌原文
+//
+// 返回：
+//
+//	*models.RefreshToken - 被轮换的旧令牌记录，包含用户ID和家族ID
+//	string - 新的刷新令牌原文
+//	error - 令牌无效、被重复使用或存储出错时返回错误
+func (m *TokenManager) RotateRefreshToken(raw string) (*models.RefreshToken, string, error) {
+	token, err := m.store.GetRefreshTokenByHash(hashRefreshToken(raw))
+	if err != nil {
+		return nil, "", err
+	}
+	if token == nil || token.RevokedAt != nil {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil {
+		return nil, "", m.revokeReusedFamily(token.FamilyID, now)
+	}
+	if now.After(token.ExpiresAt) {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+
+	// 并发请求使用同一令牌时只有一个能成功标记，其余视为重复使用
+	marked, err := m.store.MarkRefreshTokenUsed(token.ID, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if !marked {
+		return nil, "", m.revokeReusedFamily(token.FamilyID, now)
+	}
+
+	next, err := m.IssueRefreshToken(uint(token.UserID), token.FamilyID)
+	if err != nil {
+		return nil, "", err
+	}
+	return token, next, nil
+}
+
+// revokeReusedFamily 吊销重复使用的令牌所在的家族，并返回ErrRefreshTokenReused
+func (m *TokenManager) revokeReusedFamily(familyID string, at time.Time) error {
+	if err := m.store.RevokeRefreshTokenFamily(familyID, at); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// RevokeRefreshToken 吊销刷新令牌所在的整个家族，用于退出当前会话
+// 令牌不存在或不属于userID时不做任何操作
+func (m *TokenManager) RevokeRefreshToken(userID uint, raw string) error {
+	token, err := m.store.GetRefreshTokenByHash(hashRefreshToken(raw))
+	if err != nil || token == nil || token.UserID != int(userID) {
+		return err
+	}
+	return m.store.RevokeRefreshTokenFamily(token.FamilyID, time.Now())
+}
+
+// RevokeAllSessions 退出用户的所有会话
+// 吊销该用户的全部刷新令牌，并使此前签发的访问令牌全部失效
+func (m *TokenManager) RevokeAllSessions(userID uint) error {
+	return m.store.RevokeAllUserTokens(userID, time.Now())
+}
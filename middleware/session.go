@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookiePolicy cookie会话策略
+// 浏览器前端登录时可以选择把令牌放在HttpOnly cookie中，前端JavaScript无法读取令牌，
+// 修改类请求由CSRF中间件校验双重提交的令牌
+type SessionCookiePolicy struct {
+	Name        string        // 保存访问令牌的cookie名称
+	RefreshName string        // 保存刷新令牌的cookie名称，只在/api/user下发送
+	Domain      string        // cookie的Domain属性，为空时只对当前主机有效
+	Secure      bool          // 只通过HTTPS发送，本地HTTP开发时可关闭
+	SameSite    http.SameSite // cookie的SameSite属性
+}
+
+// refreshCookiePath 刷新令牌cookie的路径，覆盖刷新和退出登录接口
+const refreshCookiePath = "/api/user"
+
+// ParseSameSite 解析SameSite配置，支持lax、strict和none，为空时使用lax
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("无效的SameSite配置: %s", value)
+}
+
+// UseSessionCookies 让AuthMiddleware在没有Authorization和X-API-Key请求头时接受会话cookie
+// 未调用时不支持cookie会话
+func (m *TokenManager) UseSessionCookies(policy SessionCookiePolicy) {
+	m.session = &policy
+}
+
+// SessionCookiesEnabled 判断是否支持cookie会话
+func (m *TokenManager) SessionCookiesEnabled() bool {
+	return m.session != nil
+}
+
+// CookieSecure 返回会话cookie的Secure设置，本站的其他cookie（如第三方登录状态cookie）也使用此设置
+// TLS在反向代理终止时请求本身不是HTTPS，不能根据请求判断；未启用cookie会话时返回false
+func (m *TokenManager) CookieSecure() bool {
+	return m.session != nil && m.session.Secure
+}
+
+// SetSessionCookies 把访问令牌和刷新令牌写入HttpOnly cookie
+// 参数：
+//
+//	c - Gin上下文
+//	token - 访问令牌
+//	refreshToken - 刷新令牌
+func (m *TokenManager) SetSessionCookies(c *gin.Context, token, refreshToken string) {
+	m.setSessionCookie(c, m.session.Name, token, "/", m.accessTTL)
+	m.setSessionCookie(c, m.session.RefreshName, refreshToken, refreshCookiePath, m.refreshTTL)
+}
+
+// ClearSessionCookies 删除会话cookie，未启用cookie会话或请求没有会话cookie时不做任何事
+func (m *TokenManager) ClearSessionCookies(c *gin.Context) {
+	if m.session == nil {
+		return
+	}
+	if _, err := c.Cookie(m.session.Name); err == nil {
+		m.setSessionCookie(c, m.session.Name, "", "/", -time.Second)
+	}
+	if _, err := c.Cookie(m.session.RefreshName); err == nil {
+		m.setSessionCookie(c, m.session.RefreshName, "", refreshCookiePath, -time.Second)
+	}
+}
+
+// SessionRefreshToken 读取cookie中的刷新令牌，没有时返回空字符串
+func (m *TokenManager) SessionRefreshToken(c *gin.Context) string {
+	if m.session == nil {
+		return ""
+	}
+	token, _ := c.Cookie(m.session.RefreshName)
+	return token
+}
+
+// sessionToken 读取cookie中的访问令牌，没有时返回空字符串
+func (m *TokenManager) sessionToken(c *gin.Context) string {
+	if m.session == nil {
+		return ""
+	}
+	token, _ := c.Cookie(m.session.Name)
+	return token
+}
+
+// setSessionCookie 写入一个HttpOnly会话cookie，ttl小于0时删除cookie
+func (m *TokenManager) setSessionCookie(c *gin.Context, name, value, path string, ttl time.Duration) {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	c.SetSameSite(m.session.SameSite)
+	c.SetCookie(name, value, maxAge, path, m.session.Domain, m.session.Secure, true)
+}
@@ -0,0 +1,11 @@
+// Package migrations 包含按数据库方言划分的版本化SQL迁移文件
+// 文件命名格式为 <版本号>_<名称>.up.sql 和 <版本号>_<名称>.down.sql，
+// 版本号从1开始连续递增，每个版本必须同时提供up和down文件。
+package migrations
+
+import "embed"
+
+// FS 嵌入的迁移文件，子目录名与GORM方言名称一致（postgres、sqlite）
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
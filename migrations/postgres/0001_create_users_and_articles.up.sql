@@ -0,0 +1,27 @@
+-- 初始表结构，与之前AutoMigrate生成的结构保持一致
+-- 使用IF NOT EXISTS，已由AutoMigrate建表的数据库可以直接应用此版本
+CREATE TABLE IF NOT EXISTS users (
+    id         BIGSERIAL PRIMARY KEY,
+    username   TEXT,
+    password   TEXT,
+    email      TEXT,
+    nickname   TEXT,
+    avatar     TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS articles (
+    id         BIGSERIAL PRIMARY KEY,
+    title      TEXT,
+    content    TEXT,
+    slug       TEXT,
+    category   TEXT,
+    tags       TEXT,
+    status     TEXT,
+    views      BIGINT,
+    user_id    BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    CONSTRAINT fk_articles_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
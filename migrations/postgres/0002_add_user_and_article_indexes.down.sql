@@ -0,0 +1,6 @@
+ALTER TABLE articles ALTER COLUMN views DROP NOT NULL;
+ALTER TABLE articles ALTER COLUMN views DROP DEFAULT;
+
+DROP INDEX IF EXISTS idx_articles_user_id;
+DROP INDEX IF EXISTS idx_articles_status_created_at;
+DROP INDEX IF EXISTS idx_users_username;
@@ -0,0 +1,8 @@
+-- 用户名唯一，文章列表按状态和创建时间查询
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_articles_status_created_at ON articles (status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_user_id ON articles (user_id);
+
+ALTER TABLE articles ALTER COLUMN views SET DEFAULT 0;
+UPDATE articles SET views = 0 WHERE views IS NULL;
+ALTER TABLE articles ALTER COLUMN views SET NOT NULL;
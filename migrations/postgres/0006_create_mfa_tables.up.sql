@@ -0,0 +1,18 @@
+-- TOTP两步验证设置，确认启用前enabled为false
+CREATE TABLE user_mfa (
+    user_id      BIGINT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret       TEXT NOT NULL,
+    enabled      BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step    BIGINT NOT NULL DEFAULT 0,
+    confirmed_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+-- 两步验证恢复码，只保存SHA-256哈希
+CREATE TABLE recovery_codes (
+    id        BIGSERIAL PRIMARY KEY,
+    user_id   BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at   TIMESTAMPTZ
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
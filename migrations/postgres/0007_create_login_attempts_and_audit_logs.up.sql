@@ -0,0 +1,20 @@
+-- 登录失败计数，attempt_key形如"user:<用户名>"或"ip:<地址>"
+CREATE TABLE login_attempts (
+    attempt_key     TEXT PRIMARY KEY,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    blocked_until   TIMESTAMPTZ NOT NULL
+);
+
+-- 安全审计记录
+CREATE TABLE audit_logs (
+    id         BIGSERIAL PRIMARY KEY,
+    action     TEXT NOT NULL,
+    user_id    BIGINT,
+    target     TEXT NOT NULL DEFAULT '',
+    ip         TEXT NOT NULL DEFAULT '',
+    detail     TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_audit_logs_action_created_at ON audit_logs (action, created_at DESC);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at DESC);
@@ -0,0 +1,11 @@
+-- 通过OpenID Connect登录时关联的外部身份，同一身份只能关联一个本地用户
+CREATE TABLE user_identities (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    email      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (provider, subject)
+);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
@@ -0,0 +1,6 @@
+-- 限流令牌桶，bucket_key形如"<路由组>:<ip|user|api_key>:<值>"，tat为理论到达时间（Unix微秒）
+CREATE TABLE rate_limit_buckets (
+    bucket_key TEXT PRIMARY KEY,
+    tat        BIGINT NOT NULL
+);
+CREATE INDEX idx_rate_limit_buckets_tat ON rate_limit_buckets (tat);
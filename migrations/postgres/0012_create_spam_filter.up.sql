@@ -0,0 +1,18 @@
+-- 垃圾内容屏蔽列表，kind为word（词）、ip（IP或CIDR网段）或domain（邮箱和链接域名）
+CREATE TABLE spam_blocklist (
+    id         BIGSERIAL PRIMARY KEY,
+    kind       TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_spam_blocklist_kind_value ON spam_blocklist (kind, value);
+
+-- 朴素贝叶斯分类器的词频，token为空字符串的行保存两类样本的文档总数
+CREATE TABLE spam_tokens (
+    token TEXT PRIMARY KEY,
+    spam  BIGINT NOT NULL DEFAULT 0,
+    ham   BIGINT NOT NULL DEFAULT 0
+);
+
+-- 评论已用于训练分类器的类别（spam或ham），为空表示未训练，审核状态变化时据此撤销旧的训练
+ALTER TABLE comments ADD COLUMN trained TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE articles DROP COLUMN render_version;
+ALTER TABLE articles DROP COLUMN toc;
+ALTER TABLE articles DROP COLUMN content_html;
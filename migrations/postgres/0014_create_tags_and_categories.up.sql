@@ -0,0 +1,59 @@
+-- 标签和分类，替代articles表中的tags（逗号分隔的字符串）和category（自由文本）列
+-- 分类可以有父分类；文章与标签通过article_tags多对多关联
+CREATE TABLE categories (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    slug        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    parent_id   BIGINT REFERENCES categories (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_categories_slug ON categories (slug);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE tags (
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    slug        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_tags_slug ON tags (slug);
+
+CREATE TABLE article_tags (
+    article_id BIGINT NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+    tag_id     BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (article_id, tag_id)
+);
+CREATE INDEX idx_article_tags_tag_id ON article_tags (tag_id);
+
+ALTER TABLE articles ADD COLUMN category_id BIGINT REFERENCES categories (id) ON DELETE SET NULL;
+CREATE INDEX idx_articles_category_id ON articles (category_id);
+
+-- 拆分已有的分类和标签：标签按半角或全角逗号分隔，名称去掉首尾空白，
+-- slug为小写并把空格替换为连字符，slug相同的名称合并为同一个分类或标签
+CREATE TEMPORARY TABLE article_tag_names AS
+SELECT a.id AS article_id, TRIM(t.name) AS name, LOWER(REPLACE(TRIM(t.name), ' ', '-')) AS slug
+FROM articles a, UNNEST(STRING_TO_ARRAY(REPLACE(a.tags, '，', ','), ',')) AS t (name)
+WHERE TRIM(t.name) <> '';
+
+INSERT INTO tags (name, slug, created_at, updated_at)
+SELECT MIN(name), slug, NOW(), NOW() FROM article_tag_names GROUP BY slug;
+
+INSERT INTO article_tags (article_id, tag_id)
+SELECT DISTINCT n.article_id, t.id FROM article_tag_names n JOIN tags t ON t.slug = n.slug;
+
+DROP TABLE article_tag_names;
+
+INSERT INTO categories (name, slug, created_at, updated_at)
+SELECT MIN(TRIM(category)), LOWER(REPLACE(TRIM(category), ' ', '-')), NOW(), NOW()
+FROM articles WHERE TRIM(COALESCE(category, '')) <> ''
+GROUP BY LOWER(REPLACE(TRIM(category), ' ', '-'));
+
+UPDATE articles SET category_id = c.id
+FROM categories c WHERE c.slug = LOWER(REPLACE(TRIM(articles.category), ' ', '-'));
+
+ALTER TABLE articles DROP COLUMN tags;
+ALTER TABLE articles DROP COLUMN category;
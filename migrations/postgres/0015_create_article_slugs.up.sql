@@ -0,0 +1,14 @@
+-- 文章的slug在所有文章中唯一，用于按slug访问文章
+-- 没有slug的已有文章使用article-<id>，重复的slug除最早的文章外加上-<id>后缀
+UPDATE articles SET slug = 'article-' || id WHERE slug IS NULL OR slug = '';
+UPDATE articles SET slug = slug || '-' || id
+WHERE id NOT IN (SELECT MIN(id) FROM articles GROUP BY slug);
+CREATE UNIQUE INDEX idx_articles_slug ON articles (slug);
+
+-- 文章的历史slug，文章修改slug后通过旧的slug访问时重定向到当前的slug
+CREATE TABLE article_slugs (
+    slug       TEXT PRIMARY KEY,
+    article_id BIGINT NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_article_slugs_article_id ON article_slugs (article_id);
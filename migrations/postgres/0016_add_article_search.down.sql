@@ -0,0 +1,2 @@
+DROP INDEX idx_articles_search_vector;
+ALTER TABLE articles DROP COLUMN search_vector;
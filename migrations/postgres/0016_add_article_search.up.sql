@@ -0,0 +1,4 @@
+-- 文章的全文搜索向量，由应用按search包的分词结果生成（中文按相邻两字切分），标题权重为A，正文为B
+-- 已有文章的search_vector为空，由应用在首次搜索时补全
+ALTER TABLE articles ADD COLUMN search_vector TSVECTOR;
+CREATE INDEX idx_articles_search_vector ON articles USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS articles;
+DROP TABLE IF EXISTS users;
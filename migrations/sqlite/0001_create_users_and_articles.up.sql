@@ -0,0 +1,27 @@
+-- 初始表结构，与之前AutoMigrate生成的结构保持一致
+-- 使用IF NOT EXISTS，已由AutoMigrate建表的数据库可以直接应用此版本
+CREATE TABLE IF NOT EXISTS users (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    username   TEXT,
+    password   TEXT,
+    email      TEXT,
+    nickname   TEXT,
+    avatar     TEXT,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS articles (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    title      TEXT,
+    content    TEXT,
+    slug       TEXT,
+    category   TEXT,
+    tags       TEXT,
+    status     TEXT,
+    views      INTEGER NOT NULL DEFAULT 0,
+    user_id    INTEGER,
+    created_at DATETIME,
+    updated_at DATETIME,
+    CONSTRAINT fk_articles_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_articles_user_id;
+DROP INDEX IF EXISTS idx_articles_status_created_at;
+DROP INDEX IF EXISTS idx_users_username;
@@ -0,0 +1,5 @@
+-- 用户名唯一，文章列表按状态和创建时间查询
+-- SQLite不支持修改列约束，views的默认值已在0001中定义
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_articles_status_created_at ON articles (status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_articles_user_id ON articles (user_id);
@@ -0,0 +1,6 @@
+-- 用户角色，取值与models.Role一致
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'reader' CHECK (role IN ('admin', 'editor', 'author', 'reader'));
+
+-- 回填：种子数据中的admin账号成为管理员，已发表过文章的用户成为作者
+UPDATE users SET role = 'admin' WHERE username = 'admin';
+UPDATE users SET role = 'author' WHERE role = 'reader' AND id IN (SELECT DISTINCT user_id FROM articles);
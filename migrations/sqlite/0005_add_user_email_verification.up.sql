@@ -0,0 +1,4 @@
+-- 邮箱验证状态；邮箱统一保存为小写，非空邮箱唯一
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0;
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE email <> '';
@@ -0,0 +1,18 @@
+-- TOTP两步验证设置，确认启用前enabled为false
+CREATE TABLE user_mfa (
+    user_id      INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret       TEXT NOT NULL,
+    enabled      BOOLEAN NOT NULL DEFAULT 0,
+    last_step    INTEGER NOT NULL DEFAULT 0,
+    confirmed_at DATETIME,
+    created_at   DATETIME NOT NULL
+);
+
+-- 两步验证恢复码，只保存SHA-256哈希
+CREATE TABLE recovery_codes (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id   INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at   DATETIME
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id);
@@ -0,0 +1,14 @@
+-- 个人API密钥，只保存SHA-256哈希；scopes为权限列表的JSON数组
+CREATE TABLE api_keys (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    scopes       TEXT NOT NULL,
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    created_at   DATETIME NOT NULL,
+    revoked_at   DATETIME
+);
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,18 @@
+-- 文章评论，游客评论的user_id为空；parent_id不为空时是对另一条评论的回复
+-- status为pending（待审核）、approved（已通过）或spam（垃圾评论）
+CREATE TABLE comments (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    article_id  INTEGER NOT NULL REFERENCES articles (id) ON DELETE CASCADE,
+    parent_id   INTEGER REFERENCES comments (id) ON DELETE CASCADE,
+    user_id     INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    guest_name  TEXT NOT NULL DEFAULT '',
+    guest_email TEXT NOT NULL DEFAULT '',
+    content     TEXT NOT NULL,
+    status      TEXT NOT NULL DEFAULT 'pending',
+    ip          TEXT NOT NULL DEFAULT '',
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+CREATE INDEX idx_comments_article_id_status ON comments (article_id, status, created_at);
+CREATE INDEX idx_comments_parent_id_status ON comments (parent_id, status, created_at);
+CREATE INDEX idx_comments_status_created_at ON comments (status, created_at);
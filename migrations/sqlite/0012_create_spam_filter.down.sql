@@ -0,0 +1,3 @@
+ALTER TABLE comments DROP COLUMN trained;
+DROP TABLE IF EXISTS spam_tokens;
+DROP TABLE IF EXISTS spam_blocklist;
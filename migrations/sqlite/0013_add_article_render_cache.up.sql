@@ -0,0 +1,5 @@
+-- 文章内容的渲染缓存：Markdown渲染后的HTML、由标题生成的目录（JSON）和渲染器版本
+-- render_version为0或与当前渲染器版本不同时，读取时重新渲染
+ALTER TABLE articles ADD COLUMN content_html TEXT NOT NULL DEFAULT '';
+ALTER TABLE articles ADD COLUMN toc TEXT NOT NULL DEFAULT '';
+ALTER TABLE articles ADD COLUMN render_version INTEGER NOT NULL DEFAULT 0;
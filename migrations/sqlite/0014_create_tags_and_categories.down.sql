@@ -0,0 +1,17 @@
+-- 恢复articles表中的tags和category列，标签按名称排序后用逗号连接
+ALTER TABLE articles ADD COLUMN category TEXT;
+ALTER TABLE articles ADD COLUMN tags TEXT;
+
+UPDATE articles SET category = (SELECT c.name FROM categories c WHERE c.id = articles.category_id);
+UPDATE articles SET tags = (
+    SELECT GROUP_CONCAT(name, ',') FROM (
+        SELECT t.name FROM article_tags m JOIN tags t ON t.id = m.tag_id
+        WHERE m.article_id = articles.id ORDER BY t.name
+    )
+);
+
+DROP INDEX idx_articles_category_id;
+ALTER TABLE articles DROP COLUMN category_id;
+DROP TABLE article_tags;
+DROP TABLE tags;
+DROP TABLE categories;
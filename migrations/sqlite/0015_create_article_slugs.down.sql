@@ -0,0 +1,2 @@
+DROP TABLE article_slugs;
+DROP INDEX idx_articles_slug;
@@ -0,0 +1 @@
+-- 0016在SQLite中没有修改表结构
@@ -0,0 +1,2 @@
+-- SQLite使用进程内的倒排索引搜索文章，索引在首次搜索时由文章数据建立，不需要修改表结构
+-- 保留此版本使两种数据库的迁移版本号一致
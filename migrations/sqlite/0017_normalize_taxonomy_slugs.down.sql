@@ -0,0 +1 @@
+-- slug规范化无法撤销，回滚时保留规范化后的slug
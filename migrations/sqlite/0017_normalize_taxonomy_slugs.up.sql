@@ -0,0 +1,5 @@
+-- 0014拆分标签和分类时只把名称转为小写并把空格替换为连字符，
+-- 名称中含有标点等字符时（如"Node.js"、"C++"）得到的slug与程序生成的不一致。
+-- SQL难以实现与internal/slug相同的规则，规范化由迁移程序在执行本文件后用Go代码完成：
+-- slug不合法的标签和分类改用slug.Make(名称)，已被使用时加上数字后缀（如"C++"在已有c时为c-2）；
+-- 只有名称相同（不区分大小写）的行才合并，文章关联转移到已有合法slug或先处理的一行
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey 用户创建的个人API密钥，用于脚本和CI等无法交互登录的场景
+// 服务器只保存密钥的SHA-256哈希和用于识别的前缀；密钥的权限是Scopes与用户当前角色权限的交集
+type APIKey struct {
+	ID         int          `json:"id"`                            // 密钥ID
+	UserID     int          `json:"user_id"`                       // 所属用户
+	Name       string       `json:"name"`                          // 用户填写的名称，如"release-notes-ci"
+	Prefix     string       `json:"prefix"`                        // 密钥开头的可见部分，用于在列表中识别密钥
+	KeyHash    string       `json:"-"`                             // 密钥的SHA-256哈希（十六进制）
+	Scopes     []Permission `json:"scopes" gorm:"serializer:json"` // 密钥允许使用的权限
+	ExpiresAt  *time.Time   `json:"expires_at"`                    // 过期时间，nil表示永不过期
+	LastUsedAt *time.Time   `json:"last_used_at"`                  // 最近使用时间，从未使用时为nil
+	CreatedAt  time.Time    `json:"created_at"`                    // 创建时间
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`          // 吊销时间，未吊销时为nil
+}
+
+// Allows 判断密钥的Scopes是否包含指定权限
+func (k *APIKey) Allows(perm Permission) bool {
+	for _, p := range k.Scopes {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Active 判断密钥在at时刻是否可用（未吊销且未过期）
+func (k *APIKey) Active(at time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || at.Before(*k.ExpiresAt))
+}
+
+// APIKeyStore API密钥存储接口
+type APIKeyStore interface {
+	// CreateAPIKey 保存新创建的API密钥，会自动设置ID和CreatedAt
+	CreateAPIKey(key *APIKey) error
+	// GetAPIKeyByHash 根据哈希获取API密钥，不存在时返回nil, nil
+	GetAPIKeyByHash(hash string) (*APIKey, error)
+	// GetUserAPIKeys 获取用户未吊销的API密钥，按创建时间倒序
+	GetUserAPIKeys(userID uint) ([]*APIKey, error)
+	// TouchAPIKey 更新密钥的最近使用时间
+	TouchAPIKey(id int, at time.Time) error
+	// RevokeAPIKey 吊销用户的API密钥，密钥不存在、不属于该用户或已吊销时返回false
+	RevokeAPIKey(userID uint, id int, at time.Time) (bool, error)
+}
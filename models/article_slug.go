@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// ArticleSlug 文章曾经使用过的slug
+// 文章修改slug后，通过旧的slug访问时重定向到当前的slug
+type ArticleSlug struct {
+	Slug      string    `gorm:"primaryKey"` // 旧的slug，唯一
+	ArticleID int       // 文章ID
+	CreatedAt time.Time // 停止使用的时间
+}
+
+// ArticleSlugStore 文章slug存储接口
+// 文章当前的slug保存在Article.Slug中，在所有文章中唯一；
+// UpdateArticle修改slug时旧的slug自动记入历史，删除文章时一并删除其历史slug
+type ArticleSlugStore interface {
+	// GetArticleBySlug 根据当前的slug获取文章详情，文章不存在时返回nil, nil
+	GetArticleBySlug(slug string) (*Article, error)
+	// ResolveArticleSlug 根据历史slug查找文章当前的slug，不是任何文章的历史slug时返回空字符串
+	ResolveArticleSlug(slug string) (string, error)
+	// ArticleSlugTaken 判断slug是否已被articleID以外的文章使用，包括其他文章的历史slug
+	ArticleSlugTaken(slug string, articleID int) (bool, error)
+}
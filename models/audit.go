@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// 审计事件类型
+const (
+	AuditLoginLockout = "login.lockout"  // 登录失败次数过多，账号或IP被临时锁定
+	AuditAPIKeyCreate = "api_key.create" // 用户创建API密钥
+	AuditAPIKeyRevoke = "api_key.revoke" // 用户吊销API密钥
+	AuditOIDCLink     = "oidc.link"      // 外部身份关联到本地用户（包括自动创建的新用户）
+	AuditSpamBlocked  = "spam.blocked"   // 注册请求被判定为垃圾注册而拒绝
+)
+
+// AuditLog 安全审计记录
+type AuditLog struct {
+	ID        int       `json:"id"`         // 记录ID
+	Action    string    `json:"action"`     // 事件类型，如login.lockout
+	UserID    *int      `json:"user_id"`    // 相关用户，无法确定时为nil
+	Target    string    `json:"target"`     // 事件对象，如被锁定的计数键
+	IP        string    `json:"ip"`         // 请求来源IP
+	Detail    string    `json:"detail"`     // 事件详情
+	CreatedAt time.Time `json:"created_at"` // 记录时间
+}
+
+// AuditStore 审计记录存储接口
+type AuditStore interface {
+	// CreateAuditLog 保存审计记录，会自动设置ID和CreatedAt
+	CreateAuditLog(entry *AuditLog) error
+	// GetAuditLogs 获取审计记录，按时间倒序，action为空表示不过滤
+	GetAuditLogs(limit, offset int, action string) ([]*AuditLog, error)
+}
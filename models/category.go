@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// Category 文章分类
+// 分类可以有一个父分类，父分类的文章数量和按分类过滤文章都包括所有子分类
+type Category struct {
+	ID           int       `json:"id"`                               // 分类ID
+	Name         string    `json:"name"`                             // 分类名称
+	Slug         string    `json:"slug"`                             // 分类短链接，用于URL和过滤文章，唯一
+	Description  string    `json:"description"`                      // 分类说明
+	ParentID     *int      `json:"parent_id"`                        // 父分类ID，顶层分类为空
+	CreatedAt    time.Time `json:"created_at"`                       // 创建时间
+	UpdatedAt    time.Time `json:"updated_at"`                       // 最后修改时间
+	ArticleCount int       `json:"article_count,omitempty" gorm:"-"` // 已发布的文章数量（包括子分类），只在分类列表中返回
+}
+
+// CategoryStore 分类存储接口
+type CategoryStore interface {
+	// GetCategories 获取全部分类及其已发布的文章数量，按名称排序
+	GetCategories() ([]*Category, error)
+	// GetCategoryByID 根据ID获取分类，分类不存在时返回nil, nil
+	GetCategoryByID(id uint) (*Category, error)
+	// GetCategoryBySlug 根据slug获取分类，分类不存在时返回nil, nil
+	GetCategoryBySlug(slug string) (*Category, error)
+	// CreateCategory 创建分类，会自动设置ID、CreatedAt和UpdatedAt
+	CreateCategory(category *Category) error
+	// UpdateCategory 更新分类，会自动更新UpdatedAt
+	UpdateCategory(category *Category) error
+	// DeleteCategory 删除分类，其中的文章变为未分类，子分类移到被删除分类的父分类下
+	DeleteCategory(category *Category) error
+}
+
+// CategoryDescendants 返回分类及其所有子孙分类的ID
+// 参数：
+//
+//	categories - 全部分类
+//	id - 起始分类ID
+//
+// 返回：
+//
+//	[]int - 包括id本身在内的分类ID
+func CategoryDescendants(categories []*Category, id int) []int {
+	children := make(map[int][]int, len(categories))
+	for _, c := range categories {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+	ids := []int{id}
+	seen := map[int]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// fillCategoryCounts 按每个分类直接包含的文章数量计算包括子分类在内的文章数量
+func fillCategoryCounts(categories []*Category, direct map[int]int) {
+	for _, c := range categories {
+		c.ArticleCount = 0
+		for _, id := range CategoryDescendants(categories, c.ID) {
+			c.ArticleCount += direct[id]
+		}
+	}
+}
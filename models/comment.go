@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+// Comment 文章评论
+// 登录用户发表的评论记录UserID，游客评论记录昵称和邮箱；ParentID不为空时是对另一条评论的回复
+type Comment struct {
+	ID         int        `json:"id"`                         // 评论ID
+	ArticleID  int        `json:"article_id"`                 // 所属文章ID
+	ParentID   *int       `json:"parent_id"`                  // 回复的评论ID，顶层评论为空
+	UserID     *int       `json:"user_id"`                    // 发表评论的用户ID，游客评论为空
+	GuestName  string     `json:"guest_name,omitempty"`       // 游客昵称
+	GuestEmail string     `json:"-"`                          // 游客邮箱，不对外公开
+	Content    string     `json:"content"`                    // 评论内容
+	Status     string     `json:"status"`                     // 审核状态：pending、approved或spam
+	IP         string     `json:"-"`                          // 发表评论时的来源IP
+	Trained    string     `json:"-"`                          // 已用于训练垃圾内容分类器的类别：spam、ham或空
+	CreatedAt  time.Time  `json:"created_at"`                 // 发表时间
+	UpdatedAt  time.Time  `json:"updated_at"`                 // 最后修改时间
+	User       *User      `json:"user,omitempty"`             // 发表评论的用户信息
+	ReplyCount int        `json:"reply_count" gorm:"-"`       // 已通过审核的直接回复数量
+	Replies    []*Comment `json:"replies,omitempty" gorm:"-"` // 已通过审核的直接回复，列表接口只返回第一页
+}
+
+// 评论审核状态
+const (
+	CommentStatusPending  = "pending"  // 待审核，只有审核人可见
+	CommentStatusApproved = "approved" // 已通过，所有人可见
+	CommentStatusSpam     = "spam"     // 垃圾评论
+)
+
+// ValidCommentStatus 判断评论状态是否合法
+func ValidCommentStatus(status string) bool {
+	switch status {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusSpam:
+		return true
+	}
+	return false
+}
+
+// CommentQuery 评论查询条件，零值字段表示不过滤
+type CommentQuery struct {
+	ArticleID     int    // 所属文章
+	ParentID      *int   // 回复的评论，指向0表示只查询顶层评论
+	Status        string // 审核状态
+	ArticleUserID int    // 文章作者，用于作者审核自己文章下的评论
+	Limit         int    // 每页数量，小于等于0表示不限制
+	Offset        int    // 跳过的数量
+}
+
+// CommentStore 评论存储接口
+type CommentStore interface {
+	// GetComments 按发表时间正序查询评论，附带用户信息
+	GetComments(query CommentQuery) ([]*Comment, error)
+	// GetCommentByID 根据ID获取评论，评论不存在时返回nil, nil
+	GetCommentByID(id uint) (*Comment, error)
+	// CreateComment 创建评论，会自动设置ID、CreatedAt和UpdatedAt
+	CreateComment(comment *Comment) error
+	// UpdateComment 更新评论，会自动更新UpdatedAt
+	UpdateComment(comment *Comment) error
+	// DeleteComment 删除评论及其所有回复
+	DeleteComment(comment *Comment) error
+	// CountReplies 统计每条评论指定状态的直接回复数量，没有回复的评论不出现在结果中
+	CountReplies(parentIDs []int, status string) (map[int]int, error)
+}
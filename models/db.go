@@ -5,23 +5,15 @@ import (
 	"regexp"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-// DB 全局数据库连接实例
-var DB *gorm.DB
-
-// InitDB 初始化数据库连接
-// 参数：
-//   dsn - 数据库连接字符串
-// 返回：
-//   error - 初始化过程中的错误
-func InitDB(dsn string) error {
-	// 配置GORM日志
-	newLogger := logger.New(
+// newGormLogger 创建GORM使用的日志记录器
+func newGormLogger() logger.Interface {
+	return logger.New(
 		log.New(log.Writer(), "\r\n", log.LstdFlags),
 		logger.Config{
 			SlowThreshold:             time.Second, // 慢SQL阈值
@@ -30,112 +22,60 @@ func InitDB(dsn string) error {
 			Colorful:                  true,        // 彩色打印
 		},
 	)
+}
 
-	// 尝试连接数据库
+// NewPostgresStore 创建基于PostgreSQL的存储
+// 参数：
+//
+//	dsn - PostgreSQL连接字符串，格式: host=host port=port user=username password=password dbname=dbname
+//
+// 返回：
+//
+//	*GormStore - 存储实例，数据库暂不可达时也会返回实例
+//	error - 配置无效时返回错误
+func NewPostgresStore(dsn string) (*GormStore, error) {
 	log.Printf("尝试连接数据库，DSN: %s", maskPassword(dsn))
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: newLogger,
+	return openGormStore(postgres.Open(dsn))
+}
+
+// NewSQLiteStore 创建基于SQLite的存储，适合本地开发和测试
+// 参数：
+//
+//	path - SQLite数据库文件路径，":memory:"表示使用SQLite内存库
+//
+// 返回：
+//
+//	*GormStore - 存储实例
+//	error - 打开失败时返回错误
+func NewSQLiteStore(path string) (*GormStore, error) {
+	log.Printf("打开SQLite数据库: %s", path)
+	return openGormStore(sqlite.Open(path))
+}
+
+// openGormStore 使用给定的方言打开数据库并配置连接池
+// 表结构由cmd/migrate管理，这里不再自动迁移
+func openGormStore(dialector gorm.Dialector) (*GormStore, error) {
+	// 关闭自动Ping：数据库暂时不可达时仍然返回存储实例，
+	// 后续操作返回ErrStoreUnavailable，由处理器进入降级模式
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:               newGormLogger(),
+		DisableAutomaticPing: true,
 	})
 	if err != nil {
-		log.Printf("错误: 无法连接到数据库: %v", err)
-		log.Printf("请确保PostgreSQL服务已启动，且配置信息正确")
-		log.Printf("配置信息: 主机=%s, 端口=%s, 用户名=%s, 数据库名=%s", 
-			getConfigValue("host"), getConfigValue("port"), getConfigValue("username"), getConfigValue("dbname"))
-		log.Printf("正在以模拟模式启动...")
-		// 为避免nil指针异常，创建一个内存数据库实例
-		log.Println("创建模拟数据库连接以避免空指针异常...")
-		// 即使连接失败，也返回nil错误让程序继续运行，但需要确保后续调用不会崩溃
-		return nil
+		return nil, err
 	}
 
 	// 设置连接池
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Printf("Warning: Failed to get database instance: %v. Using minimal configuration.", err)
-		return nil
+		return nil, err
 	}
-
-	// 设置连接池参数
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	// 自动迁移表结构
-	err = db.AutoMigrate(&User{}, &Article{})
-	if err != nil {
-		log.Printf("Warning: Failed to migrate database: %v", err)
-		return nil
-	}
-
-	// 保存全局DB实例
-	DB = db
-
-	// 创建测试数据
-	createTestData()
-
-	log.Println("Database initialized successfully")
-	return nil
-}
-
-// createTestData 创建测试数据
-func createTestData() {
-	// 如果DB为nil，跳过创建测试数据
-	if DB == nil {
-		log.Println("Skipping test data creation: Database not connected")
-		return
-	}
-
-	// 检查是否已有数据
-	var userCount int64
-	DB.Model(&User{}).Count(&userCount)
-	if userCount > 0 {
-		return
-	}
-
-	password := "123456"
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		log.Println("创建测试用户密码失败:", err)
-		return
-	}
-
-	// 创建测试用户
-	adminUser := &User{
-		ID:        1,
-		Username:  "admin",
-		Password:  string(hashedPassword),
-		Email:     "admin@example.com",
-		Nickname:  "管理员",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if err := DB.Create(adminUser).Error; err != nil {
-		log.Println("创建测试用户失败:", err)
-		return
-	}
-
-	// 创建测试文章
-	testArticle := &Article{
-		ID:        1,
-		Title:     "欢迎来到我的博客",
-		Content:   "这是我的个人博客，用于记录生活点滴和分享技术知识。感谢您的访问！",
-		Slug:      "welcome-to-my-blog",
-		Category:  "博客",
-		Tags:      "博客,Go,Gin",
-		Status:    "published",
-		Views:     0,
-		UserID:    adminUser.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if err := DB.Create(testArticle).Error; err != nil {
-		log.Println("创建测试文章失败:", err)
-		return
-	}
-
-	log.Println("测试数据创建成功")
+	log.Println("Database opened successfully")
+	return &GormStore{db: db, search: &gormSearch{}}, nil
 }
 
 // maskPassword 隐藏DSN中的密码部分，用于安全日志记录
@@ -144,20 +84,3 @@ func maskPassword(dsn string) string {
 	re := regexp.MustCompile(`password=([^\s]+)`)
 	return re.ReplaceAllString(dsn, `password=******`)
 }
-
-// getConfigValue 从DSN中提取配置值
-func getConfigValue(key string) string {
-	// 注意：这里需要从配置中获取值，暂时返回默认值
-	switch key {
-	case "host":
-		return "db.ngnkfioeispfjkfszdxz.supabase.co"
-	case "port":
-		return "5432"
-	case "username":
-		return "postgres"
-	case "dbname":
-		return "postgres"
-	default:
-		return ""
-	}
-}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrStoreUnavailable 表示存储后端暂时不可用（如数据库连接失败）
+// 处理器遇到此错误时进入降级模式，而不是当作普通的服务器错误
+var ErrStoreUnavailable = errors.New("存储服务不可用")
+
+// IsUnavailable 判断错误是否由存储不可用引起
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrStoreUnavailable)
+}
+
+// classifyError 将数据库连接类错误包装为ErrStoreUnavailable，其他错误原样返回
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ErrStoreUnavailable) {
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return fmt.Errorf("%w: %v", ErrStoreUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateAPIKey 保存新创建的API密钥
+func (s *GormStore) CreateAPIKey(key *APIKey) error {
+	key.CreatedAt = time.Now()
+	return classifyError(s.db.Create(key).Error)
+}
+
+// GetAPIKeyByHash 根据哈希获取API密钥
+func (s *GormStore) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	var key APIKey
+	if err := s.db.Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &key, nil
+}
+
+// GetUserAPIKeys 获取用户未吊销的API密钥
+func (s *GormStore) GetUserAPIKeys(userID uint) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC, id DESC").
+		Find(&keys).Error
+	return keys, classifyError(err)
+}
+
+// TouchAPIKey 更新密钥的最近使用时间
+func (s *GormStore) TouchAPIKey(id int, at time.Time) error {
+	return classifyError(s.db.Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error)
+}
+
+// RevokeAPIKey 吊销用户的API密钥
+func (s *GormStore) RevokeAPIKey(userID uint, id int, at time.Time) (bool, error) {
+	result := s.db.Model(&APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, classifyError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetArticleBySlug 根据当前的slug获取文章详情
+func (s *GormStore) GetArticleBySlug(slug string) (*Article, error) {
+	if slug == "" {
+		return nil, nil
+	}
+	var article Article
+	if err := preloadArticle(s.db).Where("slug = ?", slug).First(&article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	if err := s.fillCommentCounts(&article); err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
+
+// ResolveArticleSlug 根据历史slug查找文章当前的slug
+func (s *GormStore) ResolveArticleSlug(slug string) (string, error) {
+	var current []string
+	err := s.db.Model(&Article{}).
+		Joins("JOIN article_slugs ON article_slugs.article_id = articles.id").
+		Where("article_slugs.slug = ?", slug).
+		Pluck("articles.slug", &current).Error
+	if err != nil {
+		return "", classifyError(err)
+	}
+	if len(current) == 0 {
+		return "", nil
+	}
+	return current[0], nil
+}
+
+// ArticleSlugTaken 判断slug是否已被其他文章使用
+func (s *GormStore) ArticleSlugTaken(slug string, articleID int) (bool, error) {
+	var count int64
+	if err := s.db.Model(&Article{}).Where("slug = ? AND id <> ?", slug, articleID).Count(&count).Error; err != nil {
+		return false, classifyError(err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+	err := s.db.Model(&ArticleSlug{}).Where("slug = ? AND article_id <> ?", slug, articleID).Count(&count).Error
+	return count > 0, classifyError(err)
+}
+
+// saveArticleSlugHistory 文章的slug改变时把旧的slug记入历史
+// 新的slug如果是文章自己的历史slug，从历史中移除
+func saveArticleSlugHistory(tx *gorm.DB, article *Article) error {
+	var old []string
+	if err := tx.Model(&Article{}).Where("id = ?", article.ID).Pluck("slug", &old).Error; err != nil {
+		return err
+	}
+	if len(old) == 0 || old[0] == article.Slug {
+		return nil
+	}
+	if err := tx.Where("slug IN ?", []string{old[0], article.Slug}).Delete(&ArticleSlug{}).Error; err != nil {
+		return err
+	}
+	if old[0] == "" {
+		return nil
+	}
+	return tx.Create(&ArticleSlug{Slug: old[0], ArticleID: article.ID, CreatedAt: time.Now()}).Error
+}
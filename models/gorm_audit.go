@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// CreateAuditLog 保存审计记录
+func (s *GormStore) CreateAuditLog(entry *AuditLog) error {
+	entry.CreatedAt = time.Now()
+	return classifyError(s.db.Create(entry).Error)
+}
+
+// GetAuditLogs 获取审计记录，按时间倒序
+func (s *GormStore) GetAuditLogs(limit, offset int, action string) ([]*AuditLog, error) {
+	var entries []*AuditLog
+	query := s.db.Order("created_at DESC, id DESC").Limit(limit).Offset(offset)
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetCategories 获取全部分类及其已发布的文章数量
+func (s *GormStore) GetCategories() ([]*Category, error) {
+	var categories []*Category
+	if err := s.db.Order("name ASC, id ASC").Find(&categories).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	var rows []struct {
+		ID    int
+		Count int
+	}
+	err := s.db.Model(&Article{}).
+		Select("category_id AS id, COUNT(*) AS count").
+		Where("category_id IS NOT NULL AND status = ?", ArticleStatusPublished).
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	direct := make(map[int]int, len(rows))
+	for _, row := range rows {
+		direct[row.ID] = row.Count
+	}
+	fillCategoryCounts(categories, direct)
+	return categories, nil
+}
+
+// GetCategoryByID 根据ID获取分类
+func (s *GormStore) GetCategoryByID(id uint) (*Category, error) {
+	return s.findCategory("id = ?", id)
+}
+
+// GetCategoryBySlug 根据slug获取分类
+func (s *GormStore) GetCategoryBySlug(slug string) (*Category, error) {
+	return s.findCategory("slug = ?", slug)
+}
+
+// findCategory 按条件查询一个分类，不存在时返回nil, nil
+func (s *GormStore) findCategory(query string, args ...interface{}) (*Category, error) {
+	var category Category
+	if err := s.db.Where(query, args...).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &category, nil
+}
+
+// CreateCategory 创建分类
+func (s *GormStore) CreateCategory(category *Category) error {
+	category.CreatedAt = time.Now()
+	category.UpdatedAt = time.Now()
+	return classifyError(s.db.Create(category).Error)
+}
+
+// UpdateCategory 更新分类
+func (s *GormStore) UpdateCategory(category *Category) error {
+	category.UpdatedAt = time.Now()
+	return classifyError(s.db.Save(category).Error)
+}
+
+// DeleteCategory 在事务中删除分类，并把文章和子分类从该分类中移出
+func (s *GormStore) DeleteCategory(category *Category) error {
+	return classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Article{}).Where("category_id = ?", category.ID).UpdateColumn("category_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Category{}).Where("parent_id = ?", category.ID).UpdateColumn("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	}))
+}
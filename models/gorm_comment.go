@@ -0,0 +1,123 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetComments 按条件查询评论
+func (s *GormStore) GetComments(query CommentQuery) ([]*Comment, error) {
+	var comments []*Comment
+	db := s.db.Preload("User").Order("created_at ASC, id ASC")
+	if query.ArticleID != 0 {
+		db = db.Where("article_id = ?", query.ArticleID)
+	}
+	if query.ParentID != nil {
+		if *query.ParentID == 0 {
+			db = db.Where("parent_id IS NULL")
+		} else {
+			db = db.Where("parent_id = ?", *query.ParentID)
+		}
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.ArticleUserID != 0 {
+		db = db.Where("article_id IN (?)", s.db.Model(&Article{}).Select("id").Where("user_id = ?", query.ArticleUserID))
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if err := db.Offset(query.Offset).Find(&comments).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	return comments, nil
+}
+
+// GetCommentByID 根据ID获取评论
+func (s *GormStore) GetCommentByID(id uint) (*Comment, error) {
+	var comment Comment
+	if err := s.db.Preload("User").First(&comment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &comment, nil
+}
+
+// CreateComment 创建评论
+func (s *GormStore) CreateComment(comment *Comment) error {
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = time.Now()
+	return classifyError(s.db.Omit("User").Create(comment).Error)
+}
+
+// UpdateComment 更新评论
+func (s *GormStore) UpdateComment(comment *Comment) error {
+	comment.UpdatedAt = time.Now()
+	return classifyError(s.db.Omit("User").Save(comment).Error)
+}
+
+// DeleteComment 在事务中删除评论及其所有层级的回复
+func (s *GormStore) DeleteComment(comment *Comment) error {
+	return classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		ids := []int{comment.ID}
+		for frontier := ids; len(frontier) > 0; {
+			var children []int
+			if err := tx.Model(&Comment{}).Where("parent_id IN ?", frontier).Pluck("id", &children).Error; err != nil {
+				return err
+			}
+			ids = append(ids, children...)
+			frontier = children
+		}
+		return tx.Where("id IN ?", ids).Delete(&Comment{}).Error
+	}))
+}
+
+// CountReplies 统计每条评论指定状态的直接回复数量
+func (s *GormStore) CountReplies(parentIDs []int, status string) (map[int]int, error) {
+	return s.countComments("parent_id", parentIDs, status)
+}
+
+// countComments 按column分组统计指定状态的评论数量
+func (s *GormStore) countComments(column string, ids []int, status string) (map[int]int, error) {
+	counts := make(map[int]int, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		ID    int
+		Count int
+	}
+	err := s.db.Model(&Comment{}).
+		Select(column+" AS id, COUNT(*) AS count").
+		Where(column+" IN ? AND status = ?", ids, status).
+		Group(column).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
+}
+
+// fillCommentCounts 为文章填充已通过审核的评论数量
+func (s *GormStore) fillCommentCounts(articles ...*Article) error {
+	ids := make([]int, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	counts, err := s.countComments("article_id", ids, CommentStatusApproved)
+	if err != nil {
+		return err
+	}
+	for _, article := range articles {
+		article.CommentCount = counts[article.ID]
+	}
+	return nil
+}
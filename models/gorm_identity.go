@@ -0,0 +1,26 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetUserIdentity 根据身份提供方和用户标识获取外部身份
+func (s *GormStore) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	if err := s.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &identity, nil
+}
+
+// CreateUserIdentity 保存外部身份
+func (s *GormStore) CreateUserIdentity(identity *UserIdentity) error {
+	identity.CreatedAt = time.Now()
+	return classifyError(s.db.Create(identity).Error)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetLoginAttempt 获取登录失败计数
+func (s *GormStore) GetLoginAttempt(key string) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	if err := s.db.Where("attempt_key = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &attempt, nil
+}
+
+// RecordLoginFailure 原子地将失败次数加1并返回最新计数
+func (s *GormStore) RecordLoginFailure(key string, at, resetBefore time.Time) (*LoginAttempt, error) {
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "attempt_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", resetBefore),
+			"last_failure_at": at,
+		}),
+	}).Create(&LoginAttempt{Key: key, Failures: 1, LastFailureAt: at}).Error
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return s.GetLoginAttempt(key)
+}
+
+// BlockLogin 设置在until之前拒绝该键的登录
+func (s *GormStore) BlockLogin(key string, until time.Time) error {
+	return classifyError(s.db.Model(&LoginAttempt{}).Where("attempt_key = ?", key).Update("blocked_until", until).Error)
+}
+
+// ClearLoginAttempt 删除登录失败计数
+func (s *GormStore) ClearLoginAttempt(key string) error {
+	return classifyError(s.db.Where("attempt_key = ?", key).Delete(&LoginAttempt{}).Error)
+}
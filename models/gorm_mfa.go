@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetUserMFA 获取用户的两步验证设置
+func (s *GormStore) GetUserMFA(userID uint) (*UserMFA, error) {
+	var mfa UserMFA
+	if err := s.db.Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &mfa, nil
+}
+
+// SaveUserMFA 创建或覆盖用户的两步验证设置
+func (s *GormStore) SaveUserMFA(mfa *UserMFA) error {
+	if mfa.CreatedAt.IsZero() {
+		mfa.CreatedAt = time.Now()
+	}
+	return classifyError(s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret", "enabled", "last_step", "confirmed_at", "created_at"}),
+	}).Create(mfa).Error)
+}
+
+// AdvanceMFAStep 原子地推进最近使用的验证码时间步
+func (s *GormStore) AdvanceMFAStep(userID uint, step int64) (bool, error) {
+	result := s.db.Model(&UserMFA{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	if result.Error != nil {
+		return false, classifyError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes 删除用户原有的恢复码并保存新的恢复码哈希
+func (s *GormStore) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]RecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = RecoveryCode{UserID: int(userID), CodeHash: hash}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	}))
+}
+
+// UseRecoveryCode 原子地将未使用的恢复码标记为已使用
+func (s *GormStore) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	result := s.db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, classifyError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
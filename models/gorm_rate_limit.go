@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TakeRateLimit 原子地从令牌桶中取出一个令牌
+// 条件更新保证多个实例并发请求时不会超发令牌
+func (s *GormStore) TakeRateLimit(key string, at time.Time, interval time.Duration, burst int) (time.Time, bool, error) {
+	now := at.UnixMicro()
+	err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&RateLimitBucket{Key: key, TAT: now}).Error
+	if err != nil {
+		return time.Time{}, false, classifyError(err)
+	}
+	result := s.db.Model(&RateLimitBucket{}).
+		Where("bucket_key = ? AND tat <= ?", key, now+int64(burst-1)*interval.Microseconds()).
+		Update("tat", gorm.Expr("CASE WHEN tat < ? THEN ? ELSE tat END + ?", now, now, interval.Microseconds()))
+	if result.Error != nil {
+		return time.Time{}, false, classifyError(result.Error)
+	}
+	var bucket RateLimitBucket
+	if err := s.db.Where("bucket_key = ?", key).First(&bucket).Error; err != nil {
+		return time.Time{}, false, classifyError(err)
+	}
+	return time.UnixMicro(bucket.TAT), result.RowsAffected == 1, nil
+}
+
+// PurgeRateLimits 删除已满的令牌桶
+func (s *GormStore) PurgeRateLimits(before time.Time) error {
+	return classifyError(s.db.Where("tat < ?", before.UnixMicro()).Delete(&RateLimitBucket{}).Error)
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"gofile/internal/search"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// gormSearch GormStore的全文搜索状态
+// 索引在首次搜索时准备：PostgreSQL为还没有search_vector的文章补全搜索向量，
+// SQLite读取全部文章建立进程内的倒排索引，之后随文章的增删改更新
+type gormSearch struct {
+	mu    sync.Mutex
+	ready bool          // 索引是否已准备好
+	index *search.Index // SQLite使用的倒排索引，PostgreSQL为nil
+}
+
+// searchVectorExpr 由标题和正文的索引词生成tsvector，标题的权重为A，正文为B
+const searchVectorExpr = "setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'B')"
+
+// saveSearchVector 在PostgreSQL中更新文章的search_vector，其他数据库不做任何操作
+// 搜索向量由search包分词后生成，使中文也能按词匹配
+func saveSearchVector(tx *gorm.DB, article *Article) error {
+	if tx.Dialector.Name() != DriverPostgres {
+		return nil
+	}
+	title, content := searchText(article)
+	return tx.Model(&Article{}).Where("id = ?", article.ID).
+		UpdateColumn("search_vector", gorm.Expr(searchVectorExpr, search.IndexText(title), search.IndexText(content))).Error
+}
+
+// indexArticle 文章保存后更新SQLite的倒排索引，索引尚未建立时留到首次搜索时一并建立
+func (s *GormStore) indexArticle(article *Article) {
+	s.search.mu.Lock()
+	defer s.search.mu.Unlock()
+	if s.search.index != nil {
+		title, content := searchText(article)
+		s.search.index.Add(article.ID, title, content)
+	}
+}
+
+// unindexArticle 文章删除后从SQLite的倒排索引中移除
+func (s *GormStore) unindexArticle(id int) {
+	s.search.mu.Lock()
+	defer s.search.mu.Unlock()
+	if s.search.index != nil {
+		s.search.index.Remove(id)
+	}
+}
+
+// prepareSearch 准备搜索索引，只在首次搜索时读取文章数据，失败时下次搜索重试
+func (s *GormStore) prepareSearch() error {
+	s.search.mu.Lock()
+	defer s.search.mu.Unlock()
+	if s.search.ready {
+		return nil
+	}
+
+	postgres := s.Dialect() == DriverPostgres
+	var articles []*Article
+	db := s.db.Select("id", "title", "content")
+	if postgres {
+		db = db.Where("search_vector IS NULL")
+	}
+	if err := db.Find(&articles).Error; err != nil {
+		return classifyError(err)
+	}
+	if postgres {
+		for _, article := range articles {
+			if err := saveSearchVector(s.db, article); err != nil {
+				return classifyError(err)
+			}
+		}
+	} else {
+		index := search.NewIndex()
+		for _, article := range articles {
+			title, content := searchText(article)
+			index.Add(article.ID, title, content)
+		}
+		s.search.index = index
+	}
+	s.search.ready = true
+	return nil
+}
+
+// SearchArticles 搜索已发布的文章
+// PostgreSQL在数据库中匹配和排序，SQLite由倒排索引匹配后在数据库中过滤
+func (s *GormStore) SearchArticles(query SearchQuery) ([]*SearchHit, error) {
+	if len(query.Terms) == 0 {
+		return []*SearchHit{}, nil
+	}
+	if err := s.prepareSearch(); err != nil {
+		return nil, err
+	}
+
+	var hits []*SearchHit
+	var err error
+	if s.search.index == nil {
+		hits, err = s.searchVectors(query)
+	} else {
+		hits, err = s.searchIndex(query)
+	}
+	if err != nil || len(hits) == 0 {
+		return []*SearchHit{}, err
+	}
+	return s.loadSearchHits(hits)
+}
+
+// searchMatch 匹配的文章ID、相关度和创建时间
+type searchMatch struct {
+	ID        int
+	Score     float64
+	CreatedAt time.Time
+}
+
+// searchVectors 在PostgreSQL中按search_vector匹配，返回当前页的结果，结果中的文章只有ID
+func (s *GormStore) searchVectors(query SearchQuery) ([]*SearchHit, error) {
+	tsquery := gorm.Expr("plainto_tsquery('simple', ?)", strings.Join(query.Terms, " "))
+	db := s.db.Model(&Article{}).
+		Select("id, ts_rank(search_vector, ?) AS score", tsquery).
+		Where("search_vector @@ ?", tsquery)
+	db, ok, err := s.filterSearch(db, query)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	var matches []searchMatch
+	if err := db.Order("score DESC, created_at DESC, id DESC").Offset(query.Offset).Scan(&matches).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	hits := make([]*SearchHit, len(matches))
+	for i, m := range matches {
+		hits[i] = &SearchHit{Article: &Article{ID: m.ID}, Score: m.Score}
+	}
+	return hits, nil
+}
+
+// searchIndex 在倒排索引中匹配后由数据库按条件过滤，返回当前页的结果，结果中的文章只有ID和创建时间
+func (s *GormStore) searchIndex(query SearchQuery) ([]*SearchHit, error) {
+	scores := s.search.index.Search(query.Terms)
+	if len(scores) == 0 {
+		return nil, nil
+	}
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	db := s.db.Model(&Article{}).Select("id", "created_at").Where("id IN ?", ids)
+	db, ok, err := s.filterSearch(db, query)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var matches []searchMatch
+	if err := db.Scan(&matches).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	hits := make([]*SearchHit, len(matches))
+	for i, m := range matches {
+		hits[i] = &SearchHit{Article: &Article{ID: m.ID, CreatedAt: m.CreatedAt}, Score: scores[m.ID]}
+	}
+	sortSearchHits(hits)
+	return paginate(hits, query.Limit, query.Offset), nil
+}
+
+// filterSearch 按搜索条件过滤文章，分类不存在时返回false
+func (s *GormStore) filterSearch(db *gorm.DB, query SearchQuery) (*gorm.DB, bool, error) {
+	db = db.Where("status = ?", ArticleStatusPublished)
+	if query.AuthorID != 0 {
+		db = db.Where("user_id = ?", query.AuthorID)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+	return s.filterArticles(db, query.Tag, query.Category)
+}
+
+// loadSearchHits 读取搜索结果中文章的完整信息，保持结果的顺序
+func (s *GormStore) loadSearchHits(hits []*SearchHit) ([]*SearchHit, error) {
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.Article.ID
+	}
+	var articles []*Article
+	if err := preloadArticle(s.db).Where("id IN ?", ids).Find(&articles).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	if err := s.fillCommentCounts(articles...); err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+	result := make([]*SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		// 读取期间被删除的文章不返回
+		if article, ok := byID[hit.Article.ID]; ok {
+			hit.Article = article
+			result = append(result, hit)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSpamBlocklist 获取全部屏蔽列表条目
+func (s *GormStore) GetSpamBlocklist() ([]*SpamBlockEntry, error) {
+	var entries []*SpamBlockEntry
+	if err := s.db.Order("id ASC").Find(&entries).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	return entries, nil
+}
+
+// CreateSpamBlockEntry 添加屏蔽列表条目
+func (s *GormStore) CreateSpamBlockEntry(entry *SpamBlockEntry) error {
+	entry.CreatedAt = time.Now()
+	return classifyError(s.db.Create(entry).Error)
+}
+
+// DeleteSpamBlockEntry 删除屏蔽列表条目
+func (s *GormStore) DeleteSpamBlockEntry(id uint) (bool, error) {
+	result := s.db.Delete(&SpamBlockEntry{}, id)
+	if result.Error != nil {
+		return false, classifyError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetSpamTokens 获取词的计数
+func (s *GormStore) GetSpamTokens(tokens []string) (map[string]SpamToken, error) {
+	counts := make(map[string]SpamToken, len(tokens))
+	if len(tokens) == 0 {
+		return counts, nil
+	}
+	var rows []SpamToken
+	if err := s.db.Where("token IN ?", tokens).Find(&rows).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return counts, nil
+		}
+		return nil, classifyError(err)
+	}
+	for _, row := range rows {
+		counts[row.Token] = row
+	}
+	return counts, nil
+}
+
+// AddSpamTokens 原子地更新词和文档总数的计数
+func (s *GormStore) AddSpamTokens(tokens []string, spam, ham int) error {
+	rows := make([]SpamToken, 0, len(tokens)+1)
+	rows = append(rows, SpamToken{Token: "", Spam: max(spam, 0), Ham: max(ham, 0)})
+	for _, token := range tokens {
+		rows = append(rows, SpamToken{Token: token, Spam: max(spam, 0), Ham: max(ham, 0)})
+	}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "token"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"spam": gorm.Expr("CASE WHEN spam_tokens.spam + ? < 0 THEN 0 ELSE spam_tokens.spam + ? END", spam, spam),
+			"ham":  gorm.Expr("CASE WHEN spam_tokens.ham + ? < 0 THEN 0 ELSE spam_tokens.ham + ? END", ham, ham),
+		}),
+	}).CreateInBatches(rows, 500).Error
+	return classifyError(err)
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore 基于GORM的存储实现
+// PostgreSQL和SQLite共用此实现，只是打开数据库时使用的方言不同
+type GormStore struct {
+	db     *gorm.DB
+	search *gormSearch
+}
+
+// DB 返回底层的GORM连接，供迁移等需要直接访问数据库的场景使用
+func (s *GormStore) DB() *gorm.DB {
+	return s.db
+}
+
+// Dialect 返回数据库方言名称：postgres或sqlite
+func (s *GormStore) Dialect() string {
+	return s.db.Dialector.Name()
+}
+
+// Ping 检查数据库是否可达，不可达时返回ErrStoreUnavailable
+func (s *GormStore) Ping() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return classifyError(sqlDB.Ping())
+}
+
+// Close 关闭底层数据库连接
+func (s *GormStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// preloadArticle 预加载文章的作者、分类和标签
+func preloadArticle(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").Preload("Category").Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name ASC, tags.id ASC")
+	})
+}
+
+// GetArticles 按条件查询文章
+func (s *GormStore) GetArticles(query ArticleQuery) ([]*Article, error) {
+	var articles []*Article
+	db := preloadArticle(s.db).Order("created_at DESC, id DESC")
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.AuthorID != 0 {
+		db = db.Where("user_id = ?", query.AuthorID)
+	}
+	db, ok, err := s.filterArticles(db, query.Tag, query.Category)
+	if err != nil || !ok {
+		return []*Article{}, err
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+	if err := db.Offset(query.Offset).Find(&articles).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	if err := s.fillCommentCounts(articles...); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// filterArticles 按标签和分类的slug过滤文章，slug为空表示不过滤
+// 分类不存在时返回false，表示没有符合条件的文章
+func (s *GormStore) filterArticles(db *gorm.DB, tag, category string) (*gorm.DB, bool, error) {
+	if tag != "" {
+		db = db.Where("id IN (?)", s.db.Model(&ArticleTag{}).
+			Select("article_tags.article_id").
+			Joins("JOIN tags ON tags.id = article_tags.tag_id").
+			Where("tags.slug = ?", tag))
+	}
+	if category != "" {
+		var categories []*Category
+		if err := s.db.Find(&categories).Error; err != nil {
+			return nil, false, classifyError(err)
+		}
+		ids := []int{}
+		for _, c := range categories {
+			if c.Slug == category {
+				ids = CategoryDescendants(categories, c.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil, false, nil
+		}
+		db = db.Where("category_id IN ?", ids)
+	}
+	return db, true, nil
+}
+
+// GetArticleByID 根据ID获取文章详情
+func (s *GormStore) GetArticleByID(id uint) (*Article, error) {
+	var article Article
+	if err := preloadArticle(s.db).First(&article, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	if err := s.fillCommentCounts(&article); err != nil {
+		return nil, err
+	}
+	return &article, nil
+}
+
+// CreateArticle 在事务中创建新文章及其标签关联，并更新搜索索引
+func (s *GormStore) CreateArticle(article *Article) error {
+	article.CreatedAt = time.Now()
+	article.UpdatedAt = time.Now()
+	err := classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(article).Error; err != nil {
+			return err
+		}
+		if err := saveSearchVector(tx, article); err != nil {
+			return err
+		}
+		return saveArticleTags(tx, article)
+	}))
+	if err == nil {
+		s.indexArticle(article)
+	}
+	return err
+}
+
+// UpdateArticle 在事务中更新文章信息并替换其标签，slug改变时记录旧的slug，并更新搜索索引
+// 浏览量只由IncreaseArticleViews修改，不写入文章中可能已过期的值
+func (s *GormStore) UpdateArticle(article *Article) error {
+	article.UpdatedAt = time.Now()
+	article.ClearRender()
+	err := classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := saveArticleSlugHistory(tx, article); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations, "views").Save(article).Error; err != nil {
+			return err
+		}
+		if err := saveSearchVector(tx, article); err != nil {
+			return err
+		}
+		return saveArticleTags(tx, article)
+	}))
+	if err == nil {
+		s.indexArticle(article)
+	}
+	return err
+}
+
+// saveArticleTags 把文章的标签关联替换为article.Tags
+func saveArticleTags(tx *gorm.DB, article *Article) error {
+	if err := tx.Where("article_id = ?", article.ID).Delete(&ArticleTag{}).Error; err != nil {
+		return err
+	}
+	if len(article.Tags) == 0 {
+		return nil
+	}
+	rows := make([]ArticleTag, len(article.Tags))
+	for i, tag := range article.Tags {
+		rows[i] = ArticleTag{ArticleID: article.ID, TagID: tag.ID}
+	}
+	return tx.Create(&rows).Error
+}
+
+// SaveArticleRender 保存文章的内容渲染缓存
+func (s *GormStore) SaveArticleRender(article *Article) error {
+	return classifyError(s.db.Model(&Article{}).
+		Where("id = ? AND content = ?", article.ID, article.Content).
+		UpdateColumns(map[string]interface{}{
+			"content_html":   article.ContentHTML,
+			"toc":            article.TOC,
+			"render_version": article.RenderVersion,
+		}).Error)
+}
+
+// DeleteArticle 在事务中删除文章及其评论、标签关联和历史slug，并从搜索索引中移除
+func (s *GormStore) DeleteArticle(article *Article) error {
+	err := classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("article_id = ?", article.ID).Delete(&Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id = ?", article.ID).Delete(&ArticleTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id = ?", article.ID).Delete(&ArticleSlug{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(article).Error
+	}))
+	if err == nil {
+		s.unindexArticle(article.ID)
+	}
+	return err
+}
+
+// IncreaseArticleViews 原子性地将文章浏览量加1
+func (s *GormStore) IncreaseArticleViews(id uint) error {
+	return classifyError(s.db.Model(&Article{}).Where("id = ?", id).UpdateColumn("views", gorm.Expr("views + ?", 1)).Error)
+}
+
+// GetUsers 获取用户列表
+func (s *GormStore) GetUsers(limit, offset int) ([]*User, error) {
+	var users []*User
+	if err := s.db.Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	return users, nil
+}
+
+// GetUserByID 根据ID获取用户
+func (s *GormStore) GetUserByID(id uint) (*User, error) {
+	var user User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &user, nil
+}
+
+// GetUserByUsername 根据用户名获取用户
+func (s *GormStore) GetUserByUsername(username string) (*User, error) {
+	var user User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail 根据邮箱获取用户
+func (s *GormStore) GetUserByEmail(email string) (*User, error) {
+	if email == "" {
+		return nil, nil
+	}
+	var user User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &user, nil
+}
+
+// CreateUser 创建新用户
+func (s *GormStore) CreateUser(user *User) error {
+	if user.Role == "" {
+		user.Role = RoleReader
+	}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+	return classifyError(s.db.Create(user).Error)
+}
+
+// UpdateUser 更新用户信息
+func (s *GormStore) UpdateUser(user *User) error {
+	user.UpdatedAt = time.Now()
+	return classifyError(s.db.Save(user).Error)
+}
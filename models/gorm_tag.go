@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetTags 获取全部标签及其已发布的文章数量
+func (s *GormStore) GetTags() ([]*Tag, error) {
+	var tags []*Tag
+	if err := s.db.Order("name ASC, id ASC").Find(&tags).Error; err != nil {
+		return nil, classifyError(err)
+	}
+	var rows []struct {
+		ID    int
+		Count int
+	}
+	err := s.db.Model(&ArticleTag{}).
+		Select("article_tags.tag_id AS id, COUNT(*) AS count").
+		Joins("JOIN articles ON articles.id = article_tags.article_id").
+		Where("articles.status = ?", ArticleStatusPublished).
+		Group("article_tags.tag_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	for _, tag := range tags {
+		tag.ArticleCount = counts[tag.ID]
+	}
+	return tags, nil
+}
+
+// GetTagByID 根据ID获取标签
+func (s *GormStore) GetTagByID(id uint) (*Tag, error) {
+	return s.findTag("id = ?", id)
+}
+
+// GetTagBySlug 根据slug获取标签
+func (s *GormStore) GetTagBySlug(slug string) (*Tag, error) {
+	return s.findTag("slug = ?", slug)
+}
+
+// findTag 按条件查询一个标签，不存在时返回nil, nil
+func (s *GormStore) findTag(query string, args ...interface{}) (*Tag, error) {
+	var tag Tag
+	if err := s.db.Where(query, args...).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &tag, nil
+}
+
+// CreateTag 创建标签
+func (s *GormStore) CreateTag(tag *Tag) error {
+	tag.CreatedAt = time.Now()
+	tag.UpdatedAt = time.Now()
+	return classifyError(s.db.Create(tag).Error)
+}
+
+// UpdateTag 更新标签
+func (s *GormStore) UpdateTag(tag *Tag) error {
+	tag.UpdatedAt = time.Now()
+	return classifyError(s.db.Save(tag).Error)
+}
+
+// DeleteTag 在事务中删除标签及其与文章的关联
+func (s *GormStore) DeleteTag(tag *Tag) error {
+	return classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&ArticleTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	}))
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateRefreshToken 保存新签发的刷新令牌
+func (s *GormStore) CreateRefreshToken(token *RefreshToken) error {
+	token.CreatedAt = time.Now()
+	return classifyError(s.db.Create(token).Error)
+}
+
+// GetRefreshTokenByHash 根据哈希获取刷新令牌
+func (s *GormStore) GetRefreshTokenByHash(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed 原子地将未使用的刷新令牌标记为已使用
+func (s *GormStore) MarkRefreshTokenUsed(id int, at time.Time) (bool, error) {
+	result := s.db.Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, classifyError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeRefreshTokenFamily 吊销同一家族的所有刷新令牌
+func (s *GormStore) RevokeRefreshTokenFamily(familyID string, at time.Time) error {
+	return classifyError(s.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error)
+}
+
+// RevokeAccessToken 将访问令牌加入吊销列表，并清理已过期的吊销记录
+func (s *GormStore) RevokeAccessToken(token *RevokedToken) error {
+	return classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+	}))
+}
+
+// IsAccessTokenRevoked 判断访问令牌是否已失效
+func (s *GormStore) IsAccessTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	var count int64
+	if err := s.db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, classifyError(err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := s.db.Model(&SessionRevocation{}).
+		Where("user_id = ? AND revoked_before >= ?", userID, issuedAt).
+		Count(&count).Error; err != nil {
+		return false, classifyError(err)
+	}
+	return count > 0, nil
+}
+
+// RevokeAllUserTokens 吊销用户所有的刷新令牌，并使at及之前签发的访问令牌失效
+func (s *GormStore) RevokeAllUserTokens(userID uint, at time.Time) error {
+	return classifyError(s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"revoked_before"}),
+		}).Create(&SessionRevocation{UserID: int(userID), RevokedBefore: at}).Error
+	}))
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity 用户关联的外部身份
+// 通过OpenID Connect登录时，按(Provider, Subject)找到对应的本地用户
+type UserIdentity struct {
+	ID        int       `json:"id"`         // 记录ID
+	UserID    int       `json:"user_id"`    // 关联的本地用户
+	Provider  string    `json:"provider"`   // 身份提供方名称，对应配置中的oidc.providers[].name
+	Subject   string    `json:"subject"`    // 身份提供方中的用户标识（sub声明）
+	Email     string    `json:"email"`      // 关联时身份提供方返回的邮箱
+	CreatedAt time.Time `json:"created_at"` // 关联时间
+}
+
+// IdentityStore 外部身份存储接口
+type IdentityStore interface {
+	// GetUserIdentity 根据身份提供方和用户标识获取外部身份，不存在时返回nil, nil
+	GetUserIdentity(provider, subject string) (*UserIdentity, error)
+	// CreateUserIdentity 保存外部身份，会自动设置ID和CreatedAt
+	CreateUserIdentity(identity *UserIdentity) error
+}
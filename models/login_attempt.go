@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt 登录失败计数
+// Key区分计数对象，如"user:admin"按用户名计数，"ip:127.0.0.1"按来源IP计数
+type LoginAttempt struct {
+	Key           string    `json:"key" gorm:"column:attempt_key;primaryKey"` // 计数键
+	Failures      int       `json:"failures"`                                 // 计数窗口内连续失败的次数
+	LastFailureAt time.Time `json:"last_failure_at"`                          // 最近一次失败的时间
+	BlockedUntil  time.Time `json:"blocked_until"`                            // 在此时间之前拒绝登录，零值表示未限制
+}
+
+// LoginAttemptStore 登录失败计数存储接口
+// 数据库实现可以在多个实例之间共享计数，内存实现只在单个进程内有效
+type LoginAttemptStore interface {
+	// GetLoginAttempt 获取计数，不存在时返回nil, nil
+	GetLoginAttempt(key string) (*LoginAttempt, error)
+	// RecordLoginFailure 原子地将失败次数加1并返回最新计数
+	// 上次失败早于resetBefore时，计数从1重新开始
+	RecordLoginFailure(key string, at, resetBefore time.Time) (*LoginAttempt, error)
+	// BlockLogin 设置在until之前拒绝该键的登录
+	BlockLogin(key string, until time.Time) error
+	// ClearLoginAttempt 删除计数，用于登录成功后
+	ClearLoginAttempt(key string) error
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// memoryAPIKeys 内存存储中的API密钥数据，由MemoryStore.mu保护
+type memoryAPIKeys struct {
+	keys   map[int]*APIKey
+	nextID int
+}
+
+// newMemoryAPIKeys 创建空的API密钥数据
+func newMemoryAPIKeys() *memoryAPIKeys {
+	return &memoryAPIKeys{
+		keys:   make(map[int]*APIKey),
+		nextID: 1,
+	}
+}
+
+// copyAPIKey 复制密钥，避免调用方修改内部数据
+func copyAPIKey(key *APIKey) *APIKey {
+	k := *key
+	k.Scopes = append([]Permission(nil), key.Scopes...)
+	return &k
+}
+
+// CreateAPIKey 保存新创建的API密钥
+func (s *MemoryStore) CreateAPIKey(key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.apiKeys
+	key.ID = data.nextID
+	data.nextID++
+	key.CreatedAt = time.Now()
+	data.keys[key.ID] = copyAPIKey(key)
+	return nil
+}
+
+// GetAPIKeyByHash 根据哈希获取API密钥
+func (s *MemoryStore) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.apiKeys.keys {
+		if key.KeyHash == hash {
+			return copyAPIKey(key), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUserAPIKeys 获取用户未吊销的API密钥
+func (s *MemoryStore) GetUserAPIKeys(userID uint) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*APIKey, 0)
+	for _, key := range s.apiKeys.keys {
+		if key.UserID == int(userID) && key.RevokedAt == nil {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID > keys[j].ID
+	})
+	return keys, nil
+}
+
+// TouchAPIKey 更新密钥的最近使用时间
+func (s *MemoryStore) TouchAPIKey(id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.apiKeys.keys[id]; ok {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
+
+// RevokeAPIKey 吊销用户的API密钥
+func (s *MemoryStore) RevokeAPIKey(userID uint, id int, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.apiKeys.keys[id]
+	if !ok || key.UserID != int(userID) || key.RevokedAt != nil {
+		return false, nil
+	}
+	key.RevokedAt = &at
+	return true, nil
+}
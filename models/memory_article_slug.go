@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+)
+
+// GetArticleBySlug 根据当前的slug获取文章详情
+func (s *MemoryStore) GetArticleBySlug(slug string) (*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if slug == "" {
+		return nil, nil
+	}
+	for _, article := range s.articles {
+		if article.Slug == slug {
+			return s.copyArticle(article), nil
+		}
+	}
+	return nil, nil
+}
+
+// ResolveArticleSlug 根据历史slug查找文章当前的slug
+func (s *MemoryStore) ResolveArticleSlug(slug string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if article, ok := s.articles[s.slugHistory[slug]]; ok {
+		return article.Slug, nil
+	}
+	return "", nil
+}
+
+// ArticleSlugTaken 判断slug是否已被其他文章使用
+func (s *MemoryStore) ArticleSlugTaken(slug string, articleID int) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.articleSlugTaken(slug, articleID), nil
+}
+
+// articleSlugTaken 判断slug是否已被articleID以外的文章使用
+// 调用方需持有读锁
+func (s *MemoryStore) articleSlugTaken(slug string, articleID int) bool {
+	for _, article := range s.articles {
+		if article.ID != articleID && article.Slug == slug {
+			return true
+		}
+	}
+	id, ok := s.slugHistory[slug]
+	return ok && id != articleID
+}
+
+// checkArticleSlug 模拟数据库的唯一索引，slug与其他文章的当前slug重复时返回错误
+// 调用方需持有读锁
+func (s *MemoryStore) checkArticleSlug(article *Article) error {
+	if article.Slug == "" {
+		return nil
+	}
+	for _, other := range s.articles {
+		if other.ID != article.ID && other.Slug == article.Slug {
+			return fmt.Errorf("article slug %q already exists", article.Slug)
+		}
+	}
+	return nil
+}
+
+// saveArticleSlugHistory 文章的slug改变时把旧的slug记入历史
+// 新的slug如果是文章自己的历史slug，从历史中移除
+// 调用方需持有写锁
+func (s *MemoryStore) saveArticleSlugHistory(article *Article) {
+	stored, ok := s.articles[article.ID]
+	if !ok || stored.Slug == article.Slug {
+		return
+	}
+	delete(s.slugHistory, article.Slug)
+	if stored.Slug != "" {
+		s.slugHistory[stored.Slug] = article.ID
+	}
+}
+
+// deleteArticleSlugHistory 删除文章的所有历史slug
+// 调用方需持有写锁
+func (s *MemoryStore) deleteArticleSlugHistory(articleID int) {
+	for slug, id := range s.slugHistory {
+		if id == articleID {
+			delete(s.slugHistory, slug)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// memoryAudit 内存存储中的审计记录，由MemoryStore.mu保护
+type memoryAudit struct {
+	entries []*AuditLog
+	nextID  int
+}
+
+// newMemoryAudit 创建空的审计记录
+func newMemoryAudit() *memoryAudit {
+	return &memoryAudit{nextID: 1}
+}
+
+// CreateAuditLog 保存审计记录
+func (s *MemoryStore) CreateAuditLog(entry *AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = s.audit.nextID
+	s.audit.nextID++
+	entry.CreatedAt = time.Now()
+	stored := *entry
+	s.audit.entries = append(s.audit.entries, &stored)
+	return nil
+}
+
+// GetAuditLogs 获取审计记录，按时间倒序
+func (s *MemoryStore) GetAuditLogs(limit, offset int, action string) ([]*AuditLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*AuditLog, 0, len(s.audit.entries))
+	for i := len(s.audit.entries) - 1; i >= 0; i-- {
+		entry := s.audit.entries[i]
+		if action == "" || entry.Action == action {
+			e := *entry
+			result = append(result, &e)
+		}
+	}
+	return paginate(result, limit, offset), nil
+}
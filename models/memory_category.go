@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// memoryCategories 内存存储中的分类数据，由MemoryStore.mu保护
+type memoryCategories struct {
+	categories map[int]*Category
+	nextID     int
+}
+
+// newMemoryCategories 创建空的分类数据
+func newMemoryCategories() *memoryCategories {
+	return &memoryCategories{
+		categories: make(map[int]*Category),
+		nextID:     1,
+	}
+}
+
+// allCategories 返回全部分类的副本
+// 调用方需持有读锁
+func (s *MemoryStore) allCategories() []*Category {
+	categories := make([]*Category, 0, len(s.categories.categories))
+	for _, category := range s.categories.categories {
+		c := *category
+		categories = append(categories, &c)
+	}
+	return categories
+}
+
+// GetCategories 获取全部分类及其已发布的文章数量
+func (s *MemoryStore) GetCategories() ([]*Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	direct := make(map[int]int)
+	for _, article := range s.articles {
+		if article.CategoryID != nil && article.Status == ArticleStatusPublished {
+			direct[*article.CategoryID]++
+		}
+	}
+	categories := s.allCategories()
+	fillCategoryCounts(categories, direct)
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Name == categories[j].Name {
+			return categories[i].ID < categories[j].ID
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+// GetCategoryByID 根据ID获取分类
+func (s *MemoryStore) GetCategoryByID(id uint) (*Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	category, ok := s.categories.categories[int(id)]
+	if !ok {
+		return nil, nil
+	}
+	c := *category
+	return &c, nil
+}
+
+// GetCategoryBySlug 根据slug获取分类
+func (s *MemoryStore) GetCategoryBySlug(slug string) (*Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, category := range s.categories.categories {
+		if category.Slug == slug {
+			c := *category
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateCategory 创建分类
+func (s *MemoryStore) CreateCategory(category *Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCategorySlug(category); err != nil {
+		return err
+	}
+	category.ID = s.categories.nextID
+	s.categories.nextID++
+	category.CreatedAt = time.Now()
+	category.UpdatedAt = time.Now()
+	c := *category
+	s.categories.categories[category.ID] = &c
+	return nil
+}
+
+// UpdateCategory 更新分类
+func (s *MemoryStore) UpdateCategory(category *Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCategorySlug(category); err != nil {
+		return err
+	}
+	category.UpdatedAt = time.Now()
+	c := *category
+	s.categories.categories[category.ID] = &c
+	return nil
+}
+
+// checkCategorySlug 检查slug是否已被其他分类使用，与数据库的唯一约束一致
+// 调用方需持有读锁
+func (s *MemoryStore) checkCategorySlug(category *Category) error {
+	for _, existing := range s.categories.categories {
+		if existing.Slug == category.Slug && existing.ID != category.ID {
+			return fmt.Errorf("category slug %q already exists", category.Slug)
+		}
+	}
+	return nil
+}
+
+// DeleteCategory 删除分类，并把文章和子分类从该分类中移出
+func (s *MemoryStore) DeleteCategory(category *Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.categories.categories, category.ID)
+	for _, article := range s.articles {
+		if article.CategoryID != nil && *article.CategoryID == category.ID {
+			article.CategoryID = nil
+		}
+	}
+	for _, child := range s.categories.categories {
+		if child.ParentID != nil && *child.ParentID == category.ID {
+			child.ParentID = nil
+			if category.ParentID != nil {
+				parentID := *category.ParentID
+				child.ParentID = &parentID
+			}
+		}
+	}
+	return nil
+}
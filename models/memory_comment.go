@@ -0,0 +1,180 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// memoryComments 内存存储中的评论数据，由MemoryStore.mu保护
+type memoryComments struct {
+	comments map[int]*Comment
+	nextID   int
+}
+
+// newMemoryComments 创建空的评论数据
+func newMemoryComments() *memoryComments {
+	return &memoryComments{
+		comments: make(map[int]*Comment),
+		nextID:   1,
+	}
+}
+
+// copyComment 复制评论并附带用户信息，避免调用方修改内部数据
+// 调用方需持有读锁
+func (s *MemoryStore) copyComment(comment *Comment) *Comment {
+	c := *comment
+	c.User = nil
+	if c.UserID != nil {
+		if user, ok := s.users[*c.UserID]; ok {
+			u := *user
+			c.User = &u
+		}
+	}
+	return &c
+}
+
+// GetComments 按条件查询评论
+func (s *MemoryStore) GetComments(query CommentQuery) ([]*Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := make([]*Comment, 0)
+	for _, comment := range s.comments.comments {
+		if query.ArticleID != 0 && comment.ArticleID != query.ArticleID {
+			continue
+		}
+		if query.ParentID != nil && !sameParent(comment.ParentID, *query.ParentID) {
+			continue
+		}
+		if query.Status != "" && comment.Status != query.Status {
+			continue
+		}
+		if query.ArticleUserID != 0 {
+			article, ok := s.articles[comment.ArticleID]
+			if !ok || article.UserID != query.ArticleUserID {
+				continue
+			}
+		}
+		comments = append(comments, s.copyComment(comment))
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].ID < comments[j].ID
+		}
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+	return paginate(comments, query.Limit, query.Offset), nil
+}
+
+// sameParent 判断评论是否回复parentID，parentID为0表示顶层评论
+func sameParent(parent *int, parentID int) bool {
+	if parent == nil {
+		return parentID == 0
+	}
+	return *parent == parentID
+}
+
+// GetCommentByID 根据ID获取评论
+func (s *MemoryStore) GetCommentByID(id uint) (*Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments.comments[int(id)]
+	if !ok {
+		return nil, nil
+	}
+	return s.copyComment(comment), nil
+}
+
+// CreateComment 创建评论
+func (s *MemoryStore) CreateComment(comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.comments
+	comment.ID = data.nextID
+	data.nextID++
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = time.Now()
+	stored := *comment
+	stored.User = nil
+	stored.Replies = nil
+	data.comments[comment.ID] = &stored
+	return nil
+}
+
+// UpdateComment 更新评论
+func (s *MemoryStore) UpdateComment(comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment.UpdatedAt = time.Now()
+	stored := *comment
+	stored.User = nil
+	stored.Replies = nil
+	s.comments.comments[comment.ID] = &stored
+	return nil
+}
+
+// DeleteComment 删除评论及其所有层级的回复
+func (s *MemoryStore) DeleteComment(comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteComments(func(c *Comment) bool { return c.ID == comment.ID })
+	return nil
+}
+
+// deleteComments 删除满足条件的评论及其所有层级的回复
+// 调用方需持有写锁
+func (s *MemoryStore) deleteComments(match func(*Comment) bool) {
+	deleted := make(map[int]bool)
+	for id, comment := range s.comments.comments {
+		if match(comment) {
+			deleted[id] = true
+		}
+	}
+	// 逐层删除回复，直到没有新的评论被删除
+	for len(deleted) > 0 {
+		for id := range deleted {
+			delete(s.comments.comments, id)
+		}
+		next := make(map[int]bool)
+		for id, comment := range s.comments.comments {
+			if comment.ParentID != nil && deleted[*comment.ParentID] {
+				next[id] = true
+			}
+		}
+		deleted = next
+	}
+}
+
+// CountReplies 统计每条评论指定状态的直接回复数量
+func (s *MemoryStore) CountReplies(parentIDs []int, status string) (map[int]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[int]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		wanted[id] = true
+	}
+	counts := make(map[int]int)
+	for _, comment := range s.comments.comments {
+		if comment.ParentID != nil && wanted[*comment.ParentID] && comment.Status == status {
+			counts[*comment.ParentID]++
+		}
+	}
+	return counts, nil
+}
+
+// approvedCommentCount 统计文章已通过审核的评论数量
+// 调用方需持有读锁
+func (s *MemoryStore) approvedCommentCount(articleID int) int {
+	count := 0
+	for _, comment := range s.comments.comments {
+		if comment.ArticleID == articleID && comment.Status == CommentStatusApproved {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// memoryIdentities 内存存储中的外部身份数据，由MemoryStore.mu保护
+type memoryIdentities struct {
+	identities map[string]*UserIdentity // 键为provider + "\x00" + subject
+	nextID     int
+}
+
+// newMemoryIdentities 创建空的外部身份数据
+func newMemoryIdentities() *memoryIdentities {
+	return &memoryIdentities{
+		identities: make(map[string]*UserIdentity),
+		nextID:     1,
+	}
+}
+
+// identityKey 外部身份在内存中的键
+func identityKey(provider, subject string) string {
+	return provider + "\x00" + subject
+}
+
+// GetUserIdentity 根据身份提供方和用户标识获取外部身份
+func (s *MemoryStore) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identity, ok := s.identities.identities[identityKey(provider, subject)]
+	if !ok {
+		return nil, nil
+	}
+	i := *identity
+	return &i, nil
+}
+
+// CreateUserIdentity 保存外部身份，同一身份已关联时返回错误
+func (s *MemoryStore) CreateUserIdentity(identity *UserIdentity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.identities
+	key := identityKey(identity.Provider, identity.Subject)
+	if _, ok := data.identities[key]; ok {
+		return fmt.Errorf("identity %s/%s already linked", identity.Provider, identity.Subject)
+	}
+	identity.ID = data.nextID
+	data.nextID++
+	identity.CreatedAt = time.Now()
+	stored := *identity
+	data.identities[key] = &stored
+	return nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryLoginAttempts 基于内存的登录失败计数存储
+// 可以单独使用，在使用数据库存储时也把计数保存在进程内，计数在重启后清空
+type MemoryLoginAttempts struct {
+	mu       sync.Mutex
+	attempts map[string]*LoginAttempt
+}
+
+// NewMemoryLoginAttempts 创建空的内存计数存储
+func NewMemoryLoginAttempts() *MemoryLoginAttempts {
+	return &MemoryLoginAttempts{attempts: make(map[string]*LoginAttempt)}
+}
+
+// GetLoginAttempt 获取登录失败计数
+func (s *MemoryLoginAttempts) GetLoginAttempt(key string) (*LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	a := *attempt
+	return &a, nil
+}
+
+// RecordLoginFailure 原子地将失败次数加1并返回最新计数
+func (s *MemoryLoginAttempts) RecordLoginFailure(key string, at, resetBefore time.Time) (*LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &LoginAttempt{Key: key}
+		s.attempts[key] = attempt
+	}
+	if attempt.LastFailureAt.Before(resetBefore) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	a := *attempt
+	return &a, nil
+}
+
+// BlockLogin 设置在until之前拒绝该键的登录
+func (s *MemoryLoginAttempts) BlockLogin(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.BlockedUntil = until
+	}
+	return nil
+}
+
+// ClearLoginAttempt 删除登录失败计数
+func (s *MemoryLoginAttempts) ClearLoginAttempt(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
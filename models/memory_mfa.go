@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// memoryMFA 内存存储中的两步验证数据，由MemoryStore.mu保护
+type memoryMFA struct {
+	settings map[int]*UserMFA
+	recovery map[int][]*RecoveryCode
+}
+
+// newMemoryMFA 创建空的两步验证数据
+func newMemoryMFA() *memoryMFA {
+	return &memoryMFA{
+		settings: make(map[int]*UserMFA),
+		recovery: make(map[int][]*RecoveryCode),
+	}
+}
+
+// GetUserMFA 获取用户的两步验证设置
+func (s *MemoryStore) GetUserMFA(userID uint) (*UserMFA, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mfa, ok := s.mfa.settings[int(userID)]
+	if !ok {
+		return nil, nil
+	}
+	m := *mfa
+	return &m, nil
+}
+
+// SaveUserMFA 创建或覆盖用户的两步验证设置
+func (s *MemoryStore) SaveUserMFA(mfa *UserMFA) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if mfa.CreatedAt.IsZero() {
+		mfa.CreatedAt = time.Now()
+	}
+	stored := *mfa
+	s.mfa.settings[mfa.UserID] = &stored
+	return nil
+}
+
+// AdvanceMFAStep 原子地推进最近使用的验证码时间步
+func (s *MemoryStore) AdvanceMFAStep(userID uint, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mfa, ok := s.mfa.settings[int(userID)]
+	if !ok || mfa.LastStep >= step {
+		return false, nil
+	}
+	mfa.LastStep = step
+	return true, nil
+}
+
+// ReplaceRecoveryCodes 删除用户原有的恢复码并保存新的恢复码哈希
+func (s *MemoryStore) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make([]*RecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = &RecoveryCode{ID: i + 1, UserID: int(userID), CodeHash: hash}
+	}
+	s.mfa.recovery[int(userID)] = codes
+	return nil
+}
+
+// UseRecoveryCode 原子地将未使用的恢复码标记为已使用
+func (s *MemoryStore) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range s.mfa.recovery[int(userID)] {
+		if code.CodeHash == hash && code.UsedAt == nil {
+			code.UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryRateLimits 基于内存的限流令牌桶存储
+// 可以单独使用，在使用数据库存储时也把令牌桶保存在进程内，令牌桶在重启后清空
+type MemoryRateLimits struct {
+	mu      sync.Mutex
+	buckets map[string]int64
+}
+
+// NewMemoryRateLimits 创建空的内存令牌桶存储
+func NewMemoryRateLimits() *MemoryRateLimits {
+	return &MemoryRateLimits{buckets: make(map[string]int64)}
+}
+
+// TakeRateLimit 原子地从令牌桶中取出一个令牌
+func (s *MemoryRateLimits) TakeRateLimit(key string, at time.Time, interval time.Duration, burst int) (time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := at.UnixMicro()
+	tat, ok := s.buckets[key]
+	if !ok || tat < now {
+		tat = now
+	}
+	if !rateLimitAllows(tat, now, interval, burst) {
+		return time.UnixMicro(tat), false, nil
+	}
+	tat += interval.Microseconds()
+	s.buckets[key] = tat
+	return time.UnixMicro(tat), true, nil
+}
+
+// PurgeRateLimits 删除已满的令牌桶
+func (s *MemoryRateLimits) PurgeRateLimits(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := before.UnixMicro()
+	for key, tat := range s.buckets {
+		if tat < limit {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+// SearchArticles 在倒排索引中搜索已发布的文章
+func (s *MemoryStore) SearchArticles(query SearchQuery) ([]*SearchHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	categoryIDs := s.categoryFilter(query.Category)
+	hits := []*SearchHit{}
+	for id, score := range s.search.Search(query.Terms) {
+		article, ok := s.articles[id]
+		if !ok || !matchSearchQuery(article, query) || !inCategories(article, categoryIDs) {
+			continue
+		}
+		a := s.copyArticle(article)
+		if query.Tag != "" && !hasTag(a.Tags, query.Tag) {
+			continue
+		}
+		hits = append(hits, &SearchHit{Article: a, Score: score})
+	}
+	sortSearchHits(hits)
+	return paginate(hits, query.Limit, query.Offset), nil
+}
+
+// matchSearchQuery 判断文章是否满足搜索的状态、作者和时间条件，标签和分类由调用方判断
+func matchSearchQuery(article *Article, query SearchQuery) bool {
+	if article.Status != ArticleStatusPublished {
+		return false
+	}
+	if query.AuthorID != 0 && article.UserID != query.AuthorID {
+		return false
+	}
+	if !query.From.IsZero() && article.CreatedAt.Before(query.From) {
+		return false
+	}
+	return query.To.IsZero() || article.CreatedAt.Before(query.To)
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// memorySpam 内存存储中的垃圾内容过滤数据，由MemoryStore.mu保护
+type memorySpam struct {
+	blocklist map[int]*SpamBlockEntry
+	tokens    map[string]*SpamToken
+	nextID    int
+}
+
+// newMemorySpam 创建空的垃圾内容过滤数据
+func newMemorySpam() *memorySpam {
+	return &memorySpam{
+		blocklist: make(map[int]*SpamBlockEntry),
+		tokens:    make(map[string]*SpamToken),
+		nextID:    1,
+	}
+}
+
+// GetSpamBlocklist 获取全部屏蔽列表条目
+func (s *MemoryStore) GetSpamBlocklist() ([]*SpamBlockEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]*SpamBlockEntry, 0, len(s.spam.blocklist))
+	for _, entry := range s.spam.blocklist {
+		e := *entry
+		entries = append(entries, &e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// CreateSpamBlockEntry 添加屏蔽列表条目
+func (s *MemoryStore) CreateSpamBlockEntry(entry *SpamBlockEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.spam
+	for _, existing := range data.blocklist {
+		if existing.Kind == entry.Kind && existing.Value == entry.Value {
+			return fmt.Errorf("blocklist entry %s/%s already exists", entry.Kind, entry.Value)
+		}
+	}
+	entry.ID = data.nextID
+	data.nextID++
+	entry.CreatedAt = time.Now()
+	e := *entry
+	data.blocklist[entry.ID] = &e
+	return nil
+}
+
+// DeleteSpamBlockEntry 删除屏蔽列表条目
+func (s *MemoryStore) DeleteSpamBlockEntry(id uint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.spam.blocklist[int(id)]; !ok {
+		return false, nil
+	}
+	delete(s.spam.blocklist, int(id))
+	return true, nil
+}
+
+// GetSpamTokens 获取词的计数
+func (s *MemoryStore) GetSpamTokens(tokens []string) (map[string]SpamToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]SpamToken, len(tokens))
+	for _, token := range tokens {
+		if count, ok := s.spam.tokens[token]; ok {
+			counts[token] = *count
+		}
+	}
+	return counts, nil
+}
+
+// AddSpamTokens 原子地更新词和文档总数的计数
+func (s *MemoryStore) AddSpamTokens(tokens []string, spam, ham int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range append([]string{""}, tokens...) {
+		count, ok := s.spam.tokens[token]
+		if !ok {
+			count = &SpamToken{Token: token}
+			s.spam.tokens[token] = count
+		}
+		count.Spam = max(count.Spam+spam, 0)
+		count.Ham = max(count.Ham+ham, 0)
+	}
+	return nil
+}
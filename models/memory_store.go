@@ -0,0 +1,356 @@
+package models
+
+import (
+	"gofile/internal/search"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore 纯内存的存储实现
+// 不依赖任何外部数据库，适合本地运行和测试，进程退出后数据丢失
+type MemoryStore struct {
+	mu            sync.RWMutex
+	articles      map[int]*Article
+	users         map[int]*User
+	nextArticleID int
+	nextUserID    int
+	slugHistory   map[string]int // 文章的历史slug到文章ID
+	tags          *memoryTags
+	categories    *memoryCategories
+	comments      *memoryComments
+	tokens        *memoryTokens
+	mfa           *memoryMFA
+	audit         *memoryAudit
+	apiKeys       *memoryAPIKeys
+	identities    *memoryIdentities
+	spam          *memorySpam
+	search        *search.Index // 文章的全文搜索索引
+	// 登录失败计数使用独立的锁，也可以脱离MemoryStore单独使用
+	*MemoryLoginAttempts
+	// 限流令牌桶同样使用独立的锁
+	*MemoryRateLimits
+}
+
+// NewMemoryStore 创建一个空的内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		articles:      make(map[int]*Article),
+		users:         make(map[int]*User),
+		nextArticleID: 1,
+		nextUserID:    1,
+		slugHistory:   make(map[string]int),
+		tags:          newMemoryTags(),
+		categories:    newMemoryCategories(),
+		comments:      newMemoryComments(),
+		tokens:        newMemoryTokens(),
+		mfa:           newMemoryMFA(),
+		audit:         newMemoryAudit(),
+		apiKeys:       newMemoryAPIKeys(),
+		identities:    newMemoryIdentities(),
+		spam:          newMemorySpam(),
+		search:        search.NewIndex(),
+
+		MemoryLoginAttempts: NewMemoryLoginAttempts(),
+		MemoryRateLimits:    NewMemoryRateLimits(),
+	}
+}
+
+// Close 内存存储无需释放资源
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// copyArticle 复制文章并附带作者、分类、标签信息和评论数量，避免调用方修改内部数据
+// 调用方需持有读锁
+func (s *MemoryStore) copyArticle(article *Article) *Article {
+	a := *article
+	a.CommentCount = s.approvedCommentCount(a.ID)
+	if user, ok := s.users[a.UserID]; ok {
+		u := *user
+		a.User = &u
+	} else {
+		a.User = nil
+	}
+	a.Category = nil
+	if a.CategoryID != nil {
+		if category, ok := s.categories.categories[*a.CategoryID]; ok {
+			c := *category
+			a.Category = &c
+		}
+	}
+	a.Tags = s.articleTags(a.ID)
+	return &a
+}
+
+// storeArticle 保存文章的副本，作者、分类和标签单独保存，并更新搜索索引
+// 调用方需持有写锁
+func (s *MemoryStore) storeArticle(article *Article) {
+	stored := *article
+	stored.User, stored.Category, stored.Tags = nil, nil, nil
+	s.articles[article.ID] = &stored
+	s.setArticleTags(article.ID, article.Tags)
+	title, content := searchText(article)
+	s.search.Add(article.ID, title, content)
+}
+
+// GetArticles 按条件查询文章
+func (s *MemoryStore) GetArticles(query ArticleQuery) ([]*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	categoryIDs := s.categoryFilter(query.Category)
+	articles := make([]*Article, 0, len(s.articles))
+	for _, article := range s.articles {
+		if query.Status != "" && article.Status != query.Status {
+			continue
+		}
+		if query.AuthorID != 0 && article.UserID != query.AuthorID {
+			continue
+		}
+		if !inCategories(article, categoryIDs) {
+			continue
+		}
+		a := s.copyArticle(article)
+		if query.Tag != "" && !hasTag(a.Tags, query.Tag) {
+			continue
+		}
+		articles = append(articles, a)
+	}
+	sortArticles(articles)
+	return paginate(articles, query.Limit, query.Offset), nil
+}
+
+// categoryFilter 返回指定slug的分类及其子孙分类的ID，slug为空时返回nil表示不过滤
+// 调用方需持有读锁
+func (s *MemoryStore) categoryFilter(slug string) map[int]bool {
+	if slug == "" {
+		return nil
+	}
+	ids := make(map[int]bool)
+	categories := s.allCategories()
+	for _, c := range categories {
+		if c.Slug == slug {
+			for _, id := range CategoryDescendants(categories, c.ID) {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// inCategories 判断文章是否属于categoryFilter返回的分类，categoryIDs为nil时总是返回true
+func inCategories(article *Article, categoryIDs map[int]bool) bool {
+	return categoryIDs == nil || (article.CategoryID != nil && categoryIDs[*article.CategoryID])
+}
+
+// hasTag 判断标签中是否包含指定slug的标签
+func hasTag(tags []*Tag, slug string) bool {
+	for _, tag := range tags {
+		if tag.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// GetArticleByID 根据ID获取文章详情
+func (s *MemoryStore) GetArticleByID(id uint) (*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	article, ok := s.articles[int(id)]
+	if !ok {
+		return nil, nil
+	}
+	return s.copyArticle(article), nil
+}
+
+// CreateArticle 创建新文章
+func (s *MemoryStore) CreateArticle(article *Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkArticleSlug(article); err != nil {
+		return err
+	}
+	if article.ID == 0 {
+		article.ID = s.nextArticleID
+	}
+	if article.ID >= s.nextArticleID {
+		s.nextArticleID = article.ID + 1
+	}
+	article.CreatedAt = time.Now()
+	article.UpdatedAt = time.Now()
+	s.storeArticle(article)
+	return nil
+}
+
+// UpdateArticle 更新文章信息，slug改变时记录旧的slug
+// 浏览量保留存储中的值，不使用文章中可能已过期的值
+func (s *MemoryStore) UpdateArticle(article *Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkArticleSlug(article); err != nil {
+		return err
+	}
+	if stored, ok := s.articles[article.ID]; ok {
+		article.Views = stored.Views
+	}
+	s.saveArticleSlugHistory(article)
+	article.UpdatedAt = time.Now()
+	article.ClearRender()
+	s.storeArticle(article)
+	return nil
+}
+
+// SaveArticleRender 保存文章的内容渲染缓存
+func (s *MemoryStore) SaveArticleRender(article *Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.articles[article.ID]; ok && stored.Content == article.Content {
+		stored.ContentHTML, stored.TOC, stored.RenderVersion = article.ContentHTML, article.TOC, article.RenderVersion
+	}
+	return nil
+}
+
+// DeleteArticle 删除文章及其评论、标签关联和历史slug
+func (s *MemoryStore) DeleteArticle(article *Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.articles, article.ID)
+	delete(s.tags.articles, article.ID)
+	s.deleteArticleSlugHistory(article.ID)
+	s.search.Remove(article.ID)
+	s.deleteComments(func(c *Comment) bool { return c.ArticleID == article.ID })
+	return nil
+}
+
+// IncreaseArticleViews 将文章浏览量加1
+func (s *MemoryStore) IncreaseArticleViews(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if article, ok := s.articles[int(id)]; ok {
+		article.Views++
+	}
+	return nil
+}
+
+// GetUsers 获取用户列表
+func (s *MemoryStore) GetUsers(limit, offset int) ([]*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*User, 0, len(s.users))
+	for _, user := range s.users {
+		u := *user
+		users = append(users, &u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return paginate(users, limit, offset), nil
+}
+
+// GetUserByID 根据ID获取用户
+func (s *MemoryStore) GetUserByID(id uint) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[int(id)]
+	if !ok {
+		return nil, nil
+	}
+	u := *user
+	return &u, nil
+}
+
+// GetUserByUsername 根据用户名获取用户
+func (s *MemoryStore) GetUserByUsername(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Username == username {
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetUserByEmail 根据邮箱获取用户
+func (s *MemoryStore) GetUserByEmail(email string) (*User, error) {
+	if email == "" {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateUser 创建新用户
+func (s *MemoryStore) CreateUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.Role == "" {
+		user.Role = RoleReader
+	}
+	if user.ID == 0 {
+		user.ID = s.nextUserID
+	}
+	if user.ID >= s.nextUserID {
+		s.nextUserID = user.ID + 1
+	}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+	stored := *user
+	s.users[user.ID] = &stored
+	return nil
+}
+
+// UpdateUser 更新用户信息
+func (s *MemoryStore) UpdateUser(user *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.UpdatedAt = time.Now()
+	stored := *user
+	s.users[user.ID] = &stored
+	return nil
+}
+
+// sortArticles 按创建时间倒序排列文章，创建时间相同时ID大的在前
+func sortArticles(articles []*Article) {
+	sort.Slice(articles, func(i, j int) bool {
+		if articles[i].CreatedAt.Equal(articles[j].CreatedAt) {
+			return articles[i].ID > articles[j].ID
+		}
+		return articles[i].CreatedAt.After(articles[j].CreatedAt)
+	})
+}
+
+// paginate 对切片按limit和offset进行分页，limit小于等于0表示不限制
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return items[:0]
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
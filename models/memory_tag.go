@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// memoryTags 内存存储中的标签数据，由MemoryStore.mu保护
+type memoryTags struct {
+	tags     map[int]*Tag
+	articles map[int][]int // 文章ID到标签ID的关联
+	nextID   int
+}
+
+// newMemoryTags 创建空的标签数据
+func newMemoryTags() *memoryTags {
+	return &memoryTags{
+		tags:     make(map[int]*Tag),
+		articles: make(map[int][]int),
+		nextID:   1,
+	}
+}
+
+// articleTags 返回文章按名称排序的标签副本
+// 调用方需持有读锁
+func (s *MemoryStore) articleTags(articleID int) []*Tag {
+	tags := make([]*Tag, 0, len(s.tags.articles[articleID]))
+	for _, id := range s.tags.articles[articleID] {
+		if tag, ok := s.tags.tags[id]; ok {
+			t := *tag
+			t.ArticleCount = 0
+			tags = append(tags, &t)
+		}
+	}
+	sortTags(tags)
+	return tags
+}
+
+// setArticleTags 把文章的标签关联替换为tags
+// 调用方需持有写锁
+func (s *MemoryStore) setArticleTags(articleID int, tags []*Tag) {
+	if len(tags) == 0 {
+		delete(s.tags.articles, articleID)
+		return
+	}
+	ids := make([]int, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	s.tags.articles[articleID] = ids
+}
+
+// GetTags 获取全部标签及其已发布的文章数量
+func (s *MemoryStore) GetTags() ([]*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for articleID, ids := range s.tags.articles {
+		if article, ok := s.articles[articleID]; ok && article.Status == ArticleStatusPublished {
+			for _, id := range ids {
+				counts[id]++
+			}
+		}
+	}
+	tags := make([]*Tag, 0, len(s.tags.tags))
+	for _, tag := range s.tags.tags {
+		t := *tag
+		t.ArticleCount = counts[t.ID]
+		tags = append(tags, &t)
+	}
+	sortTags(tags)
+	return tags, nil
+}
+
+// GetTagByID 根据ID获取标签
+func (s *MemoryStore) GetTagByID(id uint) (*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag, ok := s.tags.tags[int(id)]
+	if !ok {
+		return nil, nil
+	}
+	t := *tag
+	return &t, nil
+}
+
+// GetTagBySlug 根据slug获取标签
+func (s *MemoryStore) GetTagBySlug(slug string) (*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, tag := range s.tags.tags {
+		if tag.Slug == slug {
+			t := *tag
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateTag 创建标签
+func (s *MemoryStore) CreateTag(tag *Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTagSlug(tag); err != nil {
+		return err
+	}
+	tag.ID = s.tags.nextID
+	s.tags.nextID++
+	tag.CreatedAt = time.Now()
+	tag.UpdatedAt = time.Now()
+	t := *tag
+	s.tags.tags[tag.ID] = &t
+	return nil
+}
+
+// UpdateTag 更新标签
+func (s *MemoryStore) UpdateTag(tag *Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkTagSlug(tag); err != nil {
+		return err
+	}
+	tag.UpdatedAt = time.Now()
+	t := *tag
+	s.tags.tags[tag.ID] = &t
+	return nil
+}
+
+// checkTagSlug 检查slug是否已被其他标签使用，与数据库的唯一约束一致
+// 调用方需持有读锁
+func (s *MemoryStore) checkTagSlug(tag *Tag) error {
+	for _, existing := range s.tags.tags {
+		if existing.Slug == tag.Slug && existing.ID != tag.ID {
+			return fmt.Errorf("tag slug %q already exists", tag.Slug)
+		}
+	}
+	return nil
+}
+
+// DeleteTag 删除标签及其与文章的关联
+func (s *MemoryStore) DeleteTag(tag *Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tags.tags, tag.ID)
+	for articleID, ids := range s.tags.articles {
+		kept := ids[:0]
+		for _, id := range ids {
+			if id != tag.ID {
+				kept = append(kept, id)
+			}
+		}
+		s.tags.articles[articleID] = kept
+	}
+	return nil
+}
+
+// sortTags 按名称排列标签，名称相同时按ID
+func sortTags(tags []*Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Name == tags[j].Name {
+			return tags[i].ID < tags[j].ID
+		}
+		return tags[i].Name < tags[j].Name
+	})
+}
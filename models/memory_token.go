@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+)
+
+// memoryTokens 内存存储中的令牌数据，由MemoryStore.mu保护
+type memoryTokens struct {
+	refresh     map[int]*RefreshToken
+	nextRefresh int
+	revoked     map[string]*RevokedToken
+	sessions    map[int]time.Time
+}
+
+// newMemoryTokens 创建空的令牌数据
+func newMemoryTokens() *memoryTokens {
+	return &memoryTokens{
+		refresh:     make(map[int]*RefreshToken),
+		nextRefresh: 1,
+		revoked:     make(map[string]*RevokedToken),
+		sessions:    make(map[int]time.Time),
+	}
+}
+
+// CreateRefreshToken 保存新签发的刷新令牌
+func (s *MemoryStore) CreateRefreshToken(token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.tokens
+	token.ID = data.nextRefresh
+	data.nextRefresh++
+	token.CreatedAt = time.Now()
+	stored := *token
+	data.refresh[token.ID] = &stored
+	return nil
+}
+
+// GetRefreshTokenByHash 根据哈希获取刷新令牌
+func (s *MemoryStore) GetRefreshTokenByHash(hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens.refresh {
+		if token.TokenHash == hash {
+			t := *token
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// MarkRefreshTokenUsed 原子地将未使用的刷新令牌标记为已使用
+func (s *MemoryStore) MarkRefreshTokenUsed(id int, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens.refresh[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &at
+	return true, nil
+}
+
+// RevokeRefreshTokenFamily 吊销同一家族的所有刷新令牌
+func (s *MemoryStore) RevokeRefreshTokenFamily(familyID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens.refresh {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// RevokeAccessToken 将访问令牌加入吊销列表，并清理已过期的吊销记录
+func (s *MemoryStore) RevokeAccessToken(token *RevokedToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.tokens
+	now := time.Now()
+	for jti, revoked := range data.revoked {
+		if revoked.ExpiresAt.Before(now) {
+			delete(data.revoked, jti)
+		}
+	}
+	if _, ok := data.revoked[token.JTI]; !ok {
+		t := *token
+		data.revoked[token.JTI] = &t
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked 判断访问令牌是否已失效
+func (s *MemoryStore) IsAccessTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.tokens
+	if _, ok := data.revoked[jti]; ok {
+		return true, nil
+	}
+	before, ok := data.sessions[int(userID)]
+	return ok && !issuedAt.After(before), nil
+}
+
+// RevokeAllUserTokens 吊销用户所有的刷新令牌，并使at及之前签发的访问令牌失效
+func (s *MemoryStore) RevokeAllUserTokens(userID uint, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.tokens
+	for _, token := range data.refresh {
+		if token.UserID == int(userID) && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	data.sessions[int(userID)] = at
+	return nil
+}
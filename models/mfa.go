@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// UserMFA 用户的TOTP两步验证设置
+// 开始绑定时保存密钥，用户用第一个验证码确认后Enabled才为true
+type UserMFA struct {
+	UserID      int        `json:"user_id" gorm:"primaryKey;autoIncrement:false"` // 用户ID
+	Secret      string     `json:"-"`                                             // Base32编码的TOTP密钥
+	Enabled     bool       `json:"enabled"`                                       // 是否已确认启用
+	LastStep    int64      `json:"-"`                                             // 最近一次使用的验证码时间步，用于拒绝重放
+	ConfirmedAt *time.Time `json:"confirmed_at"`                                  // 确认启用的时间
+	CreatedAt   time.Time  `json:"created_at"`                                    // 开始绑定的时间
+}
+
+// TableName 指定UserMFA对应的表名
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// RecoveryCode 两步验证恢复码，丢失验证器时代替验证码使用，每个只能使用一次
+// 服务器只保存恢复码的SHA-256哈希
+type RecoveryCode struct {
+	ID       int        `json:"id"`      // 记录ID
+	UserID   int        `json:"user_id"` // 所属用户
+	CodeHash string     `json:"-"`       // 恢复码的SHA-256哈希（十六进制）
+	UsedAt   *time.Time `json:"used_at"` // 使用时间，未使用时为nil
+}
+
+// MFAStore 两步验证存储接口
+type MFAStore interface {
+	// GetUserMFA 获取用户的两步验证设置，未设置时返回nil, nil
+	GetUserMFA(userID uint) (*UserMFA, error)
+	// SaveUserMFA 创建或覆盖用户的两步验证设置
+	SaveUserMFA(mfa *UserMFA) error
+	// AdvanceMFAStep 原子地将LastStep推进到step，step不大于当前LastStep时返回false（验证码已被使用）
+	AdvanceMFAStep(userID uint, step int64) (bool, error)
+	// ReplaceRecoveryCodes 删除用户原有的恢复码并保存新的恢复码哈希
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	// UseRecoveryCode 原子地将未使用的恢复码标记为已使用，恢复码不存在或已使用时返回false
+	UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error)
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket 限流令牌桶
+// 令牌桶按GCRA方式只保存理论到达时间（TAT）：每放行一个请求TAT增加一个令牌的恢复间隔，
+// TAT早于当前时间表示令牌桶已满，超出当前时间(burst-1)个间隔表示令牌已用完
+type RateLimitBucket struct {
+	Key string `json:"key" gorm:"column:bucket_key;primaryKey"` // 限流键，如"auth:ip:127.0.0.1"
+	TAT int64  `json:"tat" gorm:"column:tat"`                   // 理论到达时间，Unix微秒
+}
+
+// RateLimitStore 限流令牌桶存储接口
+// 数据库实现可以在多个实例之间共享令牌桶，内存实现只在单个进程内有效
+type RateLimitStore interface {
+	// TakeRateLimit 原子地从令牌桶中取出一个令牌
+	// 参数interval为恢复一个令牌的间隔，burst为令牌桶容量；返回取令牌后的TAT和是否放行
+	TakeRateLimit(key string, at time.Time, interval time.Duration, burst int) (time.Time, bool, error)
+	// PurgeRateLimits 删除TAT早于before的令牌桶，这些令牌桶已满，与不存在等价
+	PurgeRateLimits(before time.Time) error
+}
+
+// rateLimitAllows 判断TAT为tat的令牌桶在at时刻是否还有令牌
+func rateLimitAllows(tat, at int64, interval time.Duration, burst int) bool {
+	return tat <= at+int64(burst-1)*interval.Microseconds()
+}
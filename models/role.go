@@ -0,0 +1,73 @@
+package models
+
+// Role 用户角色
+// 角色决定用户拥有的权限集合，存储在users.role列中
+type Role string
+
+// 系统内置角色，权限从高到低
+const (
+	RoleAdmin  Role = "admin"  // 管理员：拥有所有权限
+	RoleEditor Role = "editor" // 编辑：可以编辑和发布任何人的文章，审核评论，管理标签和分类
+	RoleAuthor Role = "author" // 作者：只能创建、编辑和发布自己的文章
+	RoleReader Role = "reader" // 读者：只能浏览，新注册用户的默认角色
+)
+
+// Permission 权限标识，格式为"资源:操作"
+type Permission string
+
+// 系统支持的权限
+const (
+	PermArticleWrite     Permission = "article:write"      // 创建文章，编辑、发布和删除自己的文章
+	PermArticleEditAny   Permission = "article:edit_any"   // 编辑他人的文章
+	PermArticlePublish   Permission = "article:publish"    // 发布或撤回他人的文章
+	PermArticleDeleteAny Permission = "article:delete_any" // 删除他人的文章
+	PermUserManage       Permission = "user:manage"        // 管理用户，包括修改角色
+	PermCommentModerate  Permission = "comment:moderate"   // 审核评论
+	PermTaxonomyManage   Permission = "taxonomy:manage"    // 管理标签和分类
+)
+
+// rolePermissions 每个角色拥有的权限
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermArticleWrite, PermArticleEditAny, PermArticlePublish, PermArticleDeleteAny,
+		PermUserManage, PermCommentModerate, PermTaxonomyManage,
+	},
+	RoleEditor: {
+		PermArticleWrite, PermArticleEditAny, PermArticlePublish, PermCommentModerate, PermTaxonomyManage,
+	},
+	RoleAuthor: {
+		PermArticleWrite,
+	},
+	RoleReader: {},
+}
+
+// Roles 返回所有内置角色，按权限从高到低排列
+func Roles() []Role {
+	return []Role{RoleAdmin, RoleEditor, RoleAuthor, RoleReader}
+}
+
+// Valid 判断是否为内置角色
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions 返回角色拥有的权限列表，未知角色返回空列表
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// Can 判断角色是否拥有指定权限
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid 判断是否为系统支持的权限
+func (p Permission) Valid() bool {
+	return RoleAdmin.Can(p)
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"gofile/internal/markdown"
+	"html"
+	"sort"
+	"time"
+)
+
+// SearchQuery 全文搜索条件，只搜索已发布的文章，零值的过滤字段表示不过滤
+type SearchQuery struct {
+	Terms    []string  // 由search.Terms切分出的词，文章必须包含所有词
+	Tag      string    // 标签的slug
+	Category string    // 分类的slug，包括其所有子分类中的文章
+	AuthorID int       // 作者ID
+	From     time.Time // 创建时间不早于此时间
+	To       time.Time // 创建时间早于此时间
+	Limit    int       // 每页数量，小于等于0表示不限制
+	Offset   int       // 跳过的数量
+}
+
+// SearchHit 一条搜索结果
+type SearchHit struct {
+	Article *Article // 匹配的文章，附带作者、分类、标签信息和评论数量
+	Score   float64  // 相关度，只用于同一次搜索的结果之间比较
+}
+
+// SearchStore 全文搜索存储接口
+// PostgreSQL使用tsvector列和GIN索引，内存和SQLite存储使用进程内的倒排索引；
+// 索引随CreateArticle、UpdateArticle和DeleteArticle更新，文章的标题和正文（去掉Markdown标记）参与搜索
+type SearchStore interface {
+	// SearchArticles 搜索已发布的文章，按相关度降序，相关度相同时按创建时间倒序
+	SearchArticles(query SearchQuery) ([]*SearchHit, error)
+}
+
+// searchText 返回文章参与搜索的标题和正文纯文本
+// 标题保存时已转义HTML，这里还原为原文
+func searchText(article *Article) (string, string) {
+	return html.UnescapeString(article.Title), markdown.PlainText(article.Content)
+}
+
+// sortSearchHits 按相关度降序排列搜索结果，相关度相同时按创建时间倒序
+func sortSearchHits(hits []*SearchHit) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		a, b := hits[i].Article, hits[j].Article
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+}
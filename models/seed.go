@@ -0,0 +1,77 @@
+package models
+
+import (
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SeedTestData 在存储为空时创建测试数据
+// 创建一个管理员账号（admin/123456）和一篇欢迎文章
+// 参数：
+//
+//	store - 要写入测试数据的存储
+func SeedTestData(store Store) {
+	// 检查是否已有数据
+	users, err := store.GetUsers(1, 0)
+	if err != nil {
+		log.Println("检查测试数据失败:", err)
+		return
+	}
+	if len(users) > 0 {
+		return
+	}
+
+	password := "123456"
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Println("创建测试用户密码失败:", err)
+		return
+	}
+
+	// 创建测试用户
+	adminUser := &User{
+		Username:      "admin",
+		Password:      string(hashedPassword),
+		Email:         "admin@example.com",
+		EmailVerified: true,
+		Nickname:      "管理员",
+		Role:          RoleAdmin,
+	}
+	if err := store.CreateUser(adminUser); err != nil {
+		log.Println("创建测试用户失败:", err)
+		return
+	}
+
+	// 创建测试文章的分类和标签
+	category := &Category{Name: "博客", Slug: "blog"}
+	if err := store.CreateCategory(category); err != nil {
+		log.Println("创建测试分类失败:", err)
+		return
+	}
+	var tags []*Tag
+	for _, tag := range []*Tag{{Name: "博客", Slug: "blog"}, {Name: "Go", Slug: "go"}, {Name: "Gin", Slug: "gin"}} {
+		if err := store.CreateTag(tag); err != nil {
+			log.Println("创建测试标签失败:", err)
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	// 创建测试文章
+	testArticle := &Article{
+		Title:      "欢迎来到我的博客",
+		Content:    "这是我的个人博客，用于记录生活点滴和分享技术知识。感谢您的访问！",
+		Slug:       "welcome-to-my-blog",
+		CategoryID: &category.ID,
+		Tags:       tags,
+		Status:     "published",
+		UserID:     adminUser.ID,
+	}
+	if err := store.CreateArticle(testArticle); err != nil {
+		log.Println("创建测试文章失败:", err)
+		return
+	}
+
+	log.Println("测试数据创建成功")
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot 只读快照缓存
+// 记录最近一次成功从存储读取到的文章和用户数据，并定期持久化到磁盘。
+// 当存储不可用时，处理器可以从快照中返回最后已知的正确数据。
+type Snapshot struct {
+	mu    sync.RWMutex
+	path  string
+	dirty bool
+	data  snapshotData
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// snapshotData 快照在磁盘上的JSON结构
+type snapshotData struct {
+	SavedAt  time.Time        `json:"saved_at"` // 最后一次记录数据的时间
+	Articles map[int]*Article `json:"articles"` // 按ID索引的文章
+	Users    map[int]*User    `json:"users"`    // 按ID索引的用户
+}
+
+// OpenSnapshot 打开快照缓存，如果文件已存在则加载其中的数据
+// 参数：
+//
+//	path - 快照文件路径
+//	flushInterval - 将内存中的变更写入磁盘的间隔
+//
+// 返回：
+//
+//	*Snapshot - 快照实例，使用完毕后需调用Close
+//	error - 快照文件存在但无法解析时返回错误
+func OpenSnapshot(path string, flushInterval time.Duration) (*Snapshot, error) {
+	s := &Snapshot{
+		path: path,
+		data: snapshotData{
+			Articles: make(map[int]*Article),
+			Users:    make(map[int]*User),
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	raw, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(raw, &s.data); err != nil {
+			return nil, err
+		}
+		if s.data.Articles == nil {
+			s.data.Articles = make(map[int]*Article)
+		}
+		if s.data.Users == nil {
+			s.data.Users = make(map[int]*User)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	if flushInterval <= 0 {
+		flushInterval = 30 * time.Second
+	}
+	go s.flushLoop(flushInterval)
+	return s, nil
+}
+
+// flushLoop 定期将快照写入磁盘，直到Close被调用
+func (s *Snapshot) flushLoop(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Printf("Warning: 写入快照失败: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close 停止后台写入并将剩余的变更写入磁盘
+func (s *Snapshot) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
+
+// Flush 如果有未写入的变更，将快照原子性地写入磁盘
+func (s *Snapshot) Flush() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	raw, err := json.Marshal(s.data)
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免进程中断时留下损坏的快照
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// SavedAt 返回快照中数据最后一次更新的时间
+func (s *Snapshot) SavedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.SavedAt
+}
+
+// RecordArticles 记录从存储成功读取的文章
+func (s *Snapshot) RecordArticles(articles ...*Article) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, article := range articles {
+		a := *article
+		if a.User != nil {
+			u := *a.User
+			a.User = &u
+			s.data.Users[u.ID] = &u
+		}
+		s.data.Articles[a.ID] = &a
+	}
+	s.touch()
+}
+
+// ForgetArticle 从快照中移除已删除的文章
+func (s *Snapshot) ForgetArticle(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.Articles, id)
+	s.touch()
+}
+
+// RecordUsers 记录从存储成功读取的用户
+func (s *Snapshot) RecordUsers(users ...*User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range users {
+		u := *user
+		s.data.Users[u.ID] = &u
+	}
+	s.touch()
+}
+
+// touch 标记快照已变更，调用方需持有写锁
+func (s *Snapshot) touch() {
+	s.data.SavedAt = time.Now()
+	s.dirty = true
+}
+
+// GetArticles 从快照中获取文章列表，排序和过滤规则与存储一致
+// 快照中没有分类的层级关系，按分类过滤时不包括子分类中的文章
+func (s *Snapshot) GetArticles(query ArticleQuery) []*Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	articles := make([]*Article, 0, len(s.data.Articles))
+	for _, article := range s.data.Articles {
+		if query.Status != "" && article.Status != query.Status {
+			continue
+		}
+		if query.AuthorID != 0 && article.UserID != query.AuthorID {
+			continue
+		}
+		if query.Tag != "" && !hasTag(article.Tags, query.Tag) {
+			continue
+		}
+		if query.Category != "" && (article.Category == nil || article.Category.Slug != query.Category) {
+			continue
+		}
+		a := *article
+		articles = append(articles, &a)
+	}
+	sortArticles(articles)
+	return paginate(articles, query.Limit, query.Offset)
+}
+
+// GetArticleByID 从快照中获取文章，不存在时返回nil
+func (s *Snapshot) GetArticleByID(id int) *Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	article, ok := s.data.Articles[id]
+	if !ok {
+		return nil
+	}
+	a := *article
+	return &a
+}
+
+// GetArticleBySlug 从快照中按当前的slug获取文章，不存在时返回nil
+// 快照中没有历史slug，旧的slug不会重定向
+func (s *Snapshot) GetArticleBySlug(slug string) *Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, article := range s.data.Articles {
+		if slug != "" && article.Slug == slug {
+			a := *article
+			return &a
+		}
+	}
+	return nil
+}
+
+// GetUsers 从快照中获取用户列表，按ID升序
+func (s *Snapshot) GetUsers(limit, offset int) []*User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]*User, 0, len(s.data.Users))
+	for _, user := range s.data.Users {
+		u := *user
+		users = append(users, &u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return paginate(users, limit, offset)
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// 垃圾内容屏蔽列表的条目类型
+const (
+	SpamBlockWord   = "word"   // 内容或昵称中包含该词（不区分大小写）
+	SpamBlockIP     = "ip"     // 来源IP，支持CIDR网段
+	SpamBlockDomain = "domain" // 邮箱或链接的域名，包括其子域名
+)
+
+// ValidSpamBlockKind 判断屏蔽列表条目类型是否合法
+func ValidSpamBlockKind(kind string) bool {
+	switch kind {
+	case SpamBlockWord, SpamBlockIP, SpamBlockDomain:
+		return true
+	}
+	return false
+}
+
+// SpamBlockEntry 垃圾内容屏蔽列表条目，由管理员维护
+type SpamBlockEntry struct {
+	ID        int       `json:"id"`         // 条目ID
+	Kind      string    `json:"kind"`       // 条目类型：word、ip或domain
+	Value     string    `json:"value"`      // 屏蔽的词、IP（或CIDR）或域名，已转为小写
+	CreatedAt time.Time `json:"created_at"` // 添加时间
+}
+
+// TableName 指定SpamBlockEntry对应的表名
+func (SpamBlockEntry) TableName() string {
+	return "spam_blocklist"
+}
+
+// SpamToken 朴素贝叶斯分类器中一个词在两类样本中出现的文档数
+// Token为空字符串的行保存两类样本的文档总数
+type SpamToken struct {
+	Token string `json:"token" gorm:"primaryKey"` // 词
+	Spam  int    `json:"spam"`                    // 包含该词的垃圾样本数
+	Ham   int    `json:"ham"`                     // 包含该词的正常样本数
+}
+
+// SpamStore 垃圾内容过滤数据的存储接口
+type SpamStore interface {
+	// GetSpamBlocklist 获取全部屏蔽列表条目，按ID升序
+	GetSpamBlocklist() ([]*SpamBlockEntry, error)
+	// CreateSpamBlockEntry 添加屏蔽列表条目，会自动设置ID和CreatedAt，同类型同值的条目已存在时返回错误
+	CreateSpamBlockEntry(entry *SpamBlockEntry) error
+	// DeleteSpamBlockEntry 删除屏蔽列表条目，返回条目是否存在
+	DeleteSpamBlockEntry(id uint) (bool, error)
+	// GetSpamTokens 获取词的计数，不存在的词不出现在结果中
+	GetSpamTokens(tokens []string) (map[string]SpamToken, error)
+	// AddSpamTokens 原子地把每个词及文档总数的计数加上spam和ham，计数最小为0
+	AddSpamTokens(tokens []string, spam, ham int) error
+}
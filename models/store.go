@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+)
+
+// ArticleStore 文章存储接口
+// 所有文章相关的持久化操作都通过此接口完成，具体实现可以是PostgreSQL、SQLite或内存
+type ArticleStore interface {
+	// GetArticles 按条件查询文章，按创建时间倒序
+	// 返回的文章附带作者、分类、标签信息和已通过审核的评论数量
+	GetArticles(query ArticleQuery) ([]*Article, error)
+	// GetArticleByID 根据ID获取文章及其分类、标签和评论数量，文章不存在时返回nil, nil
+	GetArticleByID(id uint) (*Article, error)
+	// CreateArticle 创建新文章，会自动设置ID、CreatedAt和UpdatedAt
+	// 文章的标签按Article.Tags保存，标签必须已经存在
+	CreateArticle(article *Article) error
+	// UpdateArticle 更新文章，会自动更新UpdatedAt并清空内容渲染缓存，文章的标签替换为Article.Tags
+	// 不修改浏览量，浏览量只通过IncreaseArticleViews增加
+	UpdateArticle(article *Article) error
+	// SaveArticleRender 保存文章的内容渲染缓存，不修改UpdatedAt
+	// 只有存储中的内容仍与article.Content一致时才保存，避免并发修改后写入过期的缓存
+	SaveArticleRender(article *Article) error
+	// DeleteArticle 删除文章及其评论
+	DeleteArticle(article *Article) error
+	// IncreaseArticleViews 将指定文章的浏览量加1，读取文章的方法不会增加浏览量
+	IncreaseArticleViews(id uint) error
+}
+
+// UserStore 用户存储接口
+// 所有用户相关的持久化操作都通过此接口完成
+type UserStore interface {
+	// GetUsers 获取用户列表，按ID升序
+	GetUsers(limit, offset int) ([]*User, error)
+	// GetUserByID 根据ID获取用户，用户不存在时返回nil, nil
+	GetUserByID(id uint) (*User, error)
+	// GetUserByUsername 根据用户名获取用户，用户不存在时返回nil, nil
+	GetUserByUsername(username string) (*User, error)
+	// GetUserByEmail 根据邮箱获取用户，邮箱应已转为小写，用户不存在时返回nil, nil
+	GetUserByEmail(email string) (*User, error)
+	// CreateUser 创建新用户，会自动设置ID、CreatedAt和UpdatedAt，未指定角色时为RoleReader
+	CreateUser(user *User) error
+	// UpdateUser 更新用户信息，会自动更新UpdatedAt
+	UpdateUser(user *User) error
+}
+
+// Store 应用程序使用的完整存储接口
+// 组合了所有子存储接口，由各个后端实现
+type Store interface {
+	ArticleStore
+	ArticleSlugStore
+	TagStore
+	CategoryStore
+	CommentStore
+	UserStore
+	TokenStore
+	MFAStore
+	LoginAttemptStore
+	RateLimitStore
+	AuditStore
+	APIKeyStore
+	IdentityStore
+	SpamStore
+	SearchStore
+	// Close 释放存储占用的资源（如数据库连接）
+	Close() error
+}
+
+// 支持的存储驱动名称，对应配置文件中的database.driver
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// OpenStore 根据驱动名称创建对应的存储实现
+// 参数：
+//
+//	driver - 存储驱动：postgres、sqlite或memory，空字符串等同于postgres
+//	dsn - 数据库连接字符串，memory驱动忽略此参数
+//
+// 返回：
+//
+//	Store - 创建好的存储实例
+//	error - 驱动不支持或连接失败时返回错误
+func OpenStore(driver, dsn string) (Store, error) {
+	switch driver {
+	case "", DriverPostgres:
+		return NewPostgresStore(dsn)
+	case DriverSQLite:
+		return NewSQLiteStore(dsn)
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", driver)
+	}
+}
@@ -0,0 +1,288 @@
+package models
+
+import (
+	"gofile/internal/migrate"
+	"gofile/internal/search"
+	"path/filepath"
+	"testing"
+)
+
+// storeBackends 参与共享测试的存储实现，每次调用open都返回一个空的存储
+var storeBackends = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store { return NewMemoryStore() }},
+	{"sqlite", openTestSQLiteStore},
+}
+
+// openTestSQLiteStore 在临时目录中创建SQLite存储，并应用全部迁移
+func openTestSQLiteStore(t *testing.T) Store {
+	t.Helper()
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	sqlDB, err := store.DB().DB()
+	if err != nil {
+		t.Fatalf("get sql db: %v", err)
+	}
+	m, err := migrate.New(sqlDB, store.Dialect())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return store
+}
+
+// forEachStore 对每一种存储实现运行同一个测试
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+// createTestUser 创建用户，失败时终止测试
+func createTestUser(t *testing.T, store Store, username string) *User {
+	t.Helper()
+	user := &User{Username: username, Nickname: username, Email: username + "@example.com", Role: RoleAuthor}
+	if err := store.CreateUser(user); err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
+
+// createTestArticle 创建文章，失败时终止测试
+func createTestArticle(t *testing.T, store Store, article *Article) *Article {
+	t.Helper()
+	if err := store.CreateArticle(article); err != nil {
+		t.Fatalf("create article %q: %v", article.Title, err)
+	}
+	return article
+}
+
+// getTestArticle 读取文章，文章不存在时终止测试
+func getTestArticle(t *testing.T, store Store, id int) *Article {
+	t.Helper()
+	article, err := store.GetArticleByID(uint(id))
+	if err != nil || article == nil {
+		t.Fatalf("get article %d: %v, %v", id, article, err)
+	}
+	return article
+}
+
+func TestStoreUsers(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		alice := createTestUser(t, store, "alice")
+		createTestUser(t, store, "bob")
+		if alice.ID == 0 || alice.CreatedAt.IsZero() {
+			t.Fatalf("created user = %+v, want ID and CreatedAt set", alice)
+		}
+
+		if got, err := store.GetUserByUsername("alice"); err != nil || got == nil || got.ID != alice.ID {
+			t.Fatalf("get by username = %v, %v", got, err)
+		}
+		if got, err := store.GetUserByEmail("alice@example.com"); err != nil || got == nil || got.ID != alice.ID {
+			t.Fatalf("get by email = %v, %v", got, err)
+		}
+		if got, err := store.GetUserByUsername("nobody"); err != nil || got != nil {
+			t.Fatalf("get missing user = %v, %v, want nil, nil", got, err)
+		}
+
+		alice.Nickname, alice.EmailVerified = "Alice", true
+		if err := store.UpdateUser(alice); err != nil {
+			t.Fatalf("update user: %v", err)
+		}
+		got, err := store.GetUserByID(uint(alice.ID))
+		if err != nil || got == nil || got.Nickname != "Alice" || !got.EmailVerified {
+			t.Fatalf("updated user = %+v, %v", got, err)
+		}
+
+		users, err := store.GetUsers(10, 0)
+		if err != nil || len(users) != 2 || users[0].Username != "alice" || users[1].Username != "bob" {
+			t.Fatalf("users = %v, %v, want alice and bob in ID order", users, err)
+		}
+		if users, _ := store.GetUsers(1, 1); len(users) != 1 || users[0].Username != "bob" {
+			t.Fatalf("second page = %v, want bob", users)
+		}
+	})
+}
+
+func TestStoreArticles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		author := createTestUser(t, store, "alice")
+		tag := &Tag{Name: "Go", Slug: "go"}
+		if err := store.CreateTag(tag); err != nil {
+			t.Fatalf("create tag: %v", err)
+		}
+		parent := &Category{Name: "编程", Slug: "编程"}
+		if err := store.CreateCategory(parent); err != nil {
+			t.Fatalf("create category: %v", err)
+		}
+		child := &Category{Name: "后端", Slug: "后端", ParentID: &parent.ID}
+		if err := store.CreateCategory(child); err != nil {
+			t.Fatalf("create category: %v", err)
+		}
+
+		published := createTestArticle(t, store, &Article{
+			Title: "已发布", Content: "内容", Slug: "published", Status: ArticleStatusPublished,
+			UserID: author.ID, CategoryID: &child.ID, Tags: []*Tag{tag},
+		})
+		draft := createTestArticle(t, store, &Article{
+			Title: "草稿", Content: "内容", Slug: "draft", Status: ArticleStatusDraft, UserID: author.ID,
+		})
+
+		got := getTestArticle(t, store, published.ID)
+		if got.Title != "已发布" || got.User == nil || got.User.Username != "alice" ||
+			got.Category == nil || got.Category.ID != child.ID || len(got.Tags) != 1 || got.Tags[0].Slug != "go" {
+			t.Fatalf("article = %+v, want author, category and tag loaded", got)
+		}
+		if missing, err := store.GetArticleByID(9999); err != nil || missing != nil {
+			t.Fatalf("get missing article = %v, %v, want nil, nil", missing, err)
+		}
+
+		tests := []struct {
+			name  string
+			query ArticleQuery
+			want  []int
+		}{
+			{"all", ArticleQuery{}, []int{draft.ID, published.ID}},
+			{"published", ArticleQuery{Status: ArticleStatusPublished}, []int{published.ID}},
+			{"draft", ArticleQuery{Status: ArticleStatusDraft}, []int{draft.ID}},
+			{"tag", ArticleQuery{Tag: "go"}, []int{published.ID}},
+			{"parent category", ArticleQuery{Category: "编程"}, []int{published.ID}},
+			{"missing category", ArticleQuery{Category: "missing"}, []int{}},
+			{"page", ArticleQuery{Limit: 1, Offset: 1}, []int{published.ID}},
+		}
+		for _, tt := range tests {
+			articles, err := store.GetArticles(tt.query)
+			if err != nil {
+				t.Fatalf("%s: get articles: %v", tt.name, err)
+			}
+			ids := make([]int, len(articles))
+			for i, a := range articles {
+				ids[i] = a.ID
+			}
+			if len(ids) != len(tt.want) || (len(ids) > 0 && ids[0] != tt.want[0]) {
+				t.Errorf("%s: articles = %v, want %v", tt.name, ids, tt.want)
+			}
+		}
+
+		if err := store.DeleteArticle(draft); err != nil {
+			t.Fatalf("delete article: %v", err)
+		}
+		if got, _ := store.GetArticleByID(uint(draft.ID)); got != nil {
+			t.Fatalf("deleted article still exists: %+v", got)
+		}
+	})
+}
+
+func TestStoreArticleViews(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		author := createTestUser(t, store, "alice")
+		article := createTestArticle(t, store, &Article{
+			Title: "标题", Content: "内容", Slug: "views", Status: ArticleStatusPublished, UserID: author.ID,
+		})
+
+		// 读取文章不增加浏览量
+		for i := 0; i < 3; i++ {
+			getTestArticle(t, store, article.ID)
+			if _, err := store.GetArticleBySlug("views"); err != nil {
+				t.Fatalf("get by slug: %v", err)
+			}
+		}
+		if got := getTestArticle(t, store, article.ID); got.Views != 0 {
+			t.Fatalf("views after reads = %d, want 0", got.Views)
+		}
+
+		// 先读出文章，期间浏览量增加，再用读出的副本更新，不能覆盖新的浏览量
+		stale := getTestArticle(t, store, article.ID)
+		for i := 0; i < 2; i++ {
+			if err := store.IncreaseArticleViews(uint(article.ID)); err != nil {
+				t.Fatalf("increase views: %v", err)
+			}
+		}
+		stale.Title = "新标题"
+		if err := store.UpdateArticle(stale); err != nil {
+			t.Fatalf("update article: %v", err)
+		}
+		got := getTestArticle(t, store, article.ID)
+		if got.Title != "新标题" || got.Views != 2 {
+			t.Fatalf("after update title = %q views = %d, want 新标题 and 2", got.Title, got.Views)
+		}
+	})
+}
+
+func TestStoreArticleSlugs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		author := createTestUser(t, store, "alice")
+		article := createTestArticle(t, store, &Article{
+			Title: "标题", Content: "内容", Slug: "old-slug", Status: ArticleStatusPublished, UserID: author.ID,
+		})
+		if err := store.CreateArticle(&Article{Title: "重复", Content: "内容", Slug: "old-slug", UserID: author.ID}); err == nil {
+			t.Fatal("creating an article with a duplicate slug succeeded")
+		}
+
+		article.Slug = "new-slug"
+		if err := store.UpdateArticle(article); err != nil {
+			t.Fatalf("update slug: %v", err)
+		}
+		if got, err := store.GetArticleBySlug("new-slug"); err != nil || got == nil || got.ID != article.ID {
+			t.Fatalf("get by new slug = %v, %v", got, err)
+		}
+		if got, err := store.GetArticleBySlug("old-slug"); err != nil || got != nil {
+			t.Fatalf("get by old slug = %v, %v, want nil, nil", got, err)
+		}
+		if current, err := store.ResolveArticleSlug("old-slug"); err != nil || current != "new-slug" {
+			t.Fatalf("resolve old slug = %q, %v, want new-slug", current, err)
+		}
+		if taken, err := store.ArticleSlugTaken("old-slug", 0); err != nil || !taken {
+			t.Fatalf("old slug taken by other article = %v, %v, want true", taken, err)
+		}
+		if taken, err := store.ArticleSlugTaken("old-slug", article.ID); err != nil || taken {
+			t.Fatalf("old slug taken by owner = %v, %v, want false", taken, err)
+		}
+
+		if err := store.DeleteArticle(article); err != nil {
+			t.Fatalf("delete article: %v", err)
+		}
+		if current, err := store.ResolveArticleSlug("old-slug"); err != nil || current != "" {
+			t.Fatalf("resolve slug of deleted article = %q, %v, want empty", current, err)
+		}
+	})
+}
+
+func TestStoreSearchArticles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		author := createTestUser(t, store, "alice")
+		match := createTestArticle(t, store, &Article{
+			Title: "Go语言并发", Content: "goroutine和channel", Slug: "go", Status: ArticleStatusPublished, UserID: author.ID,
+		})
+		createTestArticle(t, store, &Article{
+			Title: "Go语言草稿", Content: "并发", Slug: "draft", Status: ArticleStatusDraft, UserID: author.ID,
+		})
+		createTestArticle(t, store, &Article{
+			Title: "Rust", Content: "所有权", Slug: "rust", Status: ArticleStatusPublished, UserID: author.ID,
+		})
+
+		hits, err := store.SearchArticles(SearchQuery{Terms: search.Terms("并发 go")})
+		if err != nil {
+			t.Fatalf("search: %v", err)
+		}
+		if len(hits) != 1 || hits[0].Article.ID != match.ID || hits[0].Article.User == nil || hits[0].Score <= 0 {
+			t.Fatalf("hits = %+v, want only the published article with its author", hits)
+		}
+
+		if err := store.DeleteArticle(match); err != nil {
+			t.Fatalf("delete article: %v", err)
+		}
+		if hits, err := store.SearchArticles(SearchQuery{Terms: search.Terms("并发")}); err != nil || len(hits) != 0 {
+			t.Fatalf("hits after delete = %v, %v, want none", hits, err)
+		}
+	})
+}
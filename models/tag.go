@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag 文章标签，通过article_tags表与文章多对多关联
+type Tag struct {
+	ID           int       `json:"id"`                               // 标签ID
+	Name         string    `json:"name"`                             // 标签名称
+	Slug         string    `json:"slug"`                             // 标签短链接，用于URL和过滤文章，唯一
+	Description  string    `json:"description"`                      // 标签说明
+	CreatedAt    time.Time `json:"created_at"`                       // 创建时间
+	UpdatedAt    time.Time `json:"updated_at"`                       // 最后修改时间
+	ArticleCount int       `json:"article_count,omitempty" gorm:"-"` // 已发布的文章数量，只在标签列表中返回
+}
+
+// ArticleTag 文章与标签的关联
+type ArticleTag struct {
+	ArticleID int `gorm:"primaryKey"` // 文章ID
+	TagID     int `gorm:"primaryKey"` // 标签ID
+}
+
+// TableName 指定关联表名
+func (ArticleTag) TableName() string {
+	return "article_tags"
+}
+
+// TagStore 标签存储接口
+// 文章的标签随CreateArticle和UpdateArticle按Article.Tags保存
+type TagStore interface {
+	// GetTags 获取全部标签及其已发布的文章数量，按名称排序
+	GetTags() ([]*Tag, error)
+	// GetTagByID 根据ID获取标签，标签不存在时返回nil, nil
+	GetTagByID(id uint) (*Tag, error)
+	// GetTagBySlug 根据slug获取标签，标签不存在时返回nil, nil
+	GetTagBySlug(slug string) (*Tag, error)
+	// CreateTag 创建标签，会自动设置ID、CreatedAt和UpdatedAt
+	CreateTag(tag *Tag) error
+	// UpdateTag 更新标签，会自动更新UpdatedAt
+	UpdateTag(tag *Tag) error
+	// DeleteTag 删除标签，并从所有文章中移除该标签
+	DeleteTag(tag *Tag) error
+}
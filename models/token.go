@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken 刷新令牌
+// 服务器只保存令牌的SHA-256哈希；每次刷新都会签发同一家族(FamilyID)的新令牌并将旧令牌标记为已使用，
+// 已使用的令牌再次出现说明令牌可能被盗用，此时整个家族都会被吊销
+type RefreshToken struct {
+	ID        int        `json:"id"`         // 令牌记录ID
+	UserID    int        `json:"user_id"`    // 令牌所属用户
+	FamilyID  string     `json:"family_id"`  // 令牌家族ID，同一次登录产生的令牌属于同一家族
+	TokenHash string     `json:"-"`          // 令牌的SHA-256哈希（十六进制）
+	ExpiresAt time.Time  `json:"expires_at"` // 过期时间
+	CreatedAt time.Time  `json:"created_at"` // 签发时间
+	UsedAt    *time.Time `json:"used_at"`    // 被轮换使用的时间，未使用时为nil
+	RevokedAt *time.Time `json:"revoked_at"` // 被吊销的时间，未吊销时为nil
+}
+
+// RevokedToken 被吊销的访问令牌
+// 按JWT的jti记录，令牌过期后记录即可清理
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"column:jti;primaryKey"` // 访问令牌的jti声明
+	UserID    int       `json:"user_id"`                          // 令牌所属用户
+	ExpiresAt time.Time `json:"expires_at"`                       // 令牌原本的过期时间
+	RevokedAt time.Time `json:"revoked_at"`                       // 吊销时间
+}
+
+// SessionRevocation 用户级别的会话吊销记录
+// 签发时间不晚于RevokedBefore的该用户访问令牌全部失效，用于"退出所有会话"
+type SessionRevocation struct {
+	UserID        int       `json:"user_id" gorm:"primaryKey;autoIncrement:false"` // 用户ID
+	RevokedBefore time.Time `json:"revoked_before"`                                // 吊销此时间及之前签发的令牌
+}
+
+// TokenStore 令牌存储接口
+// 保存刷新令牌和访问令牌的吊销信息
+type TokenStore interface {
+	// CreateRefreshToken 保存新签发的刷新令牌
+	CreateRefreshToken(token *RefreshToken) error
+	// GetRefreshTokenByHash 根据哈希获取刷新令牌，不存在时返回nil, nil
+	GetRefreshTokenByHash(hash string) (*RefreshToken, error)
+	// MarkRefreshTokenUsed 原子地将未使用的刷新令牌标记为已使用
+	// 令牌已被使用过时返回false，调用方应视为重复使用
+	MarkRefreshTokenUsed(id int, at time.Time) (bool, error)
+	// RevokeRefreshTokenFamily 吊销同一家族的所有刷新令牌
+	RevokeRefreshTokenFamily(familyID string, at time.Time) error
+	// RevokeAccessToken 将访问令牌加入吊销列表，并清理已过期的吊销记录
+	RevokeAccessToken(token *RevokedToken) error
+	// IsAccessTokenRevoked 判断访问令牌是否已被单独吊销或因"退出所有会话"而失效
+	IsAccessTokenRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+	// RevokeAllUserTokens 吊销用户所有的刷新令牌，并使at及之前签发的访问令牌失效
+	RevokeAllUserTokens(userID uint, at time.Time) error
+}
@@ -2,75 +2,32 @@ package models
 
 import (
 	"time"
-
-	"gorm.io/gorm"
 )
 
 // User 用户模型结构体
 // 定义了用户的所有属性和JSON序列化规则
 type User struct {
-	ID        int       `json:"id"`        // 用户唯一标识符
-	Username  string    `json:"username"`  // 用户名，用于登录和标识
-	Password  string    `json:"-"`         // 用户密码，不输出到JSON响应
-	Email     string    `json:"email"`     // 用户邮箱
-	Nickname  string    `json:"nickname"`  // 用户昵称，显示用
-	Avatar    string    `json:"avatar"`    // 用户头像URL
-	CreatedAt time.Time `json:"created_at"` // 用户创建时间
-	UpdatedAt time.Time `json:"updated_at"` // 用户信息更新时间
-}
-// GetUsers 获取所有用户列表
-// 此函数从数据库中检索所有用户信息
-// 参数：
-//   limit - 返回结果的最大数量
-//   offset - 查询的偏移量
-// 返回：
-//   []*User - 用户指针的切片
-//   error - 操作过程中的错误，如果没有错误则为nil
-func GetUsers(limit, offset int) ([]*User, error) {
-	var users []*User
-	// 检查DB是否为nil，避免空指针异常
-	if DB == nil {
-		return users, nil
-	}
-	if err := DB.Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
-		return nil, err
-	}
-	return users, nil
+	ID            int       `json:"id"`         // 用户唯一标识符
+	Username      string    `json:"username"`   // 用户名，用于登录和标识
+	Password      string    `json:"-"`          // 用户密码，不输出到JSON响应
+	Email         string    `json:"-"`          // 用户邮箱，统一保存为小写，只通过Account输出
+	EmailVerified bool      `json:"-"`          // 邮箱是否已通过验证，只通过Account输出
+	Nickname      string    `json:"nickname"`   // 用户昵称，显示用
+	Avatar        string    `json:"avatar"`     // 用户头像URL
+	Role          Role      `json:"role"`       // 用户角色，决定用户拥有的权限
+	CreatedAt     time.Time `json:"created_at"` // 用户创建时间
+	UpdatedAt     time.Time `json:"updated_at"` // 用户信息更新时间
 }
-// GetUserByUsername 根据用户名获取用户信息
-// 此函数从数据库中检索指定用户名的用户
-// 参数：
-//   username - 要查找的用户名
-// 返回：
-//   *User - 找到的用户指针，如果用户不存在则为nil
-//   error - 操作过程中的错误，如果没有错误则为nil
-func GetUserByUsername(username string) (*User, error) {
-	// 检查DB是否为nil，避免空指针异常
-	if DB == nil {
-		return nil, nil
-	}
-	var user User
-	if err := DB.Where("username = ?", username).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &user, nil
+
+// Account 用户本人和管理员看到的账户信息
+// 在User的公开字段之外输出邮箱，用户列表、文章作者等公开响应直接使用User，不包含邮箱
+type Account struct {
+	*User
+	Email         string `json:"email"`          // 用户邮箱
+	EmailVerified bool   `json:"email_verified"` // 邮箱是否已通过验证
 }
 
-// CreateUser 创建新用户
-// 此函数为用户设置创建/更新时间，然后保存到数据库中
-// 参数：
-//   user - 要创建的用户指针
-// 返回：
-//   error - 操作过程中的错误，如果没有错误则为nil
-func CreateUser(user *User) error {
-	// 检查DB是否为nil，避免空指针异常
-	if DB == nil {
-		return nil
-	}
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
-	return DB.Create(user).Error
-}
\ No newline at end of file
+// Account 返回包含邮箱的账户信息，用于登录、注册、/api/user/me和管理员接口的响应
+func (u *User) Account() *Account {
+	return &Account{User: u, Email: u.Email, EmailVerified: u.EmailVerified}
+}